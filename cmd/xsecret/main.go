@@ -0,0 +1,73 @@
+// xsecret 生成密钥并加解密配置中的 ${enc:...} 值，密钥从 XGO_SECRET_KEY 读取
+//
+//	xsecret genkey
+//	XGO_SECRET_KEY=... xsecret encrypt < db_password.txt
+//	XGO_SECRET_KEY=... xsecret decrypt 'base64...'
+//
+// encrypt 从标准输入读取明文，避免明文出现在 shell 历史和进程列表中，末尾的换行会被去掉
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/daodao97/xgo/xapp"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "genkey":
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			fail(err)
+		}
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+	case "encrypt":
+		key, err := xapp.SecretKey()
+		if err != nil {
+			fail(err)
+		}
+		plain, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fail(err)
+		}
+		out, err := xapp.EncryptSecret(strings.TrimRight(string(plain), "\r\n"), key)
+		if err != nil {
+			fail(err)
+		}
+		fmt.Printf("${enc:%s}\n", out)
+	case "decrypt":
+		if len(os.Args) < 3 {
+			usage()
+		}
+		key, err := xapp.SecretKey()
+		if err != nil {
+			fail(err)
+		}
+		out, err := xapp.DecryptSecret(os.Args[2], key)
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println(out)
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: xsecret genkey | encrypt < plaintext | decrypt <value>")
+	os.Exit(2)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/daodao97/xgo/xmetrics"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -34,7 +35,9 @@ func (l *ConcurrencyLimiter) CanProcess(ctx context.Context, userID, resourceID
 	return currentConcurrent < l.ConcurrentLimit
 }
 
-func (l *ConcurrencyLimiter) Process(ctx context.Context, userID, resourceID string) bool {
+func (l *ConcurrencyLimiter) Process(ctx context.Context, userID, resourceID string) (ok bool) {
+	defer func() { xmetrics.ObserveLimiter("concurrency", ok) }()
+
 	const script = `
 local current = redis.call("GET", KEYS[1])
 if not current then
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/daodao97/xgo/xmetrics"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -45,7 +46,9 @@ func (l *SlidingWindowLimiter) CanProcess(ctx context.Context, userID, resourceI
 }
 
 // Process 处理请求，如果允许则记录请求时间戳
-func (l *SlidingWindowLimiter) Process(ctx context.Context, userID, resourceID string) bool {
+func (l *SlidingWindowLimiter) Process(ctx context.Context, userID, resourceID string) (ok bool) {
+	defer func() { xmetrics.ObserveLimiter("sliding_window", ok) }()
+
 	key := l.GetKey(userID, resourceID)
 	now := time.Now()
 	windowStart := now.Add(-l.WindowSize).UnixNano()
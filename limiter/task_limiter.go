@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/daodao97/xgo/xmetrics"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -34,7 +35,9 @@ func (l *TaskLimiter) CanProcess(ctx context.Context, userID, resourceID string)
 	return int(taskCount) < l.MaxRequests
 }
 
-func (l *TaskLimiter) Process(ctx context.Context, userID, resourceID string) bool {
+func (l *TaskLimiter) Process(ctx context.Context, userID, resourceID string) (ok bool) {
+	defer func() { xmetrics.ObserveLimiter("task", ok) }()
+
 	if !l.CanProcess(ctx, userID, resourceID) {
 		return false
 	}
@@ -0,0 +1,351 @@
+package xapp
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+const xclientPkgPath = "github.com/daodao97/xgo/xclient"
+
+// GenerateClient 根据 RegisterAPI 注册的路由生成 Go 客户端包，每个路由一个方法，
+// 请求与响应直接复用服务端的结构体，由 xclient 负责编码请求与解析 {code, message, data}。
+// 结构体需要是可导入包中的导出类型，文件上传接口会被跳过
+func GenerateClient(engine *gin.Engine, pkg string) ([]byte, error) {
+	apis := APIs(engine)
+	imports := newClientImports()
+	names := map[string]int{}
+
+	var methods bytes.Buffer
+	var skipped []string
+	for _, api := range apis {
+		if hasFileField(api.RequestType) {
+			skipped = append(skipped, api.Method+" "+api.Path)
+			continue
+		}
+		reqType, err := imports.typeExpr(api.RequestType)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s request: %w", api.Method, api.Path, err)
+		}
+		respType, err := imports.typeExpr(api.ResponseType)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s response: %w", api.Method, api.Path, err)
+		}
+
+		name := clientMethodName(api)
+		if n := names[name]; n > 0 {
+			names[name]++
+			name += strconv.Itoa(n + 1)
+		} else {
+			names[name] = 1
+		}
+
+		fmt.Fprintf(&methods, "\n// %s %s %s\n", name, api.Method, api.Path)
+		for _, line := range []string{api.Summary, api.Description} {
+			if line != "" {
+				fmt.Fprintf(&methods, "// %s\n", strings.ReplaceAll(line, "\n", "\n// "))
+			}
+		}
+		if api.Deprecated {
+			methods.WriteString("//\n// Deprecated: 接口已废弃\n")
+		}
+		fmt.Fprintf(&methods, "func (c *Client) %s(ctx context.Context, req %s) (*%s, error) {\n", name, reqType, respType)
+		fmt.Fprintf(&methods, "\treturn xclient.Call[%s, %s](ctx, c.c, %q, %q, req)\n}\n", reqType, respType, api.Method, api.Path)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by xapp.GenerateClient. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\nimport (\n\t\"context\"\n\n\t%q\n", pkg, xclientPkgPath)
+	for _, path := range imports.paths() {
+		fmt.Fprintf(&buf, "\t%s %q\n", imports.byPath[path], path)
+	}
+	buf.WriteString(")\n\n")
+	if len(skipped) > 0 {
+		buf.WriteString("// 以下文件上传接口未生成:\n")
+		for _, s := range skipped {
+			fmt.Fprintf(&buf, "//   - %s\n", s)
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("type Client struct {\n\tc *xclient.Client\n}\n\n")
+	buf.WriteString("func New(baseURL string, opts ...xclient.Option) *Client {\n\treturn &Client{c: xclient.New(baseURL, opts...)}\n}\n")
+	buf.Write(methods.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format client: %w", err)
+	}
+	return src, nil
+}
+
+// WriteClient 生成客户端并写入 dir/client.go，包名取目录名。
+// 一般在服务的 cmd/genclient 中构建路由后调用
+func WriteClient(engine *gin.Engine, dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	pkg := strings.NewReplacer("-", "", ".", "").Replace(filepath.Base(abs))
+	src, err := GenerateClient(engine, pkg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(abs, "client.go"), src, 0o644)
+}
+
+var (
+	nonIdentChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+	versionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+)
+
+var commonInitialisms = map[string]string{
+	"id": "ID", "ids": "IDs", "url": "URL", "uri": "URI", "api": "API", "uuid": "UUID", "http": "HTTP", "json": "JSON",
+}
+
+// clientMethodName 优先使用 OperationID，否则由方法与路径生成，如 GET /users/:id -> GetUsersByID
+func clientMethodName(api APIInfo) string {
+	if api.OperationID != "" {
+		return exportedIdent(api.OperationID)
+	}
+	var b strings.Builder
+	b.WriteString(exportedIdent(strings.ToLower(api.Method)))
+	for _, seg := range strings.Split(api.Path, "/") {
+		if seg == "" {
+			continue
+		}
+		if seg[0] == ':' || seg[0] == '*' {
+			b.WriteString("By")
+			seg = seg[1:]
+		}
+		b.WriteString(exportedIdent(seg))
+	}
+	return b.String()
+}
+
+func exportedIdent(s string) string {
+	var b strings.Builder
+	for _, part := range nonIdentChars.Split(s, -1) {
+		if part == "" {
+			continue
+		}
+		if initialism, ok := commonInitialisms[strings.ToLower(part)]; ok {
+			b.WriteString(initialism)
+			continue
+		}
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "Call" + name
+	}
+	return name
+}
+
+// clientImports 记录生成代码需要导入的包，并为同名包分配不冲突的别名
+type clientImports struct {
+	byPath  map[string]string
+	aliases map[string]bool
+}
+
+func newClientImports() *clientImports {
+	return &clientImports{
+		byPath:  map[string]string{},
+		aliases: map[string]bool{"context": true, "xclient": true},
+	}
+}
+
+func (im *clientImports) paths() []string {
+	paths := make([]string, 0, len(im.byPath))
+	for path := range im.byPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (im *clientImports) alias(path string) string {
+	if alias, ok := im.byPath[path]; ok {
+		return alias
+	}
+	parts := strings.Split(path, "/")
+	base := parts[len(parts)-1]
+	// 跳过 /v2 这类版本后缀
+	if len(parts) > 1 && versionSuffix.MatchString(base) {
+		base = parts[len(parts)-2]
+	}
+	base = strings.ToLower(nonIdentChars.ReplaceAllString(base, ""))
+	if base == "" || unicode.IsDigit(rune(base[0])) {
+		base = "pkg" + base
+	}
+	alias := base
+	for i := 2; im.aliases[alias]; i++ {
+		alias = base + strconv.Itoa(i)
+	}
+	im.aliases[alias] = true
+	im.byPath[path] = alias
+	return alias
+}
+
+// typeExpr 将 reflect.Type 转为生成代码中的类型表达式
+func (im *clientImports) typeExpr(t reflect.Type) (string, error) {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name(), nil
+		}
+		return im.namedExpr(t.PkgPath(), t.Name())
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		elem, err := im.typeExpr(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		switch t.Kind() {
+		case reflect.Ptr:
+			return "*" + elem, nil
+		case reflect.Slice:
+			return "[]" + elem, nil
+		}
+		return fmt.Sprintf("[%d]%s", t.Len(), elem), nil
+	case reflect.Map:
+		key, err := im.typeExpr(t.Key())
+		if err != nil {
+			return "", err
+		}
+		elem, err := im.typeExpr(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "map[" + key + "]" + elem, nil
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "any", nil
+		}
+	case reflect.Struct:
+		if t.NumField() == 0 {
+			return "struct{}", nil
+		}
+	}
+	return "", fmt.Errorf("unsupported anonymous type %s", t)
+}
+
+// namedExpr 处理具名类型，泛型实参以字符串形式出现在类型名中，如 PageResult[github.com/x/model.User]
+func (im *clientImports) namedExpr(pkgPath, name string) (string, error) {
+	if pkgPath == "main" {
+		return "", fmt.Errorf("type %s is declared in package main and cannot be imported", name)
+	}
+	base, args := name, ""
+	if i := strings.Index(name, "["); i >= 0 {
+		base, args = name[:i], name[i+1:len(name)-1]
+	}
+	if !ast.IsExported(base) {
+		return "", fmt.Errorf("type %s.%s is not exported", pkgPath, base)
+	}
+	expr := im.alias(pkgPath) + "." + base
+	if args == "" {
+		return expr, nil
+	}
+	var rendered []string
+	for _, arg := range splitTypeArgs(args) {
+		s, err := im.parseTypeString(strings.TrimSpace(arg))
+		if err != nil {
+			return "", err
+		}
+		rendered = append(rendered, s)
+	}
+	return expr + "[" + strings.Join(rendered, ", ") + "]", nil
+}
+
+// parseTypeString 解析 reflect 输出的类型字符串
+func (im *clientImports) parseTypeString(s string) (string, error) {
+	switch {
+	case s == "interface {}":
+		return "any", nil
+	case strings.HasPrefix(s, "*"):
+		elem, err := im.parseTypeString(s[1:])
+		return "*" + elem, err
+	case strings.HasPrefix(s, "[]"):
+		elem, err := im.parseTypeString(s[2:])
+		return "[]" + elem, err
+	case strings.HasPrefix(s, "["):
+		end := strings.Index(s, "]")
+		elem, err := im.parseTypeString(s[end+1:])
+		return s[:end+1] + elem, err
+	case strings.HasPrefix(s, "map["):
+		end := matchingBracket(s, len("map"))
+		if end < 0 {
+			return "", fmt.Errorf("invalid type %s", s)
+		}
+		key, err := im.parseTypeString(s[len("map["):end])
+		if err != nil {
+			return "", err
+		}
+		elem, err := im.parseTypeString(s[end+1:])
+		return "map[" + key + "]" + elem, err
+	}
+
+	base := s
+	if i := strings.Index(s, "["); i >= 0 {
+		base = s[:i]
+	}
+	dot := strings.LastIndex(base, ".")
+	if dot < 0 {
+		if strings.ContainsAny(s, " {(") {
+			return "", fmt.Errorf("unsupported type argument %s", s)
+		}
+		return s, nil
+	}
+	return im.namedExpr(s[:dot], s[dot+1:])
+}
+
+// splitTypeArgs 按顶层逗号拆分泛型实参
+func splitTypeArgs(s string) []string {
+	var args []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(args, s[start:])
+}
+
+func matchingBracket(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
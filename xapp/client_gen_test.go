@@ -0,0 +1,97 @@
+package xapp
+
+import (
+	"go/parser"
+	"go/token"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClientGenUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type ClientGenGetReq struct {
+	ID int `uri:"id"`
+}
+
+func TestGenerateClient(t *testing.T) {
+	originalRegistry := apiRegistry
+	apiRegistry = nil
+	defer func() {
+		apiRegistry = originalRegistry
+	}()
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/users", RegisterAPI(func(c *gin.Context, req Page) (*PageResult[ClientGenUser], error) {
+		return nil, nil
+	}, WithSummary("用户列表")))
+	e.GET("/users/:id", RegisterAPI(func(c *gin.Context, req ClientGenGetReq) (*ClientGenUser, error) {
+		return nil, nil
+	}))
+	e.POST("/users", RegisterAPI(func(c *gin.Context, req ClientGenUser) (*map[string][]*ClientGenUser, error) {
+		return nil, nil
+	}, WithOperationID("create-user")))
+	e.POST("/upload", RegisterAPI(func(c *gin.Context, req struct {
+		File *multipart.FileHeader `form:"file"`
+	}) (*Empty, error) {
+		return nil, nil
+	}))
+
+	src, err := GenerateClient(e, "userclient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(src)
+	if _, err := parser.ParseFile(token.NewFileSet(), "client.go", src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, code)
+	}
+
+	for _, want := range []string{
+		"package userclient",
+		`xapp "github.com/daodao97/xgo/xapp"`,
+		"// 用户列表",
+		"func (c *Client) GetUsers(ctx context.Context, req xapp.Page) (*xapp.PageResult[xapp.ClientGenUser], error)",
+		`xclient.Call[xapp.ClientGenGetReq, xapp.ClientGenUser](ctx, c.c, "GET", "/users/:id", req)`,
+		"func (c *Client) CreateUser(ctx context.Context, req xapp.ClientGenUser) (*map[string][]*xapp.ClientGenUser, error)",
+		"//   - POST /upload",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated client missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestGenerateClientRejectsUnexportedTypes(t *testing.T) {
+	im := newClientImports()
+	if _, err := im.typeExpr(reflect.TypeOf(openapiUser{})); err == nil {
+		t.Fatal("expected error for unexported type")
+	}
+	if _, err := im.typeExpr(reflect.TypeOf(struct{ A int }{})); err == nil {
+		t.Fatal("expected error for anonymous struct")
+	}
+}
+
+func TestClientTypeExprImportAliases(t *testing.T) {
+	im := newClientImports()
+	expr, err := im.typeExpr(reflect.TypeOf(map[string]time.Duration{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expr != "map[string]time.Duration" {
+		t.Fatalf("unexpected expr %s", expr)
+	}
+	if alias := im.alias("example.com/other/time"); alias != "time2" {
+		t.Fatalf("expected conflicting alias time2, got %s", alias)
+	}
+	if alias := im.alias("github.com/redis/go-redis/v9"); alias != "goredis" {
+		t.Fatalf("expected goredis, got %s", alias)
+	}
+}
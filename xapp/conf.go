@@ -13,7 +13,6 @@ import (
 	"github.com/daodao97/xgo/xlog"
 	"github.com/daodao97/xgo/xutil"
 	"github.com/fsnotify/fsnotify"
-	"gopkg.in/yaml.v3"
 )
 
 // conf.yaml + conf.{env}.yaml
@@ -68,10 +67,16 @@ func fileConf(dest any, files ...string) ([]string, error) {
 				return nil, fmt.Errorf("读取配置文件失败 %s: %v", file, err)
 			}
 
-			// 解析 YAML 到目标结构体
-			if err := yaml.Unmarshal(data, dest); err != nil {
+			// 解析 YAML 并替换密钥引用后写入目标结构体
+			node, err := resolveYAMLSecrets(data)
+			if err != nil {
 				return nil, fmt.Errorf("解析配置文件失败 %s: %v", file, err)
 			}
+			if node.Kind != 0 {
+				if err := node.Decode(dest); err != nil {
+					return nil, fmt.Errorf("解析配置文件失败 %s: %v", file, err)
+				}
+			}
 
 			xlog.Info("load config", xlog.String("file", file))
 			loadedFiles = append(loadedFiles, absFile)
@@ -86,7 +91,8 @@ func fileConf(dest any, files ...string) ([]string, error) {
 	return uniqueStrings(loadedFiles), nil
 }
 
-// InitConf 初始化配置
+// InitConf 初始化配置，仅在 dev 环境下原地热更新 dest。
+// 需要在运行时安全读取和订阅变更时使用 WatchConf
 func InitConf(dest any) error {
 	if !xutil.IsPtr(dest) {
 		return fmt.Errorf("配置目标必须是结构体指针类型")
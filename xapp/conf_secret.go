@@ -0,0 +1,251 @@
+package xapp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecretKeyEnv 保存 ${enc:...} 解密密钥的环境变量，值为 base64 编码的 16/24/32 字节 AES 密钥
+const SecretKeyEnv = "XGO_SECRET_KEY"
+
+// 配置中的密钥引用：${file:/run/secrets/db}、${env:DB_PASS}、${enc:base64}
+var secretRefPattern = regexp.MustCompile(`\$\{(file|env|enc):([^}]*)\}`)
+
+// minSecretLen 短于该长度的值不参与脱敏，避免 "1"、"8080" 之类的值在日志中被全局替换
+const minSecretLen = 6
+
+var (
+	resolvedSecretsMu sync.RWMutex
+	resolvedSecrets   = map[string]struct{}{}
+)
+
+// ResolveSecrets 替换字符串中的所有密钥引用，${enc:...} 解析出的值会被记录并在日志中脱敏
+func ResolveSecrets(s string) (string, error) {
+	return resolveSecrets("", s)
+}
+
+// resolveSecrets 替换密钥引用，${env:...}、${file:...} 的值只在 key 为敏感配置时记录用于脱敏
+func resolveSecrets(key, s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var resolveErr error
+	out := secretRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		m := secretRefPattern.FindStringSubmatch(ref)
+		value, err := resolveSecretRef(m[1], m[2])
+		if err != nil {
+			if resolveErr == nil {
+				resolveErr = fmt.Errorf("resolve %s: %w", ref, err)
+			}
+			return ref
+		}
+		if m[1] == "enc" || IsSecretKey(key) {
+			registerSecret(value)
+		}
+		return value
+	})
+	return out, resolveErr
+}
+
+func resolveSecretRef(kind, ref string) (string, error) {
+	switch kind {
+	case "file":
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case "env":
+		value, ok := os.LookupEnv(ref)
+		if !ok {
+			return "", fmt.Errorf("env %s not set", ref)
+		}
+		return value, nil
+	case "enc":
+		key, err := SecretKey()
+		if err != nil {
+			return "", err
+		}
+		return DecryptSecret(ref, key)
+	}
+	return "", fmt.Errorf("unknown secret kind %s", kind)
+}
+
+// SecretKey 从 SecretKeyEnv 读取密钥
+func SecretKey() ([]byte, error) {
+	raw := os.Getenv(SecretKeyEnv)
+	if raw == "" {
+		return nil, fmt.Errorf("%s not set", SecretKeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be base64 encoded: %w", SecretKeyEnv, err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("%s must be 16, 24 or 32 bytes, got %d", SecretKeyEnv, len(key))
+}
+
+// EncryptSecret 使用 AES-GCM 加密，返回 base64(nonce + ciphertext)，可直接写成 ${enc:...}
+func EncryptSecret(plain string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func DecryptSecret(encrypted string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func registerSecret(value string) {
+	if len(value) < minSecretLen {
+		return
+	}
+	resolvedSecretsMu.Lock()
+	defer resolvedSecretsMu.Unlock()
+	resolvedSecrets[value] = struct{}{}
+}
+
+// MaskSecrets 将字符串中出现的已解析密钥替换为 ******
+func MaskSecrets(s string) string {
+	resolvedSecretsMu.RLock()
+	secrets := make([]string, 0, len(resolvedSecrets))
+	for secret := range resolvedSecrets {
+		secrets = append(secrets, secret)
+	}
+	resolvedSecretsMu.RUnlock()
+
+	// 先替换较长的值，避免短值是长值的子串时残留
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, "******")
+	}
+	return s
+}
+
+// resolveYAMLSecrets 解析 yaml 并替换标量中的密钥引用，避免密钥内容破坏 yaml 语法
+func resolveYAMLSecrets(data []byte) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	if err := walkYAMLSecrets(&node, ""); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+func walkYAMLSecrets(node *yaml.Node, key string) error {
+	if node.Kind == yaml.ScalarNode && node.Tag != "!!binary" {
+		value, err := resolveSecrets(key, node.Value)
+		if err != nil {
+			return err
+		}
+		if value != node.Value {
+			node.Value = value
+			node.Tag = ""
+			node.Style = 0
+		}
+		return nil
+	}
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := walkYAMLSecrets(node.Content[i+1], joinConfKey(key, node.Content[i].Value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, child := range node.Content {
+		if err := walkYAMLSecrets(child, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func joinConfKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// resolveMapSecrets 替换 map 中所有字符串值的密钥引用，prefix 为 map 所在的配置路径
+func resolveMapSecrets(prefix string, values map[string]any) error {
+	for k, v := range values {
+		resolved, err := resolveValueSecrets(joinConfKey(prefix, k), v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		values[k] = resolved
+	}
+	return nil
+}
+
+func resolveValueSecrets(key string, v any) (any, error) {
+	switch val := v.(type) {
+	case string:
+		return resolveSecrets(key, val)
+	case rawScalar:
+		resolved, err := resolveSecrets(key, string(val))
+		return rawScalar(resolved), err
+	case map[string]any:
+		return val, resolveMapSecrets(key, val)
+	case []any:
+		for i := range val {
+			resolved, err := resolveValueSecrets(key, val[i])
+			if err != nil {
+				return nil, err
+			}
+			val[i] = resolved
+		}
+		return val, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,86 @@
+package xapp
+
+import (
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitConfResolvesSecretReferences(t *testing.T) {
+	tempDir := t.TempDir()
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	t.Setenv(SecretKeyEnv, key)
+	t.Setenv("TEST_DB_PASS", "s3cr3t-pass")
+
+	rawKey, err := SecretKey()
+	if err != nil {
+		t.Fatalf("SecretKey failed: %v", err)
+	}
+	encrypted, err := EncryptSecret("jwt-signing-key", rawKey)
+	if err != nil {
+		t.Fatalf("EncryptSecret failed: %v", err)
+	}
+
+	writeFile(t, filepath.Join(tempDir, "port"), "8080\n")
+	writeFile(t, filepath.Join(tempDir, "conf.yaml"),
+		"dsn: \"root:${env:TEST_DB_PASS}@tcp(db)/app\"\n"+
+			"jwt: ${enc:"+encrypted+"}\n"+
+			"port: ${file:"+filepath.Join(tempDir, "port")+"}\n")
+
+	originalConfDir := confDir
+	originalAppEnv := Args.AppEnv
+	confDir = []string{tempDir}
+	Args.AppEnv = "prod"
+	defer func() {
+		confDir = originalConfDir
+		Args.AppEnv = originalAppEnv
+	}()
+
+	var conf struct {
+		DSN  string `yaml:"dsn"`
+		JWT  string `yaml:"jwt"`
+		Port int    `yaml:"port"`
+	}
+	if err := InitConf(&conf); err != nil {
+		t.Fatalf("InitConf failed: %v", err)
+	}
+
+	if conf.DSN != "root:s3cr3t-pass@tcp(db)/app" || conf.JWT != "jwt-signing-key" || conf.Port != 8080 {
+		t.Fatalf("unexpected config: %+v", conf)
+	}
+
+	masked := MaskSecrets("dsn=" + conf.DSN + " jwt=" + conf.JWT)
+	if strings.Contains(masked, "s3cr3t-pass") || strings.Contains(masked, "jwt-signing-key") {
+		t.Fatalf("secrets not masked: %s", masked)
+	}
+	// 非敏感 key 的 ${file:...} 值不参与脱敏
+	if masked := MaskSecrets("listen on 8080"); masked != "listen on 8080" {
+		t.Fatalf("non-secret value masked: %s", masked)
+	}
+}
+
+func TestResolveSecretsOnlyMasksSecretValues(t *testing.T) {
+	t.Setenv("TEST_APP_REGION", "cn-north-1")
+	t.Setenv("TEST_SHORT_TOKEN", "1")
+
+	values := map[string]any{
+		"region": "${env:TEST_APP_REGION}",
+		"auth":   map[string]any{"token": "${env:TEST_SHORT_TOKEN}"},
+	}
+	if err := resolveMapSecrets("", values); err != nil {
+		t.Fatalf("resolveMapSecrets failed: %v", err)
+	}
+	if values["region"] != "cn-north-1" {
+		t.Fatalf("unexpected values: %+v", values)
+	}
+	if masked := MaskSecrets("region=cn-north-1 retries=1"); masked != "region=cn-north-1 retries=1" {
+		t.Fatalf("non-secret or short values masked: %s", masked)
+	}
+}
+
+func TestResolveSecretsMissingEnv(t *testing.T) {
+	if _, err := ResolveSecrets("${env:XGO_TEST_MISSING_ENV}"); err == nil {
+		t.Fatal("expected error for missing env")
+	}
+}
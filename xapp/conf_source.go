@@ -0,0 +1,492 @@
+package xapp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/yaml.v3"
+
+	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xredis"
+	"github.com/daodao97/xgo/xutil"
+)
+
+// ConfigSource 提供一层配置，返回嵌套的 map，key 与 yaml tag 对应
+type ConfigSource interface {
+	Name() string
+	Load(ctx context.Context) (map[string]any, error)
+}
+
+// envSource 由同时提供环境变量的 Source 实现，用于 env tag 解析
+type envSource interface {
+	Env() map[string]string
+}
+
+// LoadConf 按顺序合并 sources，后面的覆盖前面的，然后解析到 dest，最后处理 env tag。
+// sources 为空时使用 DefaultSources
+func LoadConf(dest any, sources ...ConfigSource) (*ConfReport, error) {
+	if !xutil.IsPtr(dest) {
+		return nil, fmt.Errorf("配置目标必须是结构体指针类型")
+	}
+	if len(sources) == 0 {
+		sources = DefaultSources()
+	}
+
+	ctx := context.Background()
+	report := &ConfReport{
+		values:  map[string]any{},
+		Origins: map[string]string{},
+	}
+	envs := map[string]string{}
+	for _, source := range sources {
+		values, err := source.Load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("加载配置源失败 %s: %w", source.Name(), err)
+		}
+		if len(values) > 0 {
+			mergeConf(report.values, values)
+			for key := range flattenConf("", values) {
+				report.setOrigin(key, source.Name())
+			}
+			xlog.Debug("load config source", xlog.String("source", source.Name()))
+		}
+		if es, ok := source.(envSource); ok {
+			for k, v := range es.Env() {
+				envs[k] = v
+			}
+		}
+	}
+
+	if err := resolveMapSecrets("", report.values); err != nil {
+		return nil, fmt.Errorf("解析密钥引用失败: %v", err)
+	}
+
+	data, err := yaml.Marshal(report.values)
+	if err != nil {
+		return nil, fmt.Errorf("合并配置失败: %v", err)
+	}
+	if err := yaml.Unmarshal(data, dest); err != nil {
+		return nil, fmt.Errorf("解析配置失败: %v", err)
+	}
+
+	// 进程环境变量优先级最高
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			envs[k] = v
+		}
+	}
+	envKeys := map[string]string{}
+	envConfKeys(reflect.TypeOf(dest), "", "", envKeys)
+	err = env.ParseWithOptions(dest, env.Options{
+		Environment: envs,
+		OnSet: func(tag string, value any, isDefault bool) {
+			key, ok := envKeys[tag]
+			if !ok || value == "" {
+				return
+			}
+			origin := "env:" + tag
+			if isDefault {
+				origin = "env-default:" + tag
+			}
+			setConfPath(report.values, strings.Split(key, "."), value)
+			report.setOrigin(key, origin)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("处理环境变量失败: %v", err)
+	}
+	return report, nil
+}
+
+// envConfKeys 按 yaml tag 的层级记录每个 env tag 对应的配置 key
+func envConfKeys(t reflect.Type, envPrefix, keyPrefix string, out map[string]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		key := keyPrefix
+		if !strings.Contains(opts, "inline") {
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			key = joinConfKey(keyPrefix, name)
+		}
+		if tag, _, _ := strings.Cut(f.Tag.Get("env"), ","); tag != "" {
+			out[envPrefix+tag] = key
+		}
+		envConfKeys(f.Type, envPrefix+f.Tag.Get("envPrefix"), key, out)
+	}
+}
+
+// DefaultSources 与 InitConf 一致的文件加载顺序，再叠加 .env 与 --set 参数
+func DefaultSources() []ConfigSource {
+	var sources []ConfigSource
+	for _, file := range getConfFile() {
+		for _, dir := range confDir {
+			sources = append(sources, FileSource(filepath.Join(dir, file)))
+		}
+	}
+	for _, dir := range confDir {
+		sources = append(sources, DotEnvSource(filepath.Join(dir, ".env")))
+	}
+	sources = append(sources, FlagSource())
+	return sources
+}
+
+// ConfReport 记录每个配置项的来源
+type ConfReport struct {
+	values  map[string]any
+	Origins map[string]string
+}
+
+// setOrigin 记录 key 的来源，并删除被覆盖的父级或子级 key 的来源
+func (r *ConfReport) setOrigin(key, origin string) {
+	for k := range r.Origins {
+		if strings.HasPrefix(k, key+".") || strings.HasPrefix(key, k+".") {
+			delete(r.Origins, k)
+		}
+	}
+	r.Origins[key] = origin
+}
+
+// Dump 输出每个配置项的值与来源，敏感字段脱敏
+func (r *ConfReport) Dump() string {
+	flat := flattenConf("", r.values)
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, key := range keys {
+		value := fmt.Sprint(flat[key])
+		if IsSecretKey(key) {
+			value = MaskSecret(value)
+		}
+		fmt.Fprintf(&buf, "%s = %s (%s)\n", key, MaskSecrets(value), r.Origins[key])
+	}
+	return buf.String()
+}
+
+var secretKeywords = []string{"password", "passwd", "secret", "token", "dsn", "private_key", "access_key", "secret_key", "api_key", "apikey", "credential", "credentials"}
+
+// IsSecretKey 根据 key 最后一段的后缀判断是否为敏感配置，
+// db.password、jwt_secret、accessToken 是，max_tokens、token_ttl 不是
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	for _, kw := range secretKeywords {
+		if strings.HasSuffix(key, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskSecret 仅保留首尾各两个字符
+func MaskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 6 {
+		return "******"
+	}
+	return value[:2] + "******" + value[len(value)-2:]
+}
+
+type fileSource struct {
+	path string
+}
+
+// FileSource 按扩展名解析 yaml、json、toml 文件，文件不存在时跳过
+func FileSource(path string) ConfigSource {
+	return &fileSource{path: path}
+}
+
+func (s *fileSource) Name() string {
+	return "file:" + s.path
+}
+
+func (s *fileSource) Load(ctx context.Context) (map[string]any, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(s.path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err = dec.Decode(&values); err == nil {
+			rawJSONNumbers(values)
+		}
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		err = yaml.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+type dotEnvSource struct {
+	path string
+	env  map[string]string
+}
+
+// DotEnvSource 读取 .env 文件。KEY 同时作为环境变量供 env tag 使用，
+// 并按小写、"__" 分隔映射为嵌套配置，如 DB__DSN 对应 db.dsn
+func DotEnvSource(path string) ConfigSource {
+	return &dotEnvSource{path: path}
+}
+
+func (s *dotEnvSource) Name() string {
+	return "dotenv:" + s.path
+}
+
+func (s *dotEnvSource) Env() map[string]string {
+	return s.env
+}
+
+func (s *dotEnvSource) Load(ctx context.Context) (map[string]any, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	s.env = map[string]string{}
+	values := map[string]any{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = unquote(strings.TrimSpace(value))
+		s.env[key] = value
+		setConfPath(values, strings.Split(strings.ToLower(key), "__"), parseScalar(value))
+	}
+	return values, scanner.Err()
+}
+
+type flagSource struct{}
+
+// FlagSource 读取命令行 --set key=value 参数，key 以 "." 分隔层级
+func FlagSource() ConfigSource {
+	return flagSource{}
+}
+
+func (flagSource) Name() string {
+	return "flag"
+}
+
+func (flagSource) Load(ctx context.Context) (map[string]any, error) {
+	values := map[string]any{}
+	for _, kv := range Args.Set {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --set %q, expected key=value", kv)
+		}
+		setConfPath(values, strings.Split(strings.TrimSpace(key), "."), parseScalar(value))
+	}
+	return values, nil
+}
+
+type redisSource struct {
+	client string
+	key    string
+}
+
+// RedisSource 从 xredis 读取共享配置。hash 类型的每个 field 是以 "." 分隔的 key，
+// string 类型按 yaml/json 文档解析。client 为空时使用默认客户端，key 不存在时跳过
+func RedisSource(client, key string) ConfigSource {
+	return &redisSource{client: client, key: key}
+}
+
+func (s *redisSource) Name() string {
+	return "redis:" + s.key
+}
+
+func (s *redisSource) Load(ctx context.Context) (map[string]any, error) {
+	rdb := xredis.Get()
+	if s.client != "" {
+		rdb = xredis.GetClient(s.client)
+	}
+	if rdb == nil {
+		return nil, fmt.Errorf("redis client not found: %s", s.client)
+	}
+
+	typ, err := rdb.Type(ctx, s.key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]any{}
+	switch typ {
+	case "none":
+		return nil, nil
+	case "hash":
+		fields, err := rdb.HGetAll(ctx, s.key).Result()
+		if err != nil {
+			return nil, err
+		}
+		for field, value := range fields {
+			setConfPath(values, strings.Split(field, "."), parseScalar(value))
+		}
+	case "string":
+		data, err := rdb.Get(ctx, s.key).Bytes()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported redis type %s for key %s", typ, s.key)
+	}
+	return values, nil
+}
+
+func mergeConf(dst, src map[string]any) {
+	for k, v := range src {
+		if srcMap, ok := toConfMap(v); ok {
+			if dstMap, ok := toConfMap(dst[k]); ok {
+				mergeConf(dstMap, srcMap)
+				dst[k] = dstMap
+				continue
+			}
+			copied := map[string]any{}
+			mergeConf(copied, srcMap)
+			dst[k] = copied
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+func flattenConf(prefix string, values map[string]any) map[string]any {
+	flat := map[string]any{}
+	for k, v := range values {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if m, ok := toConfMap(v); ok {
+			for fk, fv := range flattenConf(key, m) {
+				flat[fk] = fv
+			}
+			continue
+		}
+		flat[key] = v
+	}
+	return flat
+}
+
+func toConfMap(v any) (map[string]any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		return m, true
+	case map[any]any:
+		converted := make(map[string]any, len(m))
+		for k, v := range m {
+			converted[fmt.Sprint(k)] = v
+		}
+		return converted, true
+	}
+	return nil, false
+}
+
+func setConfPath(values map[string]any, path []string, value any) {
+	for i, key := range path {
+		if i == len(path)-1 {
+			values[key] = value
+			return
+		}
+		next, ok := values[key].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			values[key] = next
+		}
+		values = next
+	}
+}
+
+// rawScalar 保留 .env、--set 等来源的原始文本，序列化为不带类型的 yaml 标量，
+// 由目标字段的类型决定如何解析，"007" 解析到 string 字段时不会变成 7
+type rawScalar string
+
+func (s rawScalar) MarshalYAML() (any, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: string(s)}, nil
+}
+
+// rawJSONNumbers 把 json.Number 替换为 rawScalar，避免大整数经 float64 丢失精度
+func rawJSONNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		return rawScalar(v)
+	case map[string]any:
+		for k, item := range v {
+			v[k] = rawJSONNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = rawJSONNumbers(item)
+		}
+	}
+	return v
+}
+
+func parseScalar(s string) any {
+	if s == "" {
+		return s
+	}
+	return rawScalar(s)
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
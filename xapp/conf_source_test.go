@@ -0,0 +1,160 @@
+package xapp
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type layeredTestConf struct {
+	Bind  string `yaml:"bind"`
+	Debug bool   `yaml:"debug"`
+	DB    struct {
+		DSN     string `yaml:"dsn"`
+		MaxConn int    `yaml:"max_conn"`
+	} `yaml:"db"`
+	Region string `yaml:"region" env:"REGION"`
+}
+
+func TestLoadConfMergesSourcesInOrder(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFile(t, filepath.Join(tempDir, "conf.yaml"), "bind: yaml\ndb:\n  dsn: root:pass@tcp(db)/app\n  max_conn: 10\n")
+	writeFile(t, filepath.Join(tempDir, "conf.json"), `{"debug": true, "db": {"max_conn": 20}}`)
+	writeFile(t, filepath.Join(tempDir, "conf.toml"), "bind = \"toml\"\n")
+	writeFile(t, filepath.Join(tempDir, ".env"), "# comment\nDB__MAX_CONN=30\nREGION=\"cn\"\n")
+
+	originalSet := Args.Set
+	Args.Set = []string{"bind=flag"}
+	defer func() {
+		Args.Set = originalSet
+	}()
+
+	var conf layeredTestConf
+	report, err := LoadConf(&conf,
+		FileSource(filepath.Join(tempDir, "conf.yaml")),
+		FileSource(filepath.Join(tempDir, "conf.json")),
+		FileSource(filepath.Join(tempDir, "conf.toml")),
+		FileSource(filepath.Join(tempDir, "missing.yaml")),
+		DotEnvSource(filepath.Join(tempDir, ".env")),
+		FlagSource(),
+	)
+	if err != nil {
+		t.Fatalf("LoadConf failed: %v", err)
+	}
+
+	if conf.Bind != "flag" || !conf.Debug || conf.DB.MaxConn != 30 || conf.DB.DSN != "root:pass@tcp(db)/app" {
+		t.Fatalf("unexpected config: %+v", conf)
+	}
+	if conf.Region != "cn" {
+		t.Fatalf("expected env tag from .env, got %q", conf.Region)
+	}
+
+	if got := report.Origins["db.max_conn"]; !strings.HasPrefix(got, "dotenv:") {
+		t.Fatalf("unexpected origin for db.max_conn: %s", got)
+	}
+	if got := report.Origins["debug"]; !strings.HasSuffix(got, "conf.json") {
+		t.Fatalf("unexpected origin for debug: %s", got)
+	}
+
+	dump := report.Dump()
+	if strings.Contains(dump, "root:pass") {
+		t.Fatalf("secret not masked in dump:\n%s", dump)
+	}
+	if !strings.Contains(dump, "bind = flag (flag)") {
+		t.Fatalf("unexpected dump:\n%s", dump)
+	}
+}
+
+func TestLoadConfKeepsRawScalarText(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFile(t, filepath.Join(tempDir, ".env"), "PIN=007\nVERSION=1.10\nDB__MAX_CONN=30\n")
+
+	originalSet := Args.Set
+	Args.Set = []string{"amount=1e5", "debug=true", "note=a: b"}
+	defer func() {
+		Args.Set = originalSet
+	}()
+
+	var conf struct {
+		layeredTestConf `yaml:",inline"`
+		Pin             string `yaml:"pin"`
+		Version         string `yaml:"version"`
+		Amount          string `yaml:"amount"`
+		Note            string `yaml:"note"`
+	}
+	if _, err := LoadConf(&conf, DotEnvSource(filepath.Join(tempDir, ".env")), FlagSource()); err != nil {
+		t.Fatalf("LoadConf failed: %v", err)
+	}
+	if conf.Pin != "007" || conf.Version != "1.10" || conf.Amount != "1e5" || conf.Note != "a: b" {
+		t.Fatalf("string values lost their text: %+v", conf)
+	}
+	if conf.DB.MaxConn != 30 || !conf.Debug {
+		t.Fatalf("typed values not decoded: %+v", conf)
+	}
+}
+
+func TestLoadConfKeepsJSONIntegerPrecision(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFile(t, filepath.Join(tempDir, "conf.json"), `{"id": 1234567890123456789, "ids": [1234567890123456789], "ratio": 0.5}`)
+
+	var conf struct {
+		ID    int64   `yaml:"id"`
+		IDs   []int64 `yaml:"ids"`
+		Ratio float64 `yaml:"ratio"`
+	}
+	if _, err := LoadConf(&conf, FileSource(filepath.Join(tempDir, "conf.json"))); err != nil {
+		t.Fatalf("LoadConf failed: %v", err)
+	}
+	if conf.ID != 1234567890123456789 || len(conf.IDs) != 1 || conf.IDs[0] != 1234567890123456789 || conf.Ratio != 0.5 {
+		t.Fatalf("unexpected config: %+v", conf)
+	}
+}
+
+func TestLoadConfOrigins(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFile(t, filepath.Join(tempDir, "a.yaml"), "bind: a\nextra:\n  x: 1\n  y: 2\n")
+	writeFile(t, filepath.Join(tempDir, "b.yaml"), "extra: off\n")
+	t.Setenv("REGION", "us")
+
+	var conf struct {
+		Bind   string `yaml:"bind"`
+		Extra  any    `yaml:"extra"`
+		Region string `yaml:"region" env:"REGION"`
+	}
+	report, err := LoadConf(&conf, FileSource(filepath.Join(tempDir, "a.yaml")), FileSource(filepath.Join(tempDir, "b.yaml")))
+	if err != nil {
+		t.Fatalf("LoadConf failed: %v", err)
+	}
+	if _, ok := report.Origins["extra.x"]; ok {
+		t.Fatalf("stale origin kept: %v", report.Origins)
+	}
+	if got := report.Origins["extra"]; !strings.HasSuffix(got, "b.yaml") {
+		t.Fatalf("unexpected origin for extra: %s", got)
+	}
+	if got := report.Origins["region"]; got != "env:REGION" {
+		t.Fatalf("unexpected origin for region: %s", got)
+	}
+	if dump := report.Dump(); !strings.Contains(dump, "region = us (env:REGION)") {
+		t.Fatalf("unexpected dump:\n%s", dump)
+	}
+}
+
+func TestIsSecretKey(t *testing.T) {
+	for key, want := range map[string]bool{
+		"db.password":        true,
+		"DB_PASSWORD":        true,
+		"jwt_secret":         true,
+		"oauth.accessToken":  true,
+		"aws.secret_key":     true,
+		"db.dsn":             true,
+		"llm.max_tokens":     false,
+		"session.token_ttl":  false,
+		"secret.path":        false,
+		"private_key_path":   false,
+		"credentials_source": false,
+	} {
+		if got := IsSecretKey(key); got != want {
+			t.Errorf("IsSecretKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
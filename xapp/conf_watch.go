@@ -0,0 +1,222 @@
+package xapp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xutil"
+)
+
+var defaultConfDebounce = 200 * time.Millisecond
+
+type WatchConfOption func(*watchConfOptions)
+
+type watchConfOptions struct {
+	debounce time.Duration
+	files    []string
+}
+
+// WithDebounce 合并 debounce 时间内的多次文件变更，默认 200ms
+func WithDebounce(d time.Duration) WatchConfOption {
+	return func(o *watchConfOptions) {
+		o.debounce = d
+	}
+}
+
+// WithConfFiles 指定配置文件名，默认 conf.yaml + conf.{env}.yaml
+func WithConfFiles(files ...string) WatchConfOption {
+	return func(o *watchConfOptions) {
+		o.files = files
+	}
+}
+
+// Conf 是并发安全的配置句柄，配置文件变化时整体替换
+type Conf[T any] struct {
+	value  atomic.Pointer[T]
+	files  []string
+	opts   *watchConfOptions
+	subsMu sync.Mutex
+	subs   map[int]func(old, new T)
+	nextID int
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// WatchConf 加载配置并在所有环境下监听文件变化。
+// 新配置实现 Validator 时，校验通过后才会替换，失败则保留旧配置
+func WatchConf[T any](opts ...WatchConfOption) (*Conf[T], error) {
+	o := &watchConfOptions{debounce: defaultConfDebounce}
+	for _, opt := range opts {
+		opt(o)
+	}
+	files := o.files
+	if len(files) == 0 {
+		files = getConfFile()
+	}
+
+	c := &Conf[T]{
+		files: files,
+		opts:  o,
+		subs:  map[int]func(old, new T){},
+		done:  make(chan struct{}),
+	}
+	v, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	c.value.Store(v)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("创建监听器失败: %v", err)
+	}
+	// 监听目录而不是文件，编辑器通过 rename 保存或文件新建时也能收到事件
+	for _, dir := range confDir {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(absDir); err != nil || !info.IsDir() {
+			continue
+		}
+		if err := watcher.Add(absDir); err != nil {
+			_ = watcher.Close()
+			return nil, fmt.Errorf("添加目录监听失败 %s: %v", absDir, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	xutil.Go(ctx, func() {
+		defer close(c.done)
+		defer watcher.Close()
+		c.watch(ctx, watcher)
+	})
+	return c, nil
+}
+
+// Load 返回当前配置
+func (c *Conf[T]) Load() T {
+	return *c.value.Load()
+}
+
+// Subscribe 注册配置变更回调，返回取消订阅函数
+func (c *Conf[T]) Subscribe(fn func(old, new T)) func() {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	id := c.nextID
+	c.nextID++
+	c.subs[id] = fn
+	return func() {
+		c.subsMu.Lock()
+		defer c.subsMu.Unlock()
+		delete(c.subs, id)
+	}
+}
+
+// Reload 立即重新加载配置
+func (c *Conf[T]) Reload() error {
+	next, err := c.load()
+	if err != nil {
+		return err
+	}
+	old := c.value.Swap(next)
+
+	c.subsMu.Lock()
+	subs := make([]func(old, new T), 0, len(c.subs))
+	for _, fn := range c.subs {
+		subs = append(subs, fn)
+	}
+	c.subsMu.Unlock()
+
+	for _, fn := range subs {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					xlog.Error("配置变更回调 panic", xlog.Any("error", r))
+				}
+			}()
+			fn(*old, *next)
+		}()
+	}
+	return nil
+}
+
+// Close 停止监听
+func (c *Conf[T]) Close() {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.done
+	}
+}
+
+func (c *Conf[T]) load() (*T, error) {
+	v := new(T)
+	if _, err := fileConf(v, c.files...); err != nil {
+		return nil, err
+	}
+	if validator, ok := any(v).(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, fmt.Errorf("配置校验失败: %w", err)
+		}
+	}
+	return v, nil
+}
+
+func (c *Conf[T]) isConfFile(name string) bool {
+	base := filepath.Base(name)
+	for _, file := range c.files {
+		if base == filepath.Base(file) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Conf[T]) watch(ctx context.Context, watcher *fsnotify.Watcher) {
+	timer := time.NewTimer(c.opts.debounce)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !c.isConfFile(event.Name) || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(c.opts.debounce)
+		case <-timer.C:
+			if err := c.Reload(); err != nil {
+				xlog.Error("重新加载配置失败", xlog.Err(err))
+			} else {
+				xlog.Info("配置已重新加载", xlog.String("files", fmt.Sprint(c.files)))
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			xlog.Error("监听错误", xlog.Err(err))
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package xapp
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type watchTestConf struct {
+	Bind string `yaml:"bind"`
+	Port int    `yaml:"port"`
+}
+
+func (c *watchTestConf) Validate() error {
+	if c.Port <= 0 {
+		return errors.New("port must be positive")
+	}
+	return nil
+}
+
+func TestWatchConfReloadsAndNotifies(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFile(t, filepath.Join(tempDir, "conf.yaml"), "bind: first\nport: 80\n")
+
+	originalConfDir := confDir
+	originalAppEnv := Args.AppEnv
+	confDir = []string{tempDir}
+	Args.AppEnv = "prod"
+	defer func() {
+		confDir = originalConfDir
+		Args.AppEnv = originalAppEnv
+	}()
+
+	conf, err := WatchConf[watchTestConf](WithDebounce(20 * time.Millisecond))
+	if err != nil {
+		t.Fatalf("WatchConf failed: %v", err)
+	}
+	defer conf.Close()
+
+	if conf.Load().Bind != "first" {
+		t.Fatalf("unexpected initial config: %+v", conf.Load())
+	}
+
+	changes := make(chan [2]string, 4)
+	conf.Subscribe(func(old, new watchTestConf) {
+		changes <- [2]string{old.Bind, new.Bind}
+	})
+
+	writeFile(t, filepath.Join(tempDir, "conf.yaml"), "bind: second\nport: 80\n")
+	select {
+	case change := <-changes:
+		if change != [2]string{"first", "second"} {
+			t.Fatalf("unexpected change: %v", change)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscriber was not notified")
+	}
+
+	// 校验失败时保留旧配置
+	writeFile(t, filepath.Join(tempDir, "conf.yaml"), "bind: invalid\nport: 0\n")
+	time.Sleep(200 * time.Millisecond)
+	if conf.Load().Bind != "second" {
+		t.Fatalf("invalid config should not be applied, got %+v", conf.Load())
+	}
+}
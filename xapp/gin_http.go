@@ -19,8 +19,8 @@ import (
 	"github.com/shopspring/decimal"
 
 	"github.com/daodao97/xgo/utils"
-	"github.com/daodao97/xgo/xcode"
 	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xmetrics"
 	"github.com/daodao97/xgo/xtrace"
 	"github.com/daodao97/xgo/xutil"
 )
@@ -50,10 +50,12 @@ type AppOption func(*AppOptions)
 
 type AppOptions struct {
 	PrintReqeustLog bool
+	Metrics         bool
 }
 
 var defaultAppOptions = &AppOptions{
 	PrintReqeustLog: true,
+	Metrics:         true,
 }
 
 func WithPrintReqeustLog(printReqeustLog bool) AppOption {
@@ -62,10 +64,18 @@ func WithPrintReqeustLog(printReqeustLog bool) AppOption {
 	}
 }
 
+// WithMetrics 是否记录 xmetrics 的 HTTP 指标，默认开启，指标通过 xmetrics.RegisterRoutes 或 App.AddMetricsServer 输出
+func WithMetrics(enable bool) AppOption {
+	return func(options *AppOptions) {
+		options.Metrics = enable
+	}
+}
+
 func NewGin(opts ...AppOption) *gin.Engine {
-	appOptions := defaultAppOptions
+	// 复制默认值，避免选项修改影响之后的 NewGin
+	appOptions := *defaultAppOptions
 	for _, opt := range opts {
-		opt(appOptions)
+		opt(&appOptions)
 	}
 
 	// 添加 decimal 类型验证支持
@@ -98,6 +108,9 @@ func NewGin(opts ...AppOption) *gin.Engine {
 	r := gin.New()
 	// r.Use(gin.Recovery())
 	r.Use(xtrace.TraceId())
+	if appOptions.Metrics {
+		r.Use(xmetrics.Gin())
+	}
 	r.Use(func(c *gin.Context) {
 		if !appOptions.PrintReqeustLog {
 			c.Next()
@@ -281,18 +294,12 @@ func HanderFunc[Req any, Resp any](handler func(*gin.Context, Req) (*Resp, error
 		// 如果是文件上传请求，先处理文件
 		if isMultipart {
 			if err := c.Request.ParseMultipartForm(MaxMultipartFormSize); err != nil {
-				c.JSON(200, gin.H{
-					"code":    400,
-					"message": "文件上传失败: " + err.Error(),
-				})
+				renderer.RenderError(c, &ValidationError{Message: "文件上传失败: " + err.Error(), Err: err})
 				return
 			}
 			// 对于文件上传请求，直接使用 ShouldBind
 			if err := c.ShouldBind(&req); err != nil {
-				c.JSON(200, gin.H{
-					"code":    400,
-					"message": translateError(err),
-				})
+				renderer.RenderError(c, newValidationError(err, reflect.TypeOf(req)))
 				return
 			}
 		} else {
@@ -300,44 +307,21 @@ func HanderFunc[Req any, Resp any](handler func(*gin.Context, Req) (*Resp, error
 			c.ShouldBindQuery(&req)
 			err3 := c.ShouldBind(&req)
 			if err3 != nil && !errors.Is(err3, io.EOF) && !errors.Is(err3, io.ErrUnexpectedEOF) {
-				c.JSON(200, gin.H{
-					"code":    400,
-					"message": translateError(err3),
-				})
+				renderer.RenderError(c, newValidationError(err3, reflect.TypeOf(req)))
 				return
 			}
 		}
 
 		if validator, ok := any(&req).(Validator); ok {
 			if err := validator.Validate(); err != nil {
-				c.JSON(200, gin.H{
-					"code":    400,
-					"message": translateError(err),
-				})
+				renderer.RenderError(c, newValidationError(err, reflect.TypeOf(req)))
 				return
 			}
 		}
 
 		resp, err := handler(c, req)
 		if err != nil {
-			// 判断错误是否是 xcode.Code 类型
-			var codeErr *xcode.Code
-			if errors.As(err, &codeErr) {
-				httpCode := codeErr.HttpCode
-				if httpCode == 0 {
-					httpCode = 500 // 如果未设置 HttpCode，默认使用 500
-				}
-				c.JSON(httpCode, gin.H{
-					"code":    codeErr.Code,
-					"message": codeErr.Message,
-				})
-				return
-			}
-			// 如果不是 xcode.Code 类型，使用默认错误码 500
-			c.JSON(500, gin.H{
-				"code":    500,
-				"message": err.Error(),
-			})
+			renderer.RenderError(c, err)
 			return
 		}
 
@@ -349,16 +333,12 @@ func HanderFunc[Req any, Resp any](handler func(*gin.Context, Req) (*Resp, error
 			return
 		}
 
-		body := gin.H{
-			"code":    SuccessCode,
-			"message": "success",
-		}
-
+		// 避免 nil 指针被包装成非 nil 的 any
+		var data any
 		if resp != nil {
-			body["data"] = resp
+			data = resp
 		}
-
-		c.JSON(200, body)
+		renderer.Render(c, data)
 	}
 }
 
@@ -0,0 +1,10 @@
+package xapp
+
+import "testing"
+
+func TestNewGinOptionsDoNotLeak(t *testing.T) {
+	NewGin(WithMetrics(false), WithPrintReqeustLog(false))
+	if !defaultAppOptions.Metrics || !defaultAppOptions.PrintReqeustLog {
+		t.Fatalf("options leaked into defaults: %+v", *defaultAppOptions)
+	}
+}
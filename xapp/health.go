@@ -0,0 +1,260 @@
+package xapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/daodao97/xgo/xdb"
+	"github.com/daodao97/xgo/xqueue"
+	"github.com/daodao97/xgo/xredis"
+)
+
+// HealthCheck 返回 nil 表示组件健康
+type HealthCheck func(ctx context.Context) error
+
+const (
+	HealthStatusOK           = "ok"
+	HealthStatusFail         = "fail"
+	HealthStatusStarting     = "starting"
+	HealthStatusShuttingDown = "shutting_down"
+)
+
+var defaultHealthCheckTimeout = 3 * time.Second
+
+var (
+	globalChecksMu sync.RWMutex
+	globalChecks   = map[string]HealthCheck{}
+)
+
+// RegisterHealthCheck 注册全局健康检查，所有 App 的 /healthz、/readyz 都会执行
+func RegisterHealthCheck(name string, check HealthCheck) {
+	if check == nil {
+		return
+	}
+	globalChecksMu.Lock()
+	defer globalChecksMu.Unlock()
+	globalChecks[name] = check
+}
+
+// DBHealthCheck 检查 xdb 连接
+func DBHealthCheck(conn string) HealthCheck {
+	return func(ctx context.Context) error {
+		return xdb.Ping(ctx, conn)
+	}
+}
+
+// RedisHealthCheck 检查 xredis 客户端，name 为空时检查默认客户端
+func RedisHealthCheck(name string) HealthCheck {
+	return func(ctx context.Context) error {
+		return xredis.Ping(ctx, name)
+	}
+}
+
+// QueueHealthCheck 检查 xqueue 订阅状态
+func QueueHealthCheck(topic string) HealthCheck {
+	return func(ctx context.Context) error {
+		return xqueue.Healthy(topic)
+	}
+}
+
+type CheckResult struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type Health struct {
+	mu           sync.RWMutex
+	checks       map[string]HealthCheck
+	timeout      time.Duration
+	ready        atomic.Bool
+	shuttingDown atomic.Bool
+}
+
+func NewHealth() *Health {
+	return &Health{
+		checks:  map[string]HealthCheck{},
+		timeout: defaultHealthCheckTimeout,
+	}
+}
+
+func (h *Health) Register(name string, check HealthCheck) *Health {
+	if check == nil {
+		return h
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+	return h
+}
+
+// SetTimeout 设置单个检查的超时时间
+func (h *Health) SetTimeout(timeout time.Duration) *Health {
+	if timeout > 0 {
+		h.timeout = timeout
+	}
+	return h
+}
+
+func (h *Health) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// Shutdown 标记进入关闭流程，之后 /readyz 一直返回 503
+func (h *Health) Shutdown() {
+	h.shuttingDown.Store(true)
+	h.ready.Store(false)
+}
+
+func (h *Health) IsReady() bool {
+	return h.ready.Load() && !h.shuttingDown.Load()
+}
+
+// builtinChecks 为已初始化的 xdb 连接和 xredis 客户端生成检查，同名的注册检查优先
+func builtinChecks() map[string]HealthCheck {
+	checks := map[string]HealthCheck{}
+	for _, conn := range xdb.Conns() {
+		checks["xdb:"+conn] = DBHealthCheck(conn)
+	}
+	if xredis.Get() != nil {
+		checks["xredis"] = RedisHealthCheck("")
+	}
+	for _, name := range xredis.Names() {
+		if name != "default" {
+			checks["xredis:"+name] = RedisHealthCheck(name)
+		}
+	}
+	return checks
+}
+
+func (h *Health) allChecks() map[string]HealthCheck {
+	checks := builtinChecks()
+	globalChecksMu.RLock()
+	for name, check := range globalChecks {
+		checks[name] = check
+	}
+	globalChecksMu.RUnlock()
+
+	h.mu.RLock()
+	for name, check := range h.checks {
+		checks[name] = check
+	}
+	h.mu.RUnlock()
+	return checks
+}
+
+// Check 并发执行所有检查
+func (h *Health) Check(ctx context.Context) HealthReport {
+	checks := h.allChecks()
+	report := HealthReport{
+		Status: HealthStatusOK,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]CheckResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			results[i] = h.runCheck(ctx, check)
+		}(i, checks[name])
+	}
+	wg.Wait()
+
+	for i, name := range names {
+		if results[i].Status != HealthStatusOK {
+			report.Status = HealthStatusFail
+		}
+		report.Checks[name] = results[i]
+	}
+	return report
+}
+
+func (h *Health) runCheck(ctx context.Context, check HealthCheck) (result CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			result.Status = HealthStatusFail
+			result.Error = fmt.Sprintf("panic: %v", r)
+		}
+		result.Latency = time.Since(start).String()
+	}()
+
+	if err := check(ctx); err != nil {
+		return CheckResult{Status: HealthStatusFail, Error: err.Error()}
+	}
+	return CheckResult{Status: HealthStatusOK}
+}
+
+// LivenessHandler 进程存活即返回 200
+func (h *Health) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, HealthReport{Status: HealthStatusOK})
+}
+
+// HealthHandler 执行所有检查
+func (h *Health) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, h.Check(r.Context()))
+}
+
+// ReadinessHandler 服务未就绪或正在关闭时返回 503，否则执行所有检查
+func (h *Health) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	if h.shuttingDown.Load() {
+		writeHealthReport(w, HealthReport{Status: HealthStatusShuttingDown})
+		return
+	}
+	if !h.ready.Load() {
+		writeHealthReport(w, HealthReport{Status: HealthStatusStarting})
+		return
+	}
+	writeHealthReport(w, h.Check(r.Context()))
+}
+
+// Handler 提供 /healthz、/readyz、/livez
+func (h *Health) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", h.HealthHandler)
+	mux.HandleFunc("/readyz", h.ReadinessHandler)
+	mux.HandleFunc("/livez", h.LivenessHandler)
+	return mux
+}
+
+// RegisterRoutes 在 gin 路由上挂载健康检查接口
+func (h *Health) RegisterRoutes(r gin.IRoutes) {
+	r.GET("/healthz", gin.WrapF(h.HealthHandler))
+	r.GET("/readyz", gin.WrapF(h.ReadinessHandler))
+	r.GET("/livez", gin.WrapF(h.LivenessHandler))
+}
+
+func writeHealthReport(w http.ResponseWriter, report HealthReport) {
+	code := http.StatusOK
+	if report.Status != HealthStatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,159 @@
+package xapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	_ "modernc.org/sqlite"
+
+	"github.com/daodao97/xgo/xdb"
+	"github.com/daodao97/xgo/xqueue"
+	"github.com/daodao97/xgo/xredis"
+)
+
+func TestHealthReportsPerCheckStatus(t *testing.T) {
+	h := NewHealth().
+		Register("ok", func(ctx context.Context) error { return nil }).
+		Register("bad", func(ctx context.Context) error { return errors.New("down") })
+
+	rec := httptest.NewRecorder()
+	h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+
+	var report HealthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if report.Checks["ok"].Status != HealthStatusOK {
+		t.Fatalf("unexpected ok check: %+v", report.Checks["ok"])
+	}
+	if report.Checks["bad"].Status != HealthStatusFail || report.Checks["bad"].Error != "down" {
+		t.Fatalf("unexpected bad check: %+v", report.Checks["bad"])
+	}
+	if report.Checks["bad"].Latency == "" {
+		t.Fatal("expected latency to be reported")
+	}
+}
+
+func TestHealthCheckTimeout(t *testing.T) {
+	h := NewHealth().SetTimeout(20*time.Millisecond).
+		Register("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+	report := h.Check(context.Background())
+	if report.Status != HealthStatusFail {
+		t.Fatalf("expected fail, got %s", report.Status)
+	}
+}
+
+func TestAppRunFlipsReadiness(t *testing.T) {
+	s := newReadyTestServer()
+	injectedSignals := make(chan os.Signal, 1)
+
+	oldNotify := signalNotify
+	oldStop := signalStop
+	signalNotify = func(c chan<- os.Signal, _ ...os.Signal) {
+		go func() {
+			c <- <-injectedSignals
+		}()
+	}
+	signalStop = func(chan<- os.Signal) {}
+	defer func() {
+		signalNotify = oldNotify
+		signalStop = oldStop
+	}()
+
+	app := NewApp().AddServer(func() Server { return s })
+	readyz := func() int {
+		rec := httptest.NewRecorder()
+		app.Health().Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	runDone := make(chan error, 1)
+	go func() {
+		runDone <- app.Run()
+	}()
+
+	if code := readyz(); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 before ready, got %d", code)
+	}
+
+	close(s.started)
+	waitFor(t, time.Second, func() bool {
+		return readyz() == http.StatusOK
+	})
+
+	injectedSignals <- os.Interrupt
+	select {
+	case err := <-runDone:
+		if err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not exit after shutdown signal")
+	}
+
+	if code := readyz(); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 after shutdown, got %d", code)
+	}
+}
+
+func TestHealthBuiltinChecks(t *testing.T) {
+	err := xdb.Inits([]xdb.Config{{Name: "health_test", Driver: "sqlite", DSN: filepath.Join(t.TempDir(), "health.db")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xdb.Close()
+	// 默认客户端连接失败时仍然保留，检查应报告失败
+	_ = xredis.Init(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer xredis.Close()
+
+	report := NewHealth().Check(context.Background())
+	if report.Checks["xdb:health_test"].Status != HealthStatusOK {
+		t.Fatalf("unexpected xdb check: %+v", report.Checks)
+	}
+	if report.Checks["xredis"].Status != HealthStatusFail || report.Status != HealthStatusFail {
+		t.Fatalf("expected xredis check to fail: %+v", report)
+	}
+}
+
+func TestQueueWorkerHealthCheck(t *testing.T) {
+	q := xqueue.AddMemoryQueue("health_test", func(string) {}, 1)
+	worker := xqueue.NewQueueWorker(q)
+	app := NewApp().AddServer(func() Server { return worker })
+
+	hs, ok := Server(worker).(healthCheckServer)
+	if !ok {
+		t.Fatal("expected QueueWorker to provide a health check")
+	}
+	if hs.HealthCheckName() != "xqueue:health_test" {
+		t.Fatalf("unexpected name %q", hs.HealthCheckName())
+	}
+	app.AddHealthCheck(hs.HealthCheckName(), hs.HealthCheck)
+	if report := app.Health().Check(context.Background()); report.Checks["xqueue:health_test"].Status != HealthStatusFail {
+		t.Fatalf("expected unsubscribed queue to fail: %+v", report)
+	}
+
+	go worker.Start()
+	defer worker.Stop()
+	deadline := time.Now().Add(time.Second)
+	for app.Health().Check(context.Background()).Status != HealthStatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for queue subscription")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -21,6 +21,7 @@ func NewHttp(addr string, handler func() http.Handler) NewServer {
 
 type HTTPServer struct {
 	server      *http.Server
+	listen      ListenFunc
 	started     chan struct{}
 	startedOnce sync.Once
 }
@@ -43,7 +44,11 @@ func NewHTTPServer(addr string, handler func() http.Handler) *HTTPServer {
 
 func (s *HTTPServer) Start() error {
 	xlog.Debug("Starting HTTP server on", xlog.String("port", s.server.Addr))
-	ln, err := net.Listen("tcp", s.server.Addr)
+	listen := s.listen
+	if listen == nil {
+		listen = net.Listen
+	}
+	ln, err := listen("tcp", s.server.Addr)
 	if err != nil {
 		return err
 	}
@@ -57,19 +62,28 @@ func (s *HTTPServer) Start() error {
 	return err
 }
 
+// SetListenFunc 替换默认的 net.Listen，启用平滑升级时由 App 注入
+func (s *HTTPServer) SetListenFunc(fn ListenFunc) {
+	s.listen = fn
+}
+
 func (s *HTTPServer) Started() <-chan struct{} {
 	return s.started
 }
 
 func (s *HTTPServer) Stop() {
-	xlog.Debug("Stopping HTTP server")
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
+	_ = s.Shutdown(ctx)
+}
 
-	if err := s.server.Shutdown(ctx); err != nil {
-		if err == context.DeadlineExceeded {
-			xlog.Warn("HTTP server graceful shutdown timed out, forcing close",
-				xlog.Duration("timeout", 30*time.Second))
+// Shutdown 在 ctx 截止前排空请求，超时则强制关闭连接
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	xlog.Debug("Stopping HTTP server")
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			xlog.Warn("HTTP server graceful shutdown timed out, forcing close")
 			if err := s.server.Close(); err != nil {
 				xlog.Error("HTTP server force close failed", xlog.Err(err))
 			}
@@ -81,4 +95,5 @@ func (s *HTTPServer) Stop() {
 	}
 
 	xlog.Debug("Stop HTTP server done")
+	return err
 }
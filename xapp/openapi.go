@@ -6,12 +6,18 @@ import (
 	"net/http"
 	"os"
 	"reflect"
-	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
 
 	"github.com/daodao97/xgo/utils"
+	"github.com/daodao97/xgo/xcode"
 	"github.com/daodao97/xgo/xlog"
-	"github.com/gin-gonic/gin"
 )
 
 func WithBearerAuth() OpenAPIOption {
@@ -131,15 +137,17 @@ type OpenAPIInfo struct {
 }
 
 type PathItem struct {
-	Get    *Operation `json:"get,omitempty"`
-	Post   *Operation `json:"post,omitempty"`
-	Put    *Operation `json:"put,omitempty"`
-	Delete *Operation `json:"delete,omitempty"`
-	Patch  *Operation `json:"patch,omitempty"`
-	// 其他HTTP方法...
+	Get     *Operation `json:"get,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Options *Operation `json:"options,omitempty"`
 }
 
 type Operation struct {
+	OperationID string              `json:"operationId,omitempty"`
 	Tags        []string            `json:"tags,omitempty"`
 	Summary     string              `json:"summary"`
 	Description string              `json:"description"`
@@ -158,16 +166,24 @@ type Parameter struct {
 }
 
 type RequestBody struct {
-	Content map[string]MediaType `json:"content"`
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
 }
 
 type Response struct {
 	Description string               `json:"description"`
-	Content     map[string]MediaType `json:"content"`
+	Content     map[string]MediaType `json:"content,omitempty"`
 }
 
 type MediaType struct {
-	Schema Schema `json:"schema"`
+	Schema   Schema             `json:"schema"`
+	Example  any                `json:"example,omitempty"`
+	Examples map[string]Example `json:"examples,omitempty"`
+}
+
+type Example struct {
+	Summary string `json:"summary,omitempty"`
+	Value   any    `json:"value"`
 }
 
 type Schema struct {
@@ -179,402 +195,228 @@ type Schema struct {
 	Required             []string          `json:"required,omitempty"`
 	Format               string            `json:"format,omitempty"`
 	Enum                 []any             `json:"enum,omitempty"`
+	Default              any               `json:"default,omitempty"`
+	Examples             []any             `json:"examples,omitempty"`
 	Minimum              *float64          `json:"minimum,omitempty"`
 	Maximum              *float64          `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64          `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64          `json:"exclusiveMaximum,omitempty"`
 	MinLength            *int              `json:"minLength,omitempty"`
 	MaxLength            *int              `json:"maxLength,omitempty"`
+	MinItems             *int              `json:"minItems,omitempty"`
+	MaxItems             *int              `json:"maxItems,omitempty"`
+	UniqueItems          bool              `json:"uniqueItems,omitempty"`
 	Pattern              string            `json:"pattern,omitempty"`
 	Ref                  string            `json:"$ref,omitempty"`
 }
 
-func generateSchema(t reflect.Type) Schema {
-	schema := Schema{
-		Properties: make(map[string]Schema),
+var (
+	apiRegistryMu sync.Mutex
+	apiRegistry   []APIInfo
+)
+
+type APIInfo struct {
+	Path            string
+	Method          string
+	RequestType     reflect.Type
+	ResponseType    reflect.Type
+	Handler         gin.HandlerFunc
+	OperationID     string
+	Summary         string
+	Description     string
+	Tags            []string
+	Deprecated      bool
+	Accept          string
+	Produce         string
+	Errors          []*xcode.Code
+	RequestExample  any
+	ResponseExample any
+}
+
+// APIOption 在代码中声明路由文档，替代从源码注释中解析
+type APIOption func(*APIInfo)
+
+func WithSummary(summary string) APIOption {
+	return func(info *APIInfo) {
+		info.Summary = summary
 	}
+}
 
-	switch t.Kind() {
-	case reflect.Struct:
-		// 检查是否为 decimal.Decimal 类型
-		if t.String() == "decimal.Decimal" {
-			schema.Type = "string"
-			schema.Format = "decimal"
-			return schema
-		}
+func WithDescription(description string) APIOption {
+	return func(info *APIInfo) {
+		info.Description = description
+	}
+}
 
-		if t.String() == "time.Time" {
-			schema.Type = "string"
-			schema.Format = "date-time"
-			return schema
-		}
+func WithTags(tags ...string) APIOption {
+	return func(info *APIInfo) {
+		info.Tags = append(info.Tags, tags...)
+	}
+}
 
-		schema.Type = "object"
-		var required []string
-		for i := 0; i < t.NumField(); i++ {
-			field := t.Field(i)
-			if field.Anonymous {
-				// 处理匿名字段（嵌入式结构体）
-				embeddedSchema := generateSchema(field.Type)
-				for k, v := range embeddedSchema.Properties {
-					schema.Properties[k] = v
-				}
-				required = append(required, embeddedSchema.Required...)
-			} else {
-				if uri := field.Tag.Get("uri"); uri != "" {
-					continue
-				}
-				jsonTag := field.Tag.Get("json")
-				if jsonTag == "-" {
-					continue // 跳过被标记为忽略的字段
-				}
-				tag := jsonTag
-				if tag == "" {
-					formTag := field.Tag.Get("form")
-					if formTag == "-" {
-						continue
-					}
-					tag = formTag
-				}
-				fieldName := strings.Split(tag, ",")[0]
+func WithOperationID(id string) APIOption {
+	return func(info *APIInfo) {
+		info.OperationID = id
+	}
+}
 
-				if fieldName == "" {
-					fieldName = field.Name
-				}
-				fieldSchema := generateSchema(field.Type)
-				fieldSchema.Description = generateDescription(field)
-				schema.Properties[fieldName] = fieldSchema
+func WithDeprecated() APIOption {
+	return func(info *APIInfo) {
+		info.Deprecated = true
+	}
+}
 
-				if isRequired(field) {
-					required = append(required, fieldName)
-				}
-			}
-		}
-		if len(required) > 0 {
-			schema.Required = required
-		}
-	case reflect.Ptr:
-		return generateSchema(t.Elem())
-	case reflect.Slice, reflect.Array:
-		schema.Type = "array"
-		schema.Items = &Schema{
-			Properties: generateSchema(t.Elem()).Properties,
-		}
-	case reflect.Map:
-		schema.Type = "object"
-		schema.AdditionalProperties = &Schema{
-			Properties: generateSchema(t.Elem()).Properties,
-		}
-	case reflect.String:
-		schema.Type = "string"
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		schema.Type = "integer"
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		schema.Type = "integer"
-	case reflect.Float32, reflect.Float64:
-		schema.Type = "number"
-	case reflect.Bool:
-		schema.Type = "boolean"
-	default:
-		schema.Type = "string" // 默认处理为字符串
-	}
-
-	return schema
-}
-
-func generateDescription(field reflect.StructField) string {
-	var desc []string
-
-	// 获取 comment 标签
-	if comment := field.Tag.Get("comment"); comment != "" {
-		desc = append(desc, comment)
-	}
-
-	// 解析 default 标签
-	if defaultVal := field.Tag.Get("default"); defaultVal != "" {
-		desc = append(desc, fmt.Sprintf("默认值: %s", defaultVal))
-	}
-
-	// 解析 binding 标签
-	if binding := field.Tag.Get("binding"); binding != "" {
-		bindingRules := strings.Split(binding, ",")
-		for _, rule := range bindingRules {
-			switch {
-			case rule == "required":
-				desc = append(desc, "此字段是必需的")
-			case rule == "email":
-				desc = append(desc, "必须是有效的电子邮件地址")
-			case rule == "url":
-				desc = append(desc, "必须是有效的URL")
-			case strings.HasPrefix(rule, "min="):
-				desc = append(desc, fmt.Sprintf("最小值为 %s", strings.TrimPrefix(rule, "min=")))
-			case strings.HasPrefix(rule, "max="):
-				desc = append(desc, fmt.Sprintf("最大值为 %s", strings.TrimPrefix(rule, "max=")))
-			case strings.HasPrefix(rule, "len="):
-				desc = append(desc, fmt.Sprintf("长度必须为 %s", strings.TrimPrefix(rule, "len=")))
-			case strings.HasPrefix(rule, "oneof="):
-				desc = append(desc, fmt.Sprintf("枚举值: %s", strings.ReplaceAll(strings.TrimPrefix(rule, "oneof="), " ", ", ")))
-			case strings.HasPrefix(rule, "eq="):
-				desc = append(desc, fmt.Sprintf("必须等于 %s", strings.TrimPrefix(rule, "eq=")))
-			case strings.HasPrefix(rule, "ne="):
-				desc = append(desc, fmt.Sprintf("不能等于 %s", strings.TrimPrefix(rule, "ne=")))
-			case strings.HasPrefix(rule, "lt="):
-				desc = append(desc, fmt.Sprintf("必须小于 %s", strings.TrimPrefix(rule, "lt=")))
-			case strings.HasPrefix(rule, "lte="):
-				desc = append(desc, fmt.Sprintf("必须小于或等于 %s", strings.TrimPrefix(rule, "lte=")))
-			case strings.HasPrefix(rule, "gt="):
-				desc = append(desc, fmt.Sprintf("必须大于 %s", strings.TrimPrefix(rule, "gt=")))
-			case strings.HasPrefix(rule, "gte="):
-				desc = append(desc, fmt.Sprintf("必须大于或等于 %s", strings.TrimPrefix(rule, "gte=")))
-			case rule == "alpha":
-				desc = append(desc, "只能包含字母")
-			case rule == "alphanum":
-				desc = append(desc, "只能包含字母和数字")
-			case rule == "numeric":
-				desc = append(desc, "必须是数字")
-			case strings.HasPrefix(rule, "eqfield="):
-				desc = append(desc, fmt.Sprintf("必须等于 %s 字段", strings.TrimPrefix(rule, "eqfield=")))
-			case strings.HasPrefix(rule, "nefield="):
-				desc = append(desc, fmt.Sprintf("不能等于 %s 字段", strings.TrimPrefix(rule, "nefield=")))
-			case strings.HasPrefix(rule, "gtfield="):
-				desc = append(desc, fmt.Sprintf("必须大于 %s 字段", strings.TrimPrefix(rule, "gtfield=")))
-			case strings.HasPrefix(rule, "gtefield="):
-				desc = append(desc, fmt.Sprintf("必须大于或等于 %s 字段", strings.TrimPrefix(rule, "gtefield=")))
-			case strings.HasPrefix(rule, "ltfield="):
-				desc = append(desc, fmt.Sprintf("必须小于 %s 字段", strings.TrimPrefix(rule, "ltfield=")))
-			case strings.HasPrefix(rule, "ltefield="):
-				desc = append(desc, fmt.Sprintf("必须小于或等于 %s 字段", strings.TrimPrefix(rule, "ltefield=")))
-			case rule == "isdefault":
-				desc = append(desc, "必须是默认值")
-			case rule == "unique":
-				desc = append(desc, "必须是唯一的")
-			case rule == "alphaunicode":
-				desc = append(desc, "只能包含 unicode 字符")
-			case rule == "alphanumunicode":
-				desc = append(desc, "只能包含 unicode 字母和数字")
-			case rule == "lowercase":
-				desc = append(desc, "只能包含小写字符")
-			case rule == "uppercase":
-				desc = append(desc, "只能包含大写字符")
-			case rule == "json":
-				desc = append(desc, "必须是有效的 JSON")
-			case rule == "file":
-				desc = append(desc, "必须是有效的文件路径")
-			case rule == "uri":
-				desc = append(desc, "必须是有效的 URI")
-			case rule == "base64":
-				desc = append(desc, "必须是有效的 base64 值")
-			case strings.HasPrefix(rule, "contains="):
-				desc = append(desc, fmt.Sprintf("必须包含 %s", strings.TrimPrefix(rule, "contains=")))
-			case strings.HasPrefix(rule, "containsany="):
-				desc = append(desc, fmt.Sprintf("必须包含 %s 中的任何字符", strings.TrimPrefix(rule, "containsany=")))
-			case strings.HasPrefix(rule, "excludes="):
-				desc = append(desc, fmt.Sprintf("不能包含 %s", strings.TrimPrefix(rule, "excludes=")))
-			case strings.HasPrefix(rule, "excludesall="):
-				desc = append(desc, fmt.Sprintf("不能包含 %s 中的任何字符", strings.TrimPrefix(rule, "excludesall=")))
-			case strings.HasPrefix(rule, "startswith="):
-				desc = append(desc, fmt.Sprintf("必须以 %s 开始", strings.TrimPrefix(rule, "startswith=")))
-			case strings.HasPrefix(rule, "endswith="):
-				desc = append(desc, fmt.Sprintf("必须以 %s 结束", strings.TrimPrefix(rule, "endswith=")))
-			case rule == "ip":
-				desc = append(desc, "必须是有效的 IP 地址")
-			case rule == "ipv4":
-				desc = append(desc, "必须是有效的 IPv4 地址")
-			case rule == "datetime":
-				desc = append(desc, "必须是有效的日期时间")
-			case rule == "omitempty":
-				desc = append(desc, "非必须")
-			case rule == "datetime_range":
-				desc = append(desc, "格式必须是 'YYYY-MM-DD HH:mm:ss,YYYY-MM-DD HH:mm:ss' 且结束时间必须大于开始时间")
-			}
-		}
+// WithAccept 设置请求体的 Content-Type，多个用逗号分隔
+func WithAccept(accept string) APIOption {
+	return func(info *APIInfo) {
+		info.Accept = accept
 	}
+}
 
-	return strings.Join(desc, ", ")
+// WithProduce 设置响应体的 Content-Type，多个用逗号分隔
+func WithProduce(produce string) APIOption {
+	return func(info *APIInfo) {
+		info.Produce = produce
+	}
 }
 
-func isRequired(field reflect.StructField) bool {
-	binding := field.Tag.Get("binding")
-	return strings.Contains(binding, "required")
+// WithErrors 声明接口可能返回的错误码，按 HttpCode 分组生成响应文档
+func WithErrors(codes ...*xcode.Code) APIOption {
+	return func(info *APIInfo) {
+		info.Errors = append(info.Errors, codes...)
+	}
 }
 
-var apiRegistry []APIInfo
+func WithRequestExample(example any) APIOption {
+	return func(info *APIInfo) {
+		info.RequestExample = example
+	}
+}
 
-type APIInfo struct {
-	Path         string
-	Method       string
-	RequestType  reflect.Type
-	ResponseType reflect.Type
-	Handler      gin.HandlerFunc
-	Summary      string
-	Description  string
-	Tags         []string
-	Deprecated   bool
-	Accept       string
-	Produce      string
+// WithResponseExample 设置 data 字段的示例，文档中会包装为完整的响应体
+func WithResponseExample(example any) APIOption {
+	return func(info *APIInfo) {
+		info.ResponseExample = example
+	}
+}
+
+// handlerKey 返回闭包对象的地址。同一泛型实例化的 HanderFunc 闭包共享代码指针，
+// 只有闭包对象本身才能区分不同的路由
+func handlerKey(h gin.HandlerFunc) uintptr {
+	return *(*uintptr)(unsafe.Pointer(&h))
 }
 
 func CollectRouteInfo(engine *gin.Engine) {
+	apiRegistryMu.Lock()
+	defer apiRegistryMu.Unlock()
+
 	routes := engine.Routes()
 	for _, route := range routes {
+		key := handlerKey(route.HandlerFunc)
 		for i, info := range apiRegistry {
-			if info.Path == "" && reflect.ValueOf(route.HandlerFunc).Pointer() == reflect.ValueOf(info.Handler).Pointer() {
+			if handlerKey(info.Handler) != key {
+				continue
+			}
+			if info.Path == "" {
 				apiRegistry[i].Path = route.Path
 				apiRegistry[i].Method = route.Method
-				break
+			} else if info.Path != route.Path || info.Method != route.Method {
+				// 同一个 handler 挂在多个路由上
+				dup := info
+				dup.Path = route.Path
+				dup.Method = route.Method
+				if !hasAPI(dup.Method, dup.Path) {
+					apiRegistry = append(apiRegistry, dup)
+				}
 			}
+			break
 		}
 	}
 }
 
-func RegisterAPI[Req any, Resp any](handler func(*gin.Context, Req) (*Resp, error)) gin.HandlerFunc {
-	wrappedHandler := HanderFunc(handler)
-
-	if !Args.EnableOpenAPI {
-		return wrappedHandler
-	}
-
-	if utils.IsGoRun() {
-		reqType := reflect.TypeOf((*Req)(nil)).Elem()
-		respType := reflect.TypeOf((*Resp)(nil)).Elem()
-
-		// 获取handler函数的信息
-		handlerValue := reflect.ValueOf(handler)
-		handlerPtr := handlerValue.Pointer()
-		handlerFunc := runtime.FuncForPC(handlerPtr)
-		if handlerFunc == nil {
-			xlog.Error("无法获取handler函数信息")
-		} else {
-			fileName, _ := handlerFunc.FileLine(handlerPtr)
-			// fmt.Printf("Handler函数位置: %s:%d\n", fileName, lineNumber)
-
-			fileContent, err := os.ReadFile(fileName)
-			if err != nil {
-				xlog.Error("read file error", xlog.Err(err))
-			} else {
-				lines := strings.Split(string(fileContent), "\n")
-				funcName := handlerFunc.Name()
-				shortFuncName := funcName[strings.LastIndex(funcName, ".")+1:]
-				lineNumber := findFunctionDefinition(lines, shortFuncName)
-				// fmt.Println("funcName", funcName)
-				// fmt.Println("shortFuncName", shortFuncName)
-				// fmt.Println("lineNumber", lineNumber)
-				// fmt.Println("fileName", fileName)
-
-				var comments []string
-				for i := lineNumber - 2; i >= 0; i-- {
-					line := strings.TrimSpace(lines[i])
-					if strings.HasPrefix(line, "//") {
-						comments = append([]string{strings.TrimPrefix(line, "//")}, comments...)
-					} else {
-						break
-					}
-				}
-
-				commentResult := parseComments(strings.Join(comments, "\n"))
-				// fmt.Printf("提取的注释: summary=%s, description=%s\n", commentResult.Summary, commentResult.Description)
-
-				apiRegistry = append(apiRegistry, APIInfo{
-					RequestType:  reqType,
-					ResponseType: respType,
-					Handler:      wrappedHandler,
-					Summary:      commentResult.Summary,
-					Description:  commentResult.Description,
-					Tags:         commentResult.Tags,
-					Deprecated:   commentResult.Deprecated,
-					Accept:       commentResult.Accept,
-					Produce:      commentResult.Produce,
-				})
-			}
+func hasAPI(method, path string) bool {
+	for _, info := range apiRegistry {
+		if info.Method == method && info.Path == path {
+			return true
 		}
 	}
-
-	return wrappedHandler
+	return false
 }
 
-func findFunctionDefinition(lines []string, funcName string) int {
-	for i, line := range lines {
-		// 查找函数定义的模式
-		if strings.Contains(line, "func "+funcName) {
-			return i + 1
+// APIs 返回已注册并绑定到路由的接口
+func APIs(engine *gin.Engine) []APIInfo {
+	CollectRouteInfo(engine)
+
+	apiRegistryMu.Lock()
+	defer apiRegistryMu.Unlock()
+	apis := make([]APIInfo, 0, len(apiRegistry))
+	for _, info := range apiRegistry {
+		if info.Path != "" {
+			apis = append(apis, info)
 		}
 	}
-	return 0
-}
-
-type APIComment struct {
-	Summary     string
-	Description string
-	Tags        []string
-	Deprecated  bool
-	Accept      string
-	Produce     string
+	sort.SliceStable(apis, func(i, j int) bool {
+		if apis[i].Path != apis[j].Path {
+			return apis[i].Path < apis[j].Path
+		}
+		return apis[i].Method < apis[j].Method
+	})
+	return apis
 }
 
-func parseComments(comments string) APIComment {
-	result := APIComment{}
-	lines := strings.Split(comments, "\n")
+func RegisterAPI[Req any, Resp any](handler func(*gin.Context, Req) (*Resp, error), opts ...APIOption) gin.HandlerFunc {
+	wrappedHandler := HanderFunc(handler)
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		switch {
-		case strings.HasPrefix(line, "@summary"):
-			result.Summary = strings.TrimSpace(strings.TrimPrefix(line, "@summary"))
-		case strings.HasPrefix(line, "@description"):
-			result.Description = strings.TrimSpace(strings.TrimPrefix(line, "@description"))
-		case strings.HasPrefix(line, "@tags"):
-			tags := strings.TrimSpace(strings.TrimPrefix(line, "@tags"))
-			result.Tags = strings.Split(tags, ",")
-		case strings.HasPrefix(line, "@deprecated"):
-			result.Deprecated = true
-		case strings.HasPrefix(line, "@accept"):
-			result.Accept = strings.TrimSpace(strings.TrimPrefix(line, "@accept"))
-		case strings.HasPrefix(line, "@produce"):
-			result.Produce = strings.TrimSpace(strings.TrimPrefix(line, "@produce"))
-		}
+	info := APIInfo{
+		RequestType:  reflect.TypeOf((*Req)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*Resp)(nil)).Elem(),
+		Handler:      wrappedHandler,
+	}
+	for _, opt := range opts {
+		opt(&info)
 	}
-	return result
+
+	apiRegistryMu.Lock()
+	apiRegistry = append(apiRegistry, info)
+	apiRegistryMu.Unlock()
+
+	return wrappedHandler
 }
 
+// GenerateOpenAPIDoc 根据注册的接口生成 OpenAPI 3.1 文档，
+// 并注册 /openapi.json、/openapi.yaml、/docs 路由
 func GenerateOpenAPIDoc(engine *gin.Engine, options ...OpenAPIOption) ([]byte, error) {
 	if !Args.EnableOpenAPI {
 		return nil, fmt.Errorf("please use --enable-openapi true flag")
 	}
 
-	var doc OpenAPIDocument
-	var jsonDoc []byte
-	var err error
+	doc, err := genDoc(engine, options...)
+	if err != nil {
+		return nil, err
+	}
+	jsonDoc, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	yamlDoc, err := jsonToYAML(jsonDoc)
+	if err != nil {
+		return nil, err
+	}
 
+	// 开发模式下同时输出到文件，便于提交或给前端使用
 	if utils.IsGoRun() {
-		// 开发模式：解析并生成文档
-		doc, err = genDoc(engine, options...)
-		if err != nil {
-			return nil, err
-		}
-
-		// 将文档保存到文件
-		jsonDoc, err = json.MarshalIndent(doc, "", "  ")
-		if err != nil {
-			return nil, err
-		}
-		err = os.WriteFile("openapi.json", jsonDoc, 0644)
-		if err != nil {
+		if err := os.WriteFile("openapi.json", jsonDoc, 0644); err != nil {
 			xlog.Error("保存 openapi.json 失败", xlog.Err(err))
 		}
-	} else {
-		// 构建模式：直接读取保存的文件
-		jsonDoc, err = os.ReadFile("openapi.json")
-		if err != nil {
-			return nil, fmt.Errorf("读取 openapi.json 失败: %w", err)
-		}
-		err = json.Unmarshal(jsonDoc, &doc)
-		if err != nil {
-			return nil, fmt.Errorf("解析 openapi.json 失败: %w", err)
-		}
 	}
 
 	// 注册路由
 	engine.GET("/openapi.json", func(c *gin.Context) {
 		c.Data(http.StatusOK, "application/json", jsonDoc)
 	})
+	engine.GET("/openapi.yaml", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/yaml", yamlDoc)
+	})
 
 	// 注册 /docs 路由
 	engine.GET("/docs", func(c *gin.Context) {
@@ -607,11 +449,30 @@ func GenerateOpenAPIDoc(engine *gin.Engine, options ...OpenAPIOption) ([]byte, e
 	return jsonDoc, nil
 }
 
-func genDoc(engine *gin.Engine, options ...OpenAPIOption) (OpenAPIDocument, error) {
-	CollectRouteInfo(engine)
+// jsonToYAML 保持字段顺序地把 JSON 转为块格式的 YAML
+func jsonToYAML(data []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	var clearStyle func(n *yaml.Node)
+	clearStyle = func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode && n.Tag == "!!str" {
+			n.Style = 0
+		} else if n.Kind != yaml.ScalarNode {
+			n.Style = 0
+		}
+		for _, child := range n.Content {
+			clearStyle(child)
+		}
+	}
+	clearStyle(&node)
+	return yaml.Marshal(&node)
+}
 
+func genDoc(engine *gin.Engine, options ...OpenAPIOption) (OpenAPIDocument, error) {
 	doc := OpenAPIDocument{
-		OpenAPI: "3.0.0",
+		OpenAPI: "3.1.0",
 		Info: OpenAPIInfo{
 			Title:   "API Documentation",
 			Version: "1.0.0",
@@ -636,17 +497,19 @@ func genDoc(engine *gin.Engine, options ...OpenAPIOption) (OpenAPIDocument, erro
 		})
 	}
 
-	for _, api := range apiRegistry {
-		// 生成并注册请求和响应的 Schema
-		reqTypeName := getTypeName(api.RequestType)
-		respTypeName := getTypeName(api.ResponseType)
-
-		// 添加到 components.schemas
-		doc.Components.Schemas[reqTypeName] = generateSchema(api.RequestType)
-		doc.Components.Schemas[respTypeName] = generateSchema(api.ResponseType)
+	gen := newSchemaGenerator(doc.Components.Schemas)
+	doc.Components.Schemas[errorSchemaName] = Schema{
+		Type: "object",
+		Properties: map[string]Schema{
+			"code":    {Type: "integer", Description: "业务错误码"},
+			"message": {Type: "string"},
+		},
+		Required: []string{"code", "message"},
+	}
 
+	for _, api := range APIs(engine) {
 		// 解析路径参数
-		path, pathParams := parsePathParameters(api.Path)
+		path, pathParams := parsePathParameters(api.Path, api.RequestType, gen)
 
 		// 根据路径前缀生成标签
 		tags := api.Tags
@@ -654,71 +517,27 @@ func genDoc(engine *gin.Engine, options ...OpenAPIOption) (OpenAPIDocument, erro
 			tags = generateTags(path)
 		}
 
-		pathItem, ok := doc.Paths[path]
-		if !ok {
-			pathItem = PathItem{}
-		}
+		pathItem := doc.Paths[path]
 
 		operation := Operation{
+			OperationID: api.OperationID,
 			Tags:        tags,
 			Summary:     api.Summary,
 			Description: api.Description,
 			Deprecated:  api.Deprecated,
-			Responses: map[string]Response{
-				"200": {
-					Description: "Successful response",
-					Content: map[string]MediaType{
-						"application/json": {
-							Schema: Schema{
-								Ref: "#/components/schemas/" + respTypeName,
-							},
-						},
-					},
-				},
-			},
-		}
-
-		// 设置请求内容类型
-		if api.Accept != "" {
-			if operation.RequestBody != nil {
-				newContent := make(map[string]MediaType)
-				for _, acceptType := range strings.Split(api.Accept, ",") {
-					acceptType = strings.TrimSpace(acceptType)
-					newContent[acceptType] = operation.RequestBody.Content["application/json"]
-				}
-				operation.RequestBody.Content = newContent
-			}
-		}
-
-		// 设置响应内容类型
-		if api.Produce != "" {
-			resp := operation.Responses["200"]
-			newContent := make(map[string]MediaType)
-			for _, produceType := range strings.Split(api.Produce, ",") {
-				produceType = strings.TrimSpace(produceType)
-				newContent[produceType] = resp.Content["application/json"]
-			}
-			resp.Content = newContent
-			operation.Responses["200"] = resp
+			Responses:   generateResponses(api, gen),
 		}
 
 		// 添加路径参数
 		operation.Parameters = append(operation.Parameters, pathParams...)
+		operation.Parameters = append(operation.Parameters, generateHeaderParameters(api.RequestType, gen)...)
 
 		// 根据 HTTP 方法决定使用 Parameters 还是 RequestBody
 		switch api.Method {
-		case "GET", "DELETE":
-			operation.Parameters = append(operation.Parameters, generateParameters(api.RequestType)...)
-		case "POST", "PUT", "PATCH":
-			operation.RequestBody = &RequestBody{
-				Content: map[string]MediaType{
-					"application/json": {
-						Schema: Schema{
-							Ref: "#/components/schemas/" + reqTypeName,
-						},
-					},
-				},
-			}
+		case "GET", "DELETE", "HEAD", "OPTIONS":
+			operation.Parameters = append(operation.Parameters, generateParameters(api.RequestType, gen)...)
+		default:
+			operation.RequestBody = generateRequestBody(api, gen)
 		}
 
 		switch api.Method {
@@ -732,6 +551,10 @@ func genDoc(engine *gin.Engine, options ...OpenAPIOption) (OpenAPIDocument, erro
 			pathItem.Delete = &operation
 		case "PATCH":
 			pathItem.Patch = &operation
+		case "HEAD":
+			pathItem.Head = &operation
+		case "OPTIONS":
+			pathItem.Options = &operation
 		}
 
 		doc.Paths[path] = pathItem
@@ -740,43 +563,208 @@ func genDoc(engine *gin.Engine, options ...OpenAPIOption) (OpenAPIDocument, erro
 	return doc, nil
 }
 
-func generateParameters(t reflect.Type) []Parameter {
+const errorSchemaName = "xcode.Error"
+
+func generateRequestBody(api APIInfo, gen *schemaGenerator) *RequestBody {
+	schema := gen.schemaOf(api.RequestType)
+	contentTypes := []string{"application/json"}
+	if hasFileField(api.RequestType) {
+		// 文件上传需要内联 schema，multipart 的字段编码依赖 properties
+		t := api.RequestType
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		schema = gen.structSchema(t)
+		contentTypes = []string{"multipart/form-data"}
+	}
+	if api.Accept != "" {
+		contentTypes = splitContentTypes(api.Accept)
+	}
+
+	body := &RequestBody{
+		Required: true,
+		Content:  make(map[string]MediaType),
+	}
+	for _, contentType := range contentTypes {
+		body.Content[contentType] = MediaType{
+			Schema:  schema,
+			Example: api.RequestExample,
+		}
+	}
+	return body
+}
+
+// generateResponses 按 HanderFunc 的响应格式生成文档：成功时为 {code, message, data}，
+// 失败时按 xcode.Code 的 HttpCode 分组
+func generateResponses(api APIInfo, gen *schemaGenerator) map[string]Response {
+	envelope := Schema{
+		Type: "object",
+		Properties: map[string]Schema{
+			"code":    {Type: "integer", Enum: []any{SuccessCode}},
+			"message": {Type: "string"},
+		},
+		Required: []string{"code", "message"},
+	}
+	if api.ResponseType != nil && api.ResponseType != reflect.TypeOf(Empty{}) {
+		envelope.Properties["data"] = gen.schemaOf(api.ResponseType)
+	}
+
+	var example any
+	if api.ResponseExample != nil {
+		example = map[string]any{
+			"code":    SuccessCode,
+			"message": "success",
+			"data":    api.ResponseExample,
+		}
+	}
+
+	contentTypes := []string{"application/json"}
+	if api.Produce != "" {
+		contentTypes = splitContentTypes(api.Produce)
+	}
+	success := Response{
+		Description: "Successful response",
+		Content:     make(map[string]MediaType),
+	}
+	for _, contentType := range contentTypes {
+		success.Content[contentType] = MediaType{Schema: envelope, Example: example}
+	}
+
+	responses := map[string]Response{
+		"200": success,
+		"default": {
+			Description: "Error response",
+			Content: map[string]MediaType{
+				"application/json": {Schema: Schema{Ref: "#/components/schemas/" + errorSchemaName}},
+			},
+		},
+	}
+
+	for _, code := range api.Errors {
+		if code == nil {
+			continue
+		}
+		status := code.HttpCode
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		key := strconv.Itoa(status)
+		resp, ok := responses[key]
+		if !ok {
+			resp = Response{
+				Description: http.StatusText(status),
+				Content:     make(map[string]MediaType),
+			}
+		}
+
+		// 业务错误以 200 返回时作为成功响应的额外示例
+		media := resp.Content["application/json"]
+		if media.Schema.Type == "" && media.Schema.Ref == "" {
+			media.Schema = Schema{Ref: "#/components/schemas/" + errorSchemaName}
+		}
+		if media.Examples == nil {
+			media.Examples = make(map[string]Example)
+		}
+		// example 与 examples 互斥
+		if media.Example != nil {
+			media.Examples["success"] = Example{Value: media.Example}
+			media.Example = nil
+		}
+		media.Examples[strconv.Itoa(code.Code)] = Example{
+			Summary: code.Message,
+			Value:   map[string]any{"code": code.Code, "message": code.Message},
+		}
+		resp.Content["application/json"] = media
+		responses[key] = resp
+	}
+
+	return responses
+}
+
+func splitContentTypes(s string) []string {
+	var types []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// generateParameters 生成 query 参数，与 gin 的 ShouldBindQuery 一致优先使用 form tag
+func generateParameters(t reflect.Type, gen *schemaGenerator) []Parameter {
+	return structParameters(t, gen, "query", "form", "json")
+}
+
+func generateHeaderParameters(t reflect.Type, gen *schemaGenerator) []Parameter {
+	return structParameters(t, gen, "header", "header")
+}
+
+func structParameters(t reflect.Type, gen *schemaGenerator, in string, tags ...string) []Parameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
 	var parameters []Parameter
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		jsonTag := field.Tag.Get("json")
-		if jsonTag == "-" {
+		if field.Anonymous {
+			parameters = append(parameters, structParameters(field.Type, gen, in, tags...)...)
 			continue
 		}
-		if field.Tag.Get("uri") != "" {
+		if !field.IsExported() || field.Tag.Get("uri") != "" {
 			continue
 		}
-		name := strings.Split(jsonTag, ",")[0]
-		if name == "" {
-			name = field.Name
+		if in == "header" && field.Tag.Get("header") == "" {
+			continue
+		}
+		if in != "header" && field.Tag.Get("header") != "" {
+			continue
+		}
+		name, ok := fieldName(field, tags...)
+		if !ok {
+			continue
 		}
 
-		param := Parameter{
+		schema := gen.fieldSchema(field)
+		parameters = append(parameters, Parameter{
 			Name:        name,
-			In:          "query", // 默认为查询参数
-			Description: generateDescription(field),
+			In:          in,
+			Description: schema.Description,
 			Required:    isRequired(field),
-			Schema:      generateSchema(field.Type),
-		}
-		parameters = append(parameters, param)
+			Schema:      schema,
+		})
 	}
 	return parameters
 }
 
-func parsePathParameters(path string) (string, []Parameter) {
+// parsePathParameters 将 gin 的 :id、*path 转为 {id}，参数类型取自请求结构体的 uri tag
+func parsePathParameters(path string, reqType reflect.Type, gen *schemaGenerator) (string, []Parameter) {
+	uriFields := map[string]reflect.StructField{}
+	t := reqType
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != nil && t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if uri := strings.Split(field.Tag.Get("uri"), ",")[0]; uri != "" {
+				uriFields[uri] = field
+			}
+		}
+	}
+
 	parts := strings.Split(path, "/")
 	var params []Parameter
 	var newParts []string
 
 	for _, part := range parts {
-		if strings.HasPrefix(part, ":") {
-			paramName := strings.TrimPrefix(part, ":")
-			params = append(params, Parameter{
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			paramName := part[1:]
+			param := Parameter{
 				Name:        paramName,
 				In:          "path",
 				Description: "Path parameter " + paramName,
@@ -784,7 +772,14 @@ func parsePathParameters(path string) (string, []Parameter) {
 				Schema: Schema{
 					Type: "string",
 				},
-			})
+			}
+			if field, ok := uriFields[paramName]; ok {
+				param.Schema = gen.fieldSchema(field)
+				if param.Schema.Description != "" {
+					param.Description = param.Schema.Description
+				}
+			}
+			params = append(params, param)
 			newParts = append(newParts, "{"+paramName+"}")
 		} else {
 			newParts = append(newParts, part)
@@ -797,36 +792,8 @@ func parsePathParameters(path string) (string, []Parameter) {
 // 根据路径生成标签
 func generateTags(path string) []string {
 	parts := strings.Split(strings.Trim(path, "/"), "/")
-	if len(parts) > 0 {
+	if len(parts) > 0 && parts[0] != "" {
 		return []string{parts[0]}
 	}
 	return []string{"default"}
 }
-
-// 获取类型名称的辅助函数
-func getTypeName(t reflect.Type) string {
-	if t == nil {
-		return "void"
-	}
-
-	// 处理指针类型
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
-	}
-
-	// 获取包名和类型名
-	pkgPath := t.PkgPath()
-	name := t.Name()
-
-	// 如果是内置类型，直接返回类型名
-	if pkgPath == "" {
-		return name
-	}
-
-	// 获取包的最后一个部分
-	parts := strings.Split(pkgPath, "/")
-	pkgName := parts[len(parts)-1]
-
-	// 组合包名和类型名
-	return pkgName + "." + name
-}
@@ -0,0 +1,566 @@
+package xapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	fileHeaderType  = reflect.TypeOf(multipart.FileHeader{})
+	rawMessageType  = reflect.TypeOf(json.RawMessage{})
+	schemaNameClean = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+// schemaGenerator 为具名结构体生成 components.schemas，字段通过 $ref 引用
+type schemaGenerator struct {
+	schemas map[string]Schema
+	types   map[string]reflect.Type // schema 名称对应的类型，用于发现重名
+}
+
+func newSchemaGenerator(schemas map[string]Schema) *schemaGenerator {
+	if schemas == nil {
+		schemas = make(map[string]Schema)
+	}
+	return &schemaGenerator{schemas: schemas, types: make(map[string]reflect.Type)}
+}
+
+// schemaName 返回类型的 schema 名称，不同类型生成相同名称时追加序号
+func (g *schemaGenerator) schemaName(t reflect.Type) string {
+	base := getTypeName(t)
+	name := base
+	for i := 2; ; i++ {
+		existing, ok := g.types[name]
+		if !ok {
+			g.types[name] = t
+			return name
+		}
+		if existing == t {
+			return name
+		}
+		name = base + "_" + strconv.Itoa(i)
+	}
+}
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return Schema{Type: "string", Format: "date-time"}
+	case fileHeaderType:
+		return Schema{Type: "string", Format: "binary"}
+	case rawMessageType:
+		return Schema{}
+	}
+	if t.String() == "decimal.Decimal" {
+		return Schema{Type: "string", Format: "decimal"}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := g.schemaName(t)
+		if _, ok := g.schemas[name]; !ok {
+			// 先占位，处理自引用类型
+			g.schemas[name] = Schema{Type: "object"}
+			g.schemas[name] = g.structSchema(t)
+		}
+		return Schema{Ref: "#/components/schemas/" + name}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{Type: "string", Format: "byte"}
+		}
+		items := g.schemaOf(t.Elem())
+		return Schema{Type: "array", Items: &items}
+	case reflect.Map:
+		values := g.schemaOf(t.Elem())
+		return Schema{Type: "object", AdditionalProperties: &values}
+	case reflect.String:
+		return Schema{Type: "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		min := float64(0)
+		return Schema{Type: "integer", Minimum: &min}
+	case reflect.Float32:
+		return Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return Schema{Type: "number", Format: "double"}
+	case reflect.Bool:
+		return Schema{Type: "boolean"}
+	case reflect.Interface:
+		return Schema{}
+	}
+	return Schema{Type: "string"}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) Schema {
+	schema := Schema{
+		Type:       "object",
+		Properties: make(map[string]Schema),
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			// 处理匿名字段（嵌入式结构体）
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := g.structSchema(ft)
+				for k, v := range embedded.Properties {
+					schema.Properties[k] = v
+				}
+				schema.Required = append(schema.Required, embedded.Required...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if field.Tag.Get("uri") != "" || field.Tag.Get("header") != "" {
+			continue
+		}
+		name, ok := fieldName(field, "json", "form")
+		if !ok {
+			continue
+		}
+		schema.Properties[name] = g.fieldSchema(field)
+		if isRequired(field) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+func (g *schemaGenerator) fieldSchema(field reflect.StructField) Schema {
+	schema := g.schemaOf(field.Type)
+	schema.Description = generateDescription(field)
+	applyValidateRules(&schema, field)
+	if def := field.Tag.Get("default"); def != "" {
+		schema.Default = parseTagValue(def, schema.Type)
+	}
+	if example := field.Tag.Get("example"); example != "" {
+		schema.Examples = []any{parseTagValue(example, schema.Type)}
+	}
+	return schema
+}
+
+// fieldName 按 tags 顺序取第一个非空的名称，任一 tag 为 "-" 时忽略该字段
+func fieldName(field reflect.StructField, tags ...string) (string, bool) {
+	for _, tag := range tags {
+		value := field.Tag.Get(tag)
+		if value == "-" {
+			return "", false
+		}
+		if name := strings.Split(value, ",")[0]; name != "" {
+			return name, true
+		}
+	}
+	return field.Name, true
+}
+
+// validateRules 合并 binding 与 validate 两个 tag 的规则
+func validateRules(field reflect.StructField) []string {
+	var rules []string
+	for _, tag := range []string{"binding", "validate"} {
+		if value := field.Tag.Get(tag); value != "" {
+			rules = append(rules, strings.Split(value, ",")...)
+		}
+	}
+	return rules
+}
+
+func applyValidateRules(schema *Schema, field reflect.StructField) {
+	target := schema
+	// 数组规则中 dive 之后的部分作用于元素
+	for _, rule := range validateRules(field) {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			if target.Items != nil {
+				target = target.Items
+			}
+		case "oneof":
+			target.Enum = enumValues(strings.Fields(param), target.Type)
+		case "enum":
+			target.Enum = enumValues(strings.Split(param, "|"), target.Type)
+		case "min", "gte":
+			setBound(target, param, true, false)
+		case "max", "lte":
+			setBound(target, param, false, false)
+		case "gt":
+			setBound(target, param, true, true)
+		case "lt":
+			setBound(target, param, false, true)
+		case "len":
+			setBound(target, param, true, false)
+			setBound(target, param, false, false)
+		case "email":
+			target.Format = "email"
+		case "url", "uri", "http_url":
+			target.Format = "uri"
+		case "uuid", "uuid4":
+			target.Format = "uuid"
+		case "ipv4":
+			target.Format = "ipv4"
+		case "ipv6":
+			target.Format = "ipv6"
+		case "hostname":
+			target.Format = "hostname"
+		case "datetime":
+			target.Format = "date-time"
+		case "alpha":
+			target.Pattern = "^[a-zA-Z]+$"
+		case "alphanum":
+			target.Pattern = "^[a-zA-Z0-9]+$"
+		case "numeric":
+			target.Pattern = "^[-+]?[0-9]+(\\.[0-9]+)?$"
+		case "startswith":
+			target.Pattern = "^" + regexp.QuoteMeta(param)
+		case "endswith":
+			target.Pattern = regexp.QuoteMeta(param) + "$"
+		case "unique":
+			target.UniqueItems = true
+		}
+	}
+}
+
+// setBound 按类型把 min/max 映射为数值范围、字符串长度或数组长度
+func setBound(schema *Schema, param string, lower, exclusive bool) {
+	switch schema.Type {
+	case "string":
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return
+		}
+		if exclusive {
+			if lower {
+				n++
+			} else {
+				n--
+			}
+		}
+		if lower {
+			schema.MinLength = &n
+		} else {
+			schema.MaxLength = &n
+		}
+	case "array":
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return
+		}
+		if lower {
+			schema.MinItems = &n
+		} else {
+			schema.MaxItems = &n
+		}
+	case "integer", "number":
+		f, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		switch {
+		case lower && exclusive:
+			schema.ExclusiveMinimum = &f
+		case lower:
+			schema.Minimum = &f
+		case exclusive:
+			schema.ExclusiveMaximum = &f
+		default:
+			schema.Maximum = &f
+		}
+	}
+}
+
+func enumValues(values []string, typ string) []any {
+	result := make([]any, 0, len(values))
+	for _, v := range values {
+		result = append(result, parseTagValue(v, typ))
+	}
+	return result
+}
+
+func parseTagValue(value, typ string) any {
+	switch typ {
+	case "integer":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+func generateDescription(field reflect.StructField) string {
+	var desc []string
+
+	// 获取 comment 标签
+	for _, tag := range []string{"comment", "description"} {
+		if comment := field.Tag.Get(tag); comment != "" {
+			desc = append(desc, comment)
+		}
+	}
+
+	// 解析 default 标签
+	if defaultVal := field.Tag.Get("default"); defaultVal != "" {
+		desc = append(desc, fmt.Sprintf("默认值: %s", defaultVal))
+	}
+
+	// 解析 binding、validate 标签
+	for _, rule := range validateRules(field) {
+		switch {
+		case rule == "required":
+			desc = append(desc, "此字段是必需的")
+		case rule == "email":
+			desc = append(desc, "必须是有效的电子邮件地址")
+		case rule == "url":
+			desc = append(desc, "必须是有效的URL")
+		case strings.HasPrefix(rule, "min="):
+			desc = append(desc, fmt.Sprintf("最小值为 %s", strings.TrimPrefix(rule, "min=")))
+		case strings.HasPrefix(rule, "max="):
+			desc = append(desc, fmt.Sprintf("最大值为 %s", strings.TrimPrefix(rule, "max=")))
+		case strings.HasPrefix(rule, "len="):
+			desc = append(desc, fmt.Sprintf("长度必须为 %s", strings.TrimPrefix(rule, "len=")))
+		case strings.HasPrefix(rule, "oneof="):
+			desc = append(desc, fmt.Sprintf("枚举值: %s", strings.ReplaceAll(strings.TrimPrefix(rule, "oneof="), " ", ", ")))
+		case strings.HasPrefix(rule, "enum="):
+			desc = append(desc, fmt.Sprintf("枚举值: %s", strings.ReplaceAll(strings.TrimPrefix(rule, "enum="), "|", ", ")))
+		case strings.HasPrefix(rule, "eq="):
+			desc = append(desc, fmt.Sprintf("必须等于 %s", strings.TrimPrefix(rule, "eq=")))
+		case strings.HasPrefix(rule, "ne="):
+			desc = append(desc, fmt.Sprintf("不能等于 %s", strings.TrimPrefix(rule, "ne=")))
+		case strings.HasPrefix(rule, "lt="):
+			desc = append(desc, fmt.Sprintf("必须小于 %s", strings.TrimPrefix(rule, "lt=")))
+		case strings.HasPrefix(rule, "lte="):
+			desc = append(desc, fmt.Sprintf("必须小于或等于 %s", strings.TrimPrefix(rule, "lte=")))
+		case strings.HasPrefix(rule, "gt="):
+			desc = append(desc, fmt.Sprintf("必须大于 %s", strings.TrimPrefix(rule, "gt=")))
+		case strings.HasPrefix(rule, "gte="):
+			desc = append(desc, fmt.Sprintf("必须大于或等于 %s", strings.TrimPrefix(rule, "gte=")))
+		case rule == "alpha":
+			desc = append(desc, "只能包含字母")
+		case rule == "alphanum":
+			desc = append(desc, "只能包含字母和数字")
+		case rule == "numeric":
+			desc = append(desc, "必须是数字")
+		case strings.HasPrefix(rule, "eqfield="):
+			desc = append(desc, fmt.Sprintf("必须等于 %s 字段", strings.TrimPrefix(rule, "eqfield=")))
+		case strings.HasPrefix(rule, "nefield="):
+			desc = append(desc, fmt.Sprintf("不能等于 %s 字段", strings.TrimPrefix(rule, "nefield=")))
+		case strings.HasPrefix(rule, "gtfield="):
+			desc = append(desc, fmt.Sprintf("必须大于 %s 字段", strings.TrimPrefix(rule, "gtfield=")))
+		case strings.HasPrefix(rule, "gtefield="):
+			desc = append(desc, fmt.Sprintf("必须大于或等于 %s 字段", strings.TrimPrefix(rule, "gtefield=")))
+		case strings.HasPrefix(rule, "ltfield="):
+			desc = append(desc, fmt.Sprintf("必须小于 %s 字段", strings.TrimPrefix(rule, "ltfield=")))
+		case strings.HasPrefix(rule, "ltefield="):
+			desc = append(desc, fmt.Sprintf("必须小于或等于 %s 字段", strings.TrimPrefix(rule, "ltefield=")))
+		case rule == "isdefault":
+			desc = append(desc, "必须是默认值")
+		case rule == "unique":
+			desc = append(desc, "必须是唯一的")
+		case rule == "alphaunicode":
+			desc = append(desc, "只能包含 unicode 字符")
+		case rule == "alphanumunicode":
+			desc = append(desc, "只能包含 unicode 字母和数字")
+		case rule == "lowercase":
+			desc = append(desc, "只能包含小写字符")
+		case rule == "uppercase":
+			desc = append(desc, "只能包含大写字符")
+		case rule == "json":
+			desc = append(desc, "必须是有效的 JSON")
+		case rule == "file":
+			desc = append(desc, "必须是有效的文件路径")
+		case rule == "uri":
+			desc = append(desc, "必须是有效的 URI")
+		case rule == "base64":
+			desc = append(desc, "必须是有效的 base64 值")
+		case strings.HasPrefix(rule, "contains="):
+			desc = append(desc, fmt.Sprintf("必须包含 %s", strings.TrimPrefix(rule, "contains=")))
+		case strings.HasPrefix(rule, "containsany="):
+			desc = append(desc, fmt.Sprintf("必须包含 %s 中的任何字符", strings.TrimPrefix(rule, "containsany=")))
+		case strings.HasPrefix(rule, "excludes="):
+			desc = append(desc, fmt.Sprintf("不能包含 %s", strings.TrimPrefix(rule, "excludes=")))
+		case strings.HasPrefix(rule, "excludesall="):
+			desc = append(desc, fmt.Sprintf("不能包含 %s 中的任何字符", strings.TrimPrefix(rule, "excludesall=")))
+		case strings.HasPrefix(rule, "startswith="):
+			desc = append(desc, fmt.Sprintf("必须以 %s 开始", strings.TrimPrefix(rule, "startswith=")))
+		case strings.HasPrefix(rule, "endswith="):
+			desc = append(desc, fmt.Sprintf("必须以 %s 结束", strings.TrimPrefix(rule, "endswith=")))
+		case rule == "ip":
+			desc = append(desc, "必须是有效的 IP 地址")
+		case rule == "ipv4":
+			desc = append(desc, "必须是有效的 IPv4 地址")
+		case rule == "datetime":
+			desc = append(desc, "必须是有效的日期时间")
+		case rule == "omitempty":
+			desc = append(desc, "非必须")
+		case rule == "datetime_range":
+			desc = append(desc, "格式必须是 'YYYY-MM-DD HH:mm:ss,YYYY-MM-DD HH:mm:ss' 且结束时间必须大于开始时间")
+		}
+	}
+
+	return strings.Join(desc, ", ")
+}
+
+func isRequired(field reflect.StructField) bool {
+	for _, rule := range validateRules(field) {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFileField 判断请求结构体是否包含文件上传字段
+func hasFileField(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i).Type
+		for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+		}
+		if ft == fileHeaderType {
+			return true
+		}
+		if t.Field(i).Anonymous && hasFileField(ft) {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取类型名称的辅助函数，泛型参数中的包路径只保留包名，
+// 如 xapp.PageResult[github.com/x/model.User] 生成 xapp.PageResult-model.User。
+// 参数中的 *T、[]T、[N]T、map[K]V 分别写作 Ptr-T、List-T、ArrayN-T、Map-K-V，
+// 嵌套的泛型参数不是最后一个参数时以 _ 结束，如 Pair[List[A],B] 生成 Pair-List-A_-B
+func getTypeName(t reflect.Type) string {
+	if t == nil {
+		return "void"
+	}
+
+	// 处理指针类型
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	name := t.Name()
+	if strings.Contains(name, "[") {
+		expr, _ := parseTypeExpr(name)
+		name = expr.render(true)
+	}
+	name = schemaNameClean.ReplaceAllString(name, "_")
+
+	// 如果是内置类型，直接返回类型名
+	pkgPath := t.PkgPath()
+	if pkgPath == "" {
+		return name
+	}
+
+	// 组合包名和类型名
+	parts := strings.Split(pkgPath, "/")
+	return parts[len(parts)-1] + "." + name
+}
+
+// typeExpr 是 reflect 类型名中泛型参数的语法树
+type typeExpr struct {
+	name string
+	args []typeExpr
+}
+
+func (e typeExpr) render(last bool) string {
+	if len(e.args) == 0 {
+		return e.name
+	}
+	parts := make([]string, len(e.args))
+	for i, arg := range e.args {
+		parts[i] = arg.render(last && i == len(e.args)-1)
+	}
+	out := e.name + "-" + strings.Join(parts, "-")
+	if !last {
+		out += "_"
+	}
+	return out
+}
+
+// parseTypeExpr 解析 s 开头的一个类型表达式，返回剩余部分
+func parseTypeExpr(s string) (typeExpr, string) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "*"):
+		elem, rest := parseTypeExpr(s[1:])
+		return typeExpr{name: "Ptr", args: []typeExpr{elem}}, rest
+	case strings.HasPrefix(s, "[]"):
+		elem, rest := parseTypeExpr(s[2:])
+		return typeExpr{name: "List", args: []typeExpr{elem}}, rest
+	case strings.HasPrefix(s, "["):
+		n, rest, _ := strings.Cut(s[1:], "]")
+		elem, rest := parseTypeExpr(rest)
+		return typeExpr{name: "Array" + n, args: []typeExpr{elem}}, rest
+	case strings.HasPrefix(s, "map["):
+		key, rest := parseTypeExpr(s[4:])
+		value, rest := parseTypeExpr(strings.TrimPrefix(rest, "]"))
+		return typeExpr{name: "Map", args: []typeExpr{key, value}}, rest
+	}
+
+	// 具名类型，func、struct 等字面量类型整体作为名称
+	depth, end := 0, len(s)
+loop:
+	for i, r := range s {
+		switch r {
+		case '(', '{':
+			depth++
+		case ')', '}':
+			depth--
+		case '[', ',', ']':
+			if depth == 0 {
+				end = i
+				break loop
+			}
+		}
+	}
+	expr := typeExpr{name: s[:end]}
+	if i := strings.LastIndex(expr.name, "/"); i >= 0 && !strings.ContainsAny(expr.name, "({") {
+		expr.name = expr.name[i+1:]
+	}
+	rest := s[end:]
+	if strings.HasPrefix(rest, "[") {
+		rest = rest[1:]
+		for rest != "" && !strings.HasPrefix(rest, "]") {
+			var arg typeExpr
+			arg, rest = parseTypeExpr(rest)
+			expr.args = append(expr.args, arg)
+			rest = strings.TrimPrefix(rest, ",")
+		}
+		rest = strings.TrimPrefix(rest, "]")
+	}
+	return expr, rest
+}
@@ -0,0 +1,176 @@
+package xapp
+
+import (
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+
+	"github.com/daodao97/xgo/xcode"
+)
+
+type openapiUser struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name" binding:"required,min=2,max=20"`
+	Status string `json:"status" validate:"enum=active|disabled"`
+}
+
+type openapiListReq struct {
+	Page
+	Keyword string `form:"keyword" comment:"关键字"`
+	Token   string `header:"X-Token" binding:"required"`
+}
+
+type openapiGetReq struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type openapiUploadReq struct {
+	Name string                `form:"name"`
+	File *multipart.FileHeader `form:"file" binding:"required"`
+}
+
+var errUserNotFound = &xcode.Code{Code: 10404, HttpCode: 404, Message: "user not found"}
+
+func TestGenerateOpenAPIDocFromRegisteredRoutes(t *testing.T) {
+	originalEnable := Args.EnableOpenAPI
+	originalRegistry := apiRegistry
+	Args.EnableOpenAPI = true
+	apiRegistry = nil
+	defer func() {
+		Args.EnableOpenAPI = originalEnable
+		apiRegistry = originalRegistry
+	}()
+
+	// 开发模式下会写出 openapi.json，避免污染源码目录
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/users", RegisterAPI(func(c *gin.Context, req openapiListReq) (*PageResult[openapiUser], error) {
+		return nil, nil
+	}, WithSummary("list users"), WithTags("user")))
+	e.GET("/users/:id", RegisterAPI(func(c *gin.Context, req openapiGetReq) (*openapiUser, error) {
+		return nil, nil
+	}, WithSummary("get user"), WithErrors(errUserNotFound), WithResponseExample(openapiUser{ID: 1, Name: "tom"})))
+	e.DELETE("/users/:id", RegisterAPI(func(c *gin.Context, req openapiGetReq) (*openapiUser, error) {
+		return nil, nil
+	}, WithSummary("delete user")))
+	e.POST("/upload", RegisterAPI(func(c *gin.Context, req openapiUploadReq) (*Empty, error) {
+		return nil, nil
+	}))
+
+	data, err := GenerateOpenAPIDoc(e, WithInfo("test", "1.0.0", ""))
+	if err != nil {
+		t.Fatalf("GenerateOpenAPIDoc failed: %v", err)
+	}
+	doc := gjson.ParseBytes(data)
+
+	if doc.Get("openapi").String() != "3.1.0" {
+		t.Fatalf("unexpected version: %s", doc.Get("openapi"))
+	}
+
+	list := doc.Get("paths./users.get")
+	if list.Get("summary").String() != "list users" || list.Get("tags.0").String() != "user" {
+		t.Fatalf("unexpected list operation: %s", list.Raw)
+	}
+	if !hasParam(list, "keyword", "query") || !hasParam(list, "X-Token", "header") || !hasParam(list, "page", "query") {
+		t.Fatalf("missing parameters: %s", list.Get("parameters").Raw)
+	}
+
+	pageRef := list.Get("responses.200.content.application/json.schema.properties.data.$ref").String()
+	pageSchema := doc.Get("components.schemas." + strings.ReplaceAll(strings.TrimPrefix(pageRef, "#/components/schemas/"), ".", `\.`))
+	if !pageSchema.Exists() || pageSchema.Get("properties.items.type").String() != "array" {
+		t.Fatalf("generic schema not generated, ref=%s", pageRef)
+	}
+
+	user := doc.Get(`components.schemas.xapp\.openapiUser`)
+	if user.Get("properties.status.enum.#").Int() != 2 || user.Get("properties.name.minLength").Int() != 2 {
+		t.Fatalf("validate rules not applied: %s", user.Raw)
+	}
+
+	get := doc.Get("paths./users/{id}.get")
+	if get.Get("parameters.0.schema.type").String() != "integer" {
+		t.Fatalf("path parameter type not resolved: %s", get.Get("parameters").Raw)
+	}
+	if get.Get("responses.404.content.application/json.examples.10404.value.message").String() != "user not found" {
+		t.Fatalf("error response missing: %s", get.Get("responses").Raw)
+	}
+	if get.Get("responses.200.content.application/json.example.data.name").String() != "tom" {
+		t.Fatalf("response example missing: %s", get.Get("responses.200").Raw)
+	}
+	if doc.Get("paths./users/{id}.delete.summary").String() != "delete user" {
+		t.Fatal("routes sharing a handler instantiation were not distinguished")
+	}
+
+	upload := doc.Get("paths./upload.post.requestBody.content.multipart/form-data.schema")
+	if upload.Get("properties.file.format").String() != "binary" {
+		t.Fatalf("file upload schema missing: %s", upload.Raw)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "openapi: 3.1.0") {
+		t.Fatalf("unexpected yaml response: %d %s", rec.Code, rec.Body.String())
+	}
+	if !json.Valid(data) {
+		t.Fatal("invalid json output")
+	}
+}
+
+func hasParam(op gjson.Result, name, in string) bool {
+	for _, p := range op.Get("parameters").Array() {
+		if p.Get("name").String() == name && p.Get("in").String() == in {
+			return true
+		}
+	}
+	return false
+}
+
+type openapiPair[A, B any] struct {
+	First  A `json:"first"`
+	Second B `json:"second"`
+}
+
+func TestGetTypeNameGenericArgs(t *testing.T) {
+	cases := map[reflect.Type]string{
+		reflect.TypeOf(PageResult[openapiUser]{}):                           "xapp.PageResult-xapp.openapiUser",
+		reflect.TypeOf(PageResult[*openapiUser]{}):                          "xapp.PageResult-Ptr-xapp.openapiUser",
+		reflect.TypeOf(PageResult[[]openapiUser]{}):                         "xapp.PageResult-List-xapp.openapiUser",
+		reflect.TypeOf(PageResult[[2]openapiUser]{}):                        "xapp.PageResult-Array2-xapp.openapiUser",
+		reflect.TypeOf(PageResult[map[string][]int]{}):                      "xapp.PageResult-Map-string-List-int",
+		reflect.TypeOf(openapiPair[string, PageResult[openapiUser]]{}):      "xapp.openapiPair-string-xapp.PageResult-xapp.openapiUser",
+		reflect.TypeOf(openapiPair[openapiPair[int, string], bool]{}):       "xapp.openapiPair-xapp.openapiPair-int-string_-bool",
+		reflect.TypeOf(openapiPair[int, openapiPair[string, bool]]{}):       "xapp.openapiPair-int-xapp.openapiPair-string-bool",
+		reflect.TypeOf(openapiPair[func(int) string, struct{ A, B int }]{}): "xapp.openapiPair-func_int_string-struct_A_int_B_int_",
+	}
+	seen := map[string]reflect.Type{}
+	for typ, want := range cases {
+		got := getTypeName(typ)
+		if got != want {
+			t.Errorf("getTypeName(%s) = %s, want %s", typ, got, want)
+		}
+		if other, ok := seen[got]; ok {
+			t.Errorf("%s and %s share schema name %s", typ, other, got)
+		}
+		seen[got] = typ
+	}
+
+	// 生成的名称相同时追加序号，而不是复用其他类型的 schema
+	gen := newSchemaGenerator(nil)
+	gen.types["xapp.openapiUser"] = reflect.TypeOf(openapiGetReq{})
+	if ref := gen.schemaOf(reflect.TypeOf(openapiUser{})).Ref; ref != "#/components/schemas/xapp.openapiUser_2" {
+		t.Fatalf("unexpected ref %s", ref)
+	}
+}
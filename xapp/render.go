@@ -0,0 +1,266 @@
+package xapp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/daodao97/xgo/xcode"
+	"github.com/daodao97/xgo/xlog"
+)
+
+// Renderer 决定 HanderFunc 的响应格式
+type Renderer interface {
+	Render(c *gin.Context, data any)
+	RenderError(c *gin.Context, err error)
+}
+
+var renderer Renderer = EnvelopeRenderer{}
+
+// SetRenderer 替换全局 Renderer，默认为 EnvelopeRenderer
+func SetRenderer(r Renderer) {
+	renderer = r
+}
+
+var maskInternalErrors = IsProd
+
+// SetMaskInternalErrors 设置是否隐藏非 xcode.Code 错误的详情，默认仅在生产环境隐藏。
+// 隐藏时响应中返回 error_id，详情记录在日志中
+func SetMaskInternalErrors(mask bool) {
+	maskInternalErrors = func() bool { return mask }
+}
+
+// FieldError 描述单个字段的校验失败
+type FieldError struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag,omitempty"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError 表示请求参数绑定或校验失败，handler 也可以直接返回它
+type ValidationError struct {
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// newValidationError 将绑定或校验错误转换为 ValidationError，字段名取 json/form tag
+func newValidationError(err error, reqType reflect.Type) *ValidationError {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve
+	}
+	ve = &ValidationError{Message: translateError(err), Err: err}
+	var fieldErrs validator.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		for _, fe := range fieldErrs {
+			ve.Fields = append(ve.Fields, FieldError{
+				Field:   fieldPath(reqType, fe.StructNamespace()),
+				Tag:     fe.Tag(),
+				Param:   fe.Param(),
+				Message: translateError(validator.ValidationErrors{fe}),
+			})
+		}
+	}
+	return ve
+}
+
+// fieldPath 将 Req.Items[0].Name 转为 items[0].name
+func fieldPath(t reflect.Type, namespace string) string {
+	parts := strings.Split(namespace, ".")
+	if len(parts) > 1 {
+		parts = parts[1:]
+	}
+	for i, part := range parts {
+		name, index, _ := strings.Cut(part, "[")
+		if index != "" {
+			index = "[" + index
+		}
+		for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map) {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			continue
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			t = nil
+			continue
+		}
+		name, _ = fieldName(field, "json", "form", "uri")
+		parts[i] = name + index
+		t = field.Type
+	}
+	return strings.Join(parts, ".")
+}
+
+// errorInfo 是各 Renderer 共用的错误描述
+type errorInfo struct {
+	Status  int
+	Code    int
+	Type    string
+	Message string
+	Fields  []FieldError
+	ErrorID string
+	// validation 为 true 表示请求参数错误
+	validation bool
+}
+
+func resolveError(c *gin.Context, err error) errorInfo {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return errorInfo{Status: http.StatusBadRequest, Code: 400, Message: ve.Message, Fields: ve.Fields, validation: true}
+	}
+
+	var codeErr *xcode.Code
+	if errors.As(err, &codeErr) {
+		status := codeErr.HttpCode
+		if status == 0 {
+			status = http.StatusInternalServerError // 如果未设置 HttpCode，默认使用 500
+		}
+		return errorInfo{Status: status, Code: codeErr.Code, Type: codeErr.Type, Message: codeErr.Message}
+	}
+
+	info := errorInfo{Status: http.StatusInternalServerError, Code: 500, Message: err.Error()}
+	if maskInternalErrors() {
+		info.ErrorID = newErrorID()
+		info.Message = "internal server error"
+		xlog.ErrorC(c, "internal error", xlog.String("error_id", info.ErrorID), xlog.Err(err))
+	}
+	return info
+}
+
+func newErrorID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// EnvelopeRenderer 输出 {code, message, data}。参数错误时 HTTP 状态码为 200、code 为 400，
+// 与之前的行为保持一致
+type EnvelopeRenderer struct{}
+
+func (EnvelopeRenderer) Render(c *gin.Context, data any) {
+	if data != nil && negotiate(c) == binding.MIMEPROTOBUF {
+		if msg, ok := data.(proto.Message); ok {
+			c.ProtoBuf(http.StatusOK, msg)
+			return
+		}
+	}
+	body := gin.H{
+		"code":    SuccessCode,
+		"message": "success",
+	}
+	if data != nil {
+		body["data"] = data
+	}
+	writeNegotiated(c, http.StatusOK, body)
+}
+
+func (EnvelopeRenderer) RenderError(c *gin.Context, err error) {
+	info := resolveError(c, err)
+	status := info.Status
+	if info.validation {
+		status = http.StatusOK
+	}
+	body := gin.H{
+		"code":    info.Code,
+		"message": info.Message,
+	}
+	if len(info.Fields) > 0 {
+		body["errors"] = info.Fields
+	}
+	if info.ErrorID != "" {
+		body["error_id"] = info.ErrorID
+	}
+	writeNegotiated(c, status, body)
+}
+
+const MIMEProblemJSON = "application/problem+json"
+
+// ProblemRenderer 成功时直接输出 data（无数据时 204），失败时使用 HTTP 状态码与 RFC 7807 problem+json。
+// 参数错误带字段列表时返回 422，否则返回 400
+type ProblemRenderer struct{}
+
+// Problem 是 RFC 7807 响应体，code、errors、error_id 为扩展字段
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     int          `json:"code,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	ErrorID  string       `json:"error_id,omitempty"`
+}
+
+func (ProblemRenderer) Render(c *gin.Context, data any) {
+	if data == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if negotiate(c) == binding.MIMEPROTOBUF {
+		if msg, ok := data.(proto.Message); ok {
+			c.ProtoBuf(http.StatusOK, msg)
+			return
+		}
+	}
+	writeNegotiated(c, http.StatusOK, data)
+}
+
+func (ProblemRenderer) RenderError(c *gin.Context, err error) {
+	info := resolveError(c, err)
+	status := info.Status
+	if info.validation && len(info.Fields) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   info.Message,
+		Instance: c.Request.URL.Path,
+		Code:     info.Code,
+		Errors:   info.Fields,
+		ErrorID:  info.ErrorID,
+	}
+	if info.Type != "" {
+		problem.Type = info.Type
+	}
+	// render.JSON 不会覆盖已设置的 Content-Type
+	c.Header("Content-Type", MIMEProblemJSON)
+	c.Render(status, render.JSON{Data: problem})
+}
+
+// negotiate 根据 Accept 选择响应格式，未声明时使用 JSON
+func negotiate(c *gin.Context) string {
+	return c.NegotiateFormat(binding.MIMEJSON, binding.MIMEMSGPACK, binding.MIMEMSGPACK2, binding.MIMEPROTOBUF)
+}
+
+// writeNegotiated 按 Accept 输出 JSON 或 MsgPack，Protobuf 仅支持 proto.Message，其余情况回退到 JSON
+func writeNegotiated(c *gin.Context, status int, body any) {
+	switch negotiate(c) {
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		c.Render(status, render.MsgPack{Data: body})
+	default:
+		c.JSON(status, body)
+	}
+}
@@ -0,0 +1,143 @@
+package xapp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/tidwall/gjson"
+	"github.com/ugorji/go/codec"
+)
+
+type renderItem struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type renderReq struct {
+	Title string       `json:"title" binding:"required"`
+	Items []renderItem `json:"items" binding:"dive"`
+}
+
+func newRenderEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.POST("/items", HanderFunc(func(c *gin.Context, req renderReq) (*renderReq, error) {
+		return &req, nil
+	}))
+	e.GET("/fail", HanderFunc(func(c *gin.Context, req Empty) (*Empty, error) {
+		return nil, errors.New("dial tcp 10.0.0.1:3306: connection refused")
+	}))
+	e.GET("/empty", HanderFunc(func(c *gin.Context, req Empty) (*Empty, error) {
+		return nil, nil
+	}))
+	e.GET("/notfound", HanderFunc(func(c *gin.Context, req Empty) (*Empty, error) {
+		return nil, errUserNotFound
+	}))
+	return e
+}
+
+func serveRender(r Renderer, e *gin.Engine, method, path, body string, header ...string) *httptest.ResponseRecorder {
+	original := renderer
+	renderer = r
+	defer func() { renderer = original }()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestEnvelopeRendererValidationFields(t *testing.T) {
+	e := newRenderEngine()
+	w := serveRender(EnvelopeRenderer{}, e, http.MethodPost, "/items", `{"items":[{"name":""}]}`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	body := gjson.Parse(w.Body.String())
+	if body.Get("code").Int() != 400 {
+		t.Fatalf("expected code 400, got %s", w.Body.String())
+	}
+	fields := body.Get("errors.#.field").Array()
+	if len(fields) != 2 || fields[0].String() != "title" || fields[1].String() != "items[0].name" {
+		t.Fatalf("unexpected field errors %s", body.Get("errors").Raw)
+	}
+}
+
+func TestProblemRenderer(t *testing.T) {
+	e := newRenderEngine()
+
+	w := serveRender(ProblemRenderer{}, e, http.MethodPost, "/items", `{"items":[]}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != MIMEProblemJSON {
+		t.Fatalf("unexpected content type %s", ct)
+	}
+	body := gjson.Parse(w.Body.String())
+	if body.Get("status").Int() != 422 || body.Get("instance").String() != "/items" || body.Get("errors.0.field").String() != "title" {
+		t.Fatalf("unexpected problem %s", w.Body.String())
+	}
+
+	w = serveRender(ProblemRenderer{}, e, http.MethodGet, "/notfound", "")
+	if w.Code != http.StatusNotFound || gjson.Get(w.Body.String(), "code").Int() != 10404 {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	w = serveRender(ProblemRenderer{}, e, http.MethodGet, "/empty", "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+
+	w = serveRender(ProblemRenderer{}, e, http.MethodPost, "/items", `{"title":"a"}`)
+	if w.Code != http.StatusOK || gjson.Get(w.Body.String(), "title").String() != "a" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRendererMasksInternalErrors(t *testing.T) {
+	original := maskInternalErrors
+	defer func() { maskInternalErrors = original }()
+	e := newRenderEngine()
+
+	SetMaskInternalErrors(true)
+	w := serveRender(EnvelopeRenderer{}, e, http.MethodGet, "/fail", "")
+	body := gjson.Parse(w.Body.String())
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "10.0.0.1") || body.Get("error_id").String() == "" {
+		t.Fatalf("internal error not masked: %s", w.Body.String())
+	}
+
+	SetMaskInternalErrors(false)
+	w = serveRender(EnvelopeRenderer{}, e, http.MethodGet, "/fail", "")
+	if !strings.Contains(w.Body.String(), "connection refused") {
+		t.Fatalf("expected error detail, got %s", w.Body.String())
+	}
+}
+
+func TestRendererMsgPack(t *testing.T) {
+	e := newRenderEngine()
+	w := serveRender(EnvelopeRenderer{}, e, http.MethodPost, "/items", `{"title":"a"}`, "Accept", binding.MIMEMSGPACK)
+
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), binding.MIMEMSGPACK2) {
+		t.Fatalf("unexpected content type %s", w.Header().Get("Content-Type"))
+	}
+	var out map[string]any
+	handle := &codec.MsgpackHandle{}
+	handle.RawToString = true
+	if err := codec.NewDecoderBytes(w.Body.Bytes(), handle).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out["message"] != "success" {
+		t.Fatalf("unexpected body %v", out)
+	}
+}
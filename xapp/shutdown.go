@@ -0,0 +1,209 @@
+package xapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/daodao97/xgo/xdb"
+	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xredis"
+	"github.com/daodao97/xgo/xtrace"
+)
+
+// 关闭顺序，数值小的先执行，相同优先级的钩子并发执行
+const (
+	ShutdownPriorityHTTP     = 100 // 排空 HTTP 请求
+	ShutdownPriorityWorker   = 200 // 停止 xqueue、xcron 等后台任务
+	ShutdownPriorityResource = 300 // 关闭 xdb、xredis 等连接
+)
+
+var (
+	defaultShutdownTimeout = 30 * time.Second
+	defaultHookTimeout     = 10 * time.Second
+)
+
+var osExit = os.Exit
+
+type ShutdownFunc func(ctx context.Context) error
+
+type ShutdownHook struct {
+	Name     string
+	Fn       ShutdownFunc
+	Priority int
+	Timeout  time.Duration // 为 0 时使用 App 的默认钩子超时
+}
+
+// shutdownPriority 由需要自定义关闭顺序的 Server 实现
+type shutdownPriority interface {
+	ShutdownPriority() int
+}
+
+// backgroundWorker 由 xqueue、xcron 等无法引用 xapp 的后台任务实现，按 ShutdownPriorityWorker 关闭
+type backgroundWorker interface {
+	BackgroundWorker()
+}
+
+// gracefulServer 由支持带超时关闭的 Server 实现，优先于 Stop 调用
+type gracefulServer interface {
+	Shutdown(ctx context.Context) error
+}
+
+// AddShutdown 注册关闭钩子，按 priority 从小到大执行
+func (a *App) AddShutdown(name string, fn ShutdownFunc, priority int) *App {
+	return a.AddShutdownHook(ShutdownHook{Name: name, Fn: fn, Priority: priority})
+}
+
+func (a *App) AddShutdownHook(hook ...ShutdownHook) *App {
+	a.shutdownHooks = append(a.shutdownHooks, hook...)
+	return a
+}
+
+// SetShutdownTimeout 设置整体关闭期限，超过后强制退出进程
+func (a *App) SetShutdownTimeout(timeout time.Duration) *App {
+	a.shutdownTimeout = timeout
+	return a
+}
+
+// SetHookTimeout 设置单个钩子的默认超时
+func (a *App) SetHookTimeout(timeout time.Duration) *App {
+	a.hookTimeout = timeout
+	return a
+}
+
+// WithoutResourceClose 关闭时不再自动关闭 xdb、xredis 及 xtrace，由调用方自行处理
+func WithoutResourceClose() Option {
+	return func(a *App) {
+		a.keepResources = true
+	}
+}
+
+// resourceShutdownHooks 在 Server 与后台任务停止后关闭连接并导出剩余的 span
+func resourceShutdownHooks() []ShutdownHook {
+	return []ShutdownHook{
+		{Name: "xtrace", Fn: FlushTraces, Priority: ShutdownPriorityResource},
+		{Name: "xdb", Fn: CloseDB, Priority: ShutdownPriorityResource},
+		{Name: "xredis", Fn: CloseRedis, Priority: ShutdownPriorityResource},
+	}
+}
+
+// CloseDB 关闭所有 xdb 连接池，可直接作为关闭钩子
+func CloseDB(ctx context.Context) error {
+	xdb.Close()
+	return nil
+}
+
+// CloseRedis 关闭所有 xredis 客户端，可直接作为关闭钩子
+func CloseRedis(ctx context.Context) error {
+	return xredis.Close()
+}
+
+// FlushTraces 导出剩余的 span 并关闭 xtrace 导出器，可直接作为关闭钩子
+func FlushTraces(ctx context.Context) error {
+	return xtrace.Shutdown(ctx)
+}
+
+func serverShutdownHook(index int, server Server) ShutdownHook {
+	priority := ShutdownPriorityHTTP
+	if p, ok := server.(shutdownPriority); ok {
+		priority = p.ShutdownPriority()
+	} else if _, ok := server.(backgroundWorker); ok {
+		priority = ShutdownPriorityWorker
+	}
+	return ShutdownHook{
+		Name:     fmt.Sprintf("server-%d(%T)", index, server),
+		Priority: priority,
+		Fn: func(ctx context.Context) error {
+			if s, ok := server.(gracefulServer); ok {
+				return s.Shutdown(ctx)
+			}
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				server.Stop()
+			}()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// runShutdownHooks 按优先级分组执行，组内并发，组间串行
+func (a *App) runShutdownHooks(ctx context.Context, hooks []ShutdownHook) error {
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].Priority < hooks[j].Priority
+	})
+
+	var errs []error
+	for start := 0; start < len(hooks); {
+		end := start
+		for end < len(hooks) && hooks[end].Priority == hooks[start].Priority {
+			end++
+		}
+
+		group := hooks[start:end]
+		groupErrs := make([]error, len(group))
+		var wg sync.WaitGroup
+		for i, hook := range group {
+			wg.Add(1)
+			go func(i int, hook ShutdownHook) {
+				defer wg.Done()
+				groupErrs[i] = a.runShutdownHook(ctx, hook)
+			}(i, hook)
+		}
+		wg.Wait()
+		errs = append(errs, groupErrs...)
+		start = end
+	}
+	return errors.Join(errs...)
+}
+
+func (a *App) runShutdownHook(ctx context.Context, hook ShutdownHook) error {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = a.hookTimeout
+	}
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- hook.Fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	args := []any{
+		xlog.String("name", hook.Name),
+		xlog.Int("priority", hook.Priority),
+		xlog.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		xlog.Error("shutdown hook failed", append(args, xlog.Err(err))...)
+		return fmt.Errorf("shutdown hook %s: %w", hook.Name, err)
+	}
+	xlog.Debug("shutdown hook done", args...)
+	return nil
+}
@@ -0,0 +1,137 @@
+package xapp
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daodao97/xgo/xcron"
+	"github.com/daodao97/xgo/xdb"
+	"github.com/daodao97/xgo/xqueue"
+)
+
+type priorityTestServer struct {
+	*readyTestServer
+	priority int
+}
+
+func (s priorityTestServer) ShutdownPriority() int {
+	return s.priority
+}
+
+func TestShutdownHooksRunInPriorityOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) ShutdownFunc {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	app := NewApp().
+		AddShutdown("db", record("db"), ShutdownPriorityResource).
+		AddShutdown("queue", record("queue"), ShutdownPriorityWorker).
+		AddShutdown("http", record("http"), ShutdownPriorityHTTP)
+
+	if err := app.runShutdownHooks(context.Background(), app.shutdownHooks); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(order, ","); got != "http,queue,db" {
+		t.Fatalf("unexpected order: %s", got)
+	}
+}
+
+func TestShutdownHookTimeout(t *testing.T) {
+	app := NewApp().AddShutdownHook(ShutdownHook{
+		Name:    "slow",
+		Timeout: 20 * time.Millisecond,
+		Fn: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}).AddShutdown("broken", func(ctx context.Context) error {
+		return errors.New("boom")
+	}, ShutdownPriorityResource)
+
+	start := time.Now()
+	err := app.runShutdownHooks(context.Background(), app.shutdownHooks)
+	if err == nil {
+		t.Fatal("expected hook errors")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("hook timeout was not applied")
+	}
+}
+
+func TestServersStopBeforeResourceHooks(t *testing.T) {
+	worker := priorityTestServer{readyTestServer: newReadyTestServer(), priority: ShutdownPriorityWorker}
+	resourceClosed := make(chan struct{})
+
+	app := NewApp().AddShutdown("db", func(ctx context.Context) error {
+		select {
+		case <-worker.stopCalled:
+		default:
+			t.Error("resource closed before worker stopped")
+		}
+		close(resourceClosed)
+		return nil
+	}, ShutdownPriorityResource)
+
+	hooks := append([]ShutdownHook{serverShutdownHook(0, worker)}, app.shutdownHooks...)
+	if err := app.runShutdownHooks(context.Background(), hooks); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-resourceClosed
+}
+
+func TestBackgroundWorkersStopAtWorkerPriority(t *testing.T) {
+	for _, server := range []Server{xqueue.NewQueueWorker(), xcron.New()} {
+		if hook := serverShutdownHook(0, server); hook.Priority != ShutdownPriorityWorker {
+			t.Fatalf("%T: expected worker priority, got %d", server, hook.Priority)
+		}
+	}
+}
+
+func TestShutdownClosesResourcesLast(t *testing.T) {
+	initDB := func() {
+		err := xdb.Inits([]xdb.Config{{Name: "shutdown_test", Driver: "sqlite", DSN: filepath.Join(t.TempDir(), "shutdown.db")}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer xdb.Close()
+
+	initDB()
+	var pingErr error
+	app := NewApp().AddShutdown("worker", func(ctx context.Context) error {
+		pingErr = xdb.Ping(ctx, "shutdown_test")
+		return nil
+	}, ShutdownPriorityWorker)
+	if err := app.shutdown(nil, &sync.WaitGroup{}); err != nil {
+		t.Fatal(err)
+	}
+	if pingErr != nil {
+		t.Fatalf("xdb closed before workers stopped: %v", pingErr)
+	}
+	if conns := xdb.Conns(); len(conns) != 0 {
+		t.Fatalf("expected xdb to be closed, got %v", conns)
+	}
+
+	initDB()
+	if err := NewApp(WithoutResourceClose()).shutdown(nil, &sync.WaitGroup{}); err != nil {
+		t.Fatal(err)
+	}
+	if conns := xdb.Conns(); len(conns) != 1 {
+		t.Fatalf("expected xdb to stay open, got %v", conns)
+	}
+}
@@ -0,0 +1,85 @@
+package xapp
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/cloudflare/tableflip"
+
+	"github.com/daodao97/xgo/xlog"
+)
+
+type Option func(*App)
+
+// WithGracefulUpgrade 启用零停机升级：收到 SIGHUP 后启动新的二进制并把监听的 socket 交给它，
+// 新进程所有 Server 就绪后旧进程才开始排空并退出。pidFile 记录当前就绪进程的 pid，可为空
+func WithGracefulUpgrade(pidFile string) Option {
+	return func(a *App) {
+		a.upgrade = &upgradeOptions{pidFile: pidFile}
+	}
+}
+
+// WithUpgradeTimeout 设置等待新进程就绪的超时时间
+func WithUpgradeTimeout(timeout time.Duration) Option {
+	return func(a *App) {
+		if a.upgrade == nil {
+			a.upgrade = &upgradeOptions{}
+		}
+		a.upgrade.timeout = timeout
+	}
+}
+
+type upgradeOptions struct {
+	pidFile string
+	timeout time.Duration
+}
+
+type ListenFunc func(network, addr string) (net.Listener, error)
+
+// listenerServer 由可以使用外部监听器的 Server 实现
+type listenerServer interface {
+	SetListenFunc(fn ListenFunc)
+}
+
+type upgrader interface {
+	Listen(network, addr string) (net.Listener, error)
+	Ready() error
+	Upgrade() error
+	Exit() <-chan struct{}
+	Stop()
+}
+
+var newUpgrader = func(opts *upgradeOptions) (upgrader, error) {
+	return tableflip.New(tableflip.Options{
+		PIDFile:        opts.pidFile,
+		UpgradeTimeout: opts.timeout,
+	})
+}
+
+// watchUpgrade 在收到 SIGHUP 时触发升级，返回停止监听的函数
+func watchUpgrade(upg upgrader) func() {
+	sig := make(chan os.Signal, 1)
+	signalNotify(sig, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-sig:
+				xlog.Info("received SIGHUP, upgrading")
+				if err := upg.Upgrade(); err != nil {
+					xlog.Error("upgrade failed", xlog.Err(err))
+					continue
+				}
+				xlog.Info("upgrade succeeded, new process is ready")
+			}
+		}
+	}()
+	return func() {
+		signalStop(sig)
+		close(done)
+	}
+}
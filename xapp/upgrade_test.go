@@ -0,0 +1,72 @@
+package xapp
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeUpgrader struct {
+	listens atomic.Int32
+	ready   chan struct{}
+	exit    chan struct{}
+}
+
+func (u *fakeUpgrader) Listen(network, addr string) (net.Listener, error) {
+	u.listens.Add(1)
+	return net.Listen(network, addr)
+}
+
+func (u *fakeUpgrader) Ready() error {
+	close(u.ready)
+	return nil
+}
+
+func (u *fakeUpgrader) Upgrade() error        { return nil }
+func (u *fakeUpgrader) Exit() <-chan struct{} { return u.exit }
+func (u *fakeUpgrader) Stop()                 {}
+
+func TestAppRunWithGracefulUpgrade(t *testing.T) {
+	upg := &fakeUpgrader{ready: make(chan struct{}), exit: make(chan struct{})}
+
+	oldUpgrader := newUpgrader
+	oldNotify := signalNotify
+	oldStop := signalStop
+	newUpgrader = func(*upgradeOptions) (upgrader, error) { return upg, nil }
+	signalNotify = func(chan<- os.Signal, ...os.Signal) {}
+	signalStop = func(chan<- os.Signal) {}
+	defer func() {
+		newUpgrader = oldUpgrader
+		signalNotify = oldNotify
+		signalStop = oldStop
+	}()
+
+	runDone := make(chan error, 1)
+	go func() {
+		runDone <- NewApp(WithGracefulUpgrade("")).
+			AddServer(NewHttp("127.0.0.1:0", func() http.Handler { return http.NotFoundHandler() })).
+			Run()
+	}()
+
+	select {
+	case <-upg.ready:
+	case <-time.After(time.Second):
+		t.Fatal("upgrader was not marked ready")
+	}
+	if upg.listens.Load() != 1 {
+		t.Fatalf("expected listener from upgrader, got %d", upg.listens.Load())
+	}
+
+	close(upg.exit)
+	select {
+	case err := <-runDone:
+		if err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not exit after upgrade")
+	}
+}
@@ -0,0 +1,323 @@
+package xapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xutil"
+)
+
+// WorkerFunc 是后台循环的主体，ctx 取消时应尽快返回。
+// 返回 nil 表示任务已完成，不再重启；返回错误或 panic 时按退避策略重启
+type WorkerFunc func(ctx context.Context) error
+
+type WorkerOption func(*workerOptions)
+
+type workerOptions struct {
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	maxRestarts int
+	stopTimeout time.Duration
+}
+
+// WithWorkerBackoff 设置重启退避时间，从 min 开始翻倍直到 max，默认 1s ~ 30s。
+// min 最小为 minWorkerBackoff，单次运行超过 max 后退避时间与重启计数会重置
+func WithWorkerBackoff(min, max time.Duration) WorkerOption {
+	return func(o *workerOptions) {
+		o.minBackoff = min
+		o.maxBackoff = max
+	}
+}
+
+// WithMaxRestarts 设置连续重启次数上限，超过后 Worker 退出并返回最后一次错误，App 随之优雅关闭。
+// 默认 0 表示不限制
+func WithMaxRestarts(n int) WorkerOption {
+	return func(o *workerOptions) {
+		o.maxRestarts = n
+	}
+}
+
+// WithWorkerStopTimeout 设置取消 ctx 后等待 WorkerFunc 返回的时间，默认 10s
+func WithWorkerStopTimeout(timeout time.Duration) WorkerOption {
+	return func(o *workerOptions) {
+		o.stopTimeout = timeout
+	}
+}
+
+// minWorkerBackoff 重启退避的下限，避免失败的 Worker 无间隔地重启
+const minWorkerBackoff = time.Millisecond
+
+type workerIndexKey struct{}
+
+// WorkerIndex 返回 NewWorkerPool 中副本的序号，单个 Worker 为 0
+func WorkerIndex(ctx context.Context) int {
+	index, _ := ctx.Value(workerIndexKey{}).(int)
+	return index
+}
+
+// NewWorker 创建受监管的后台任务，加入 App 时注册名为 worker:{name} 的健康检查
+func NewWorker(name string, fn WorkerFunc, opts ...WorkerOption) NewServer {
+	return func() Server {
+		return newWorker(name, 0, fn, opts...)
+	}
+}
+
+// NewWorkerPool 创建 n 个受监管的副本，副本序号通过 WorkerIndex 获取。
+// 任一副本超过重启上限时整个 Pool 退出
+func NewWorkerPool(n int, name string, fn WorkerFunc, opts ...WorkerOption) NewServer {
+	return func() Server {
+		p := &WorkerPool{name: name}
+		for i := 0; i < n; i++ {
+			p.workers = append(p.workers, newWorker(fmt.Sprintf("%s-%d", name, i), i, fn, opts...))
+		}
+		return p
+	}
+}
+
+// Worker 实现 Server，Start 阻塞直到任务完成、超过重启上限或被停止
+type Worker struct {
+	name string
+	fn   WorkerFunc
+	opts *workerOptions
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+	started chan struct{}
+	once    sync.Once
+
+	mu       sync.Mutex
+	running  bool
+	failed   bool // 超过重启上限，不再重启
+	restarts int
+	lastErr  error
+}
+
+func newWorker(name string, index int, fn WorkerFunc, opts ...WorkerOption) *Worker {
+	o := &workerOptions{
+		minBackoff:  time.Second,
+		maxBackoff:  30 * time.Second,
+		stopTimeout: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.minBackoff < minWorkerBackoff {
+		o.minBackoff = minWorkerBackoff
+	}
+	if o.maxBackoff < o.minBackoff {
+		o.maxBackoff = o.minBackoff
+	}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), workerIndexKey{}, index))
+	return &Worker{
+		name:    name,
+		fn:      fn,
+		opts:    o,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		started: make(chan struct{}),
+	}
+}
+
+func (w *Worker) Start() error {
+	defer close(w.done)
+
+	backoff := w.opts.minBackoff
+	for {
+		w.setRunning(true, nil)
+		w.once.Do(func() { close(w.started) })
+
+		begin := time.Now()
+		err := w.run()
+		if w.ctx.Err() != nil {
+			w.setRunning(false, nil)
+			return nil
+		}
+		if err == nil {
+			xlog.Info("worker finished", xlog.String("worker", w.name))
+			w.setRunning(false, nil)
+			return nil
+		}
+
+		// 运行足够久视为已恢复，重新计算退避
+		if time.Since(begin) > w.opts.maxBackoff {
+			backoff = w.opts.minBackoff
+			w.mu.Lock()
+			w.restarts = 0
+			w.mu.Unlock()
+		}
+
+		w.mu.Lock()
+		w.running = false
+		w.lastErr = err
+		w.restarts++
+		restarts := w.restarts
+		w.mu.Unlock()
+
+		if w.opts.maxRestarts > 0 && restarts > w.opts.maxRestarts {
+			w.mu.Lock()
+			w.failed = true
+			w.mu.Unlock()
+			xlog.Error("worker exceeded max restarts", xlog.String("worker", w.name), xlog.Int("max_restarts", w.opts.maxRestarts), xlog.Err(err))
+			return fmt.Errorf("worker %s exceeded max restarts: %w", w.name, err)
+		}
+
+		xlog.Warn("worker failed, restarting",
+			xlog.String("worker", w.name),
+			xlog.Int("restarts", restarts),
+			xlog.Duration("backoff", backoff),
+			xlog.Err(err))
+
+		select {
+		case <-w.ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > w.opts.maxBackoff {
+			backoff = w.opts.maxBackoff
+		}
+	}
+}
+
+// run 执行一次 WorkerFunc，panic 转为错误
+func (w *Worker) run() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			xlog.Error("worker panic", xlog.String("worker", w.name), xlog.Any("error", r), xlog.String("stack", string(xutil.Stack(3))))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return w.fn(w.ctx)
+}
+
+func (w *Worker) setRunning(running bool, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running = running
+	w.lastErr = err
+}
+
+// Started 在第一次运行 WorkerFunc 时关闭
+func (w *Worker) Started() <-chan struct{} {
+	return w.started
+}
+
+func (w *Worker) ShutdownPriority() int {
+	return ShutdownPriorityWorker
+}
+
+// Shutdown 取消 ctx 并等待 WorkerFunc 返回，最长等待 WithWorkerStopTimeout
+func (w *Worker) Shutdown(ctx context.Context) error {
+	w.cancel()
+	if w.opts.stopTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.opts.stopTimeout)
+		defer cancel()
+	}
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("worker %s did not stop: %w", w.name, ctx.Err())
+	}
+}
+
+func (w *Worker) Stop() {
+	if err := w.Shutdown(context.Background()); err != nil {
+		xlog.Warn("worker stop", xlog.Err(err))
+	}
+}
+
+// HealthCheckName 实现 healthCheckServer，加入 App 时注册健康检查
+func (w *Worker) HealthCheckName() string {
+	return "worker:" + w.name
+}
+
+// HealthCheck 在 WorkerFunc 因错误退出、等待重启期间返回最后一次错误，超过重启上限后返回失败
+func (w *Worker) HealthCheck(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failed {
+		return fmt.Errorf("worker %s failed after %d restarts: %w", w.name, w.restarts-1, w.lastErr)
+	}
+	if !w.running && w.lastErr != nil {
+		return fmt.Errorf("worker %s is restarting: %w", w.name, w.lastErr)
+	}
+	return nil
+}
+
+// WorkerPool 同时运行多个 Worker 副本
+type WorkerPool struct {
+	name    string
+	workers []*Worker
+}
+
+func (p *WorkerPool) Start() error {
+	errs := make(chan error, len(p.workers))
+	var wg sync.WaitGroup
+	for _, w := range p.workers {
+		wg.Add(1)
+		go func(w *Worker) {
+			defer wg.Done()
+			if err := w.Start(); err != nil {
+				errs <- err
+				// 一个副本失败时停止其余副本
+				for _, other := range p.workers {
+					other.cancel()
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	var all []error
+	for err := range errs {
+		all = append(all, err)
+	}
+	return errors.Join(all...)
+}
+
+func (p *WorkerPool) ShutdownPriority() int {
+	return ShutdownPriorityWorker
+}
+
+func (p *WorkerPool) Shutdown(ctx context.Context) error {
+	errs := make([]error, len(p.workers))
+	var wg sync.WaitGroup
+	for i, w := range p.workers {
+		wg.Add(1)
+		go func(i int, w *Worker) {
+			defer wg.Done()
+			errs[i] = w.Shutdown(ctx)
+		}(i, w)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (p *WorkerPool) Stop() {
+	if err := p.Shutdown(context.Background()); err != nil {
+		xlog.Warn("worker pool stop", xlog.Err(err))
+	}
+}
+
+func (p *WorkerPool) HealthCheckName() string {
+	return "worker:" + p.name
+}
+
+func (p *WorkerPool) HealthCheck(ctx context.Context) error {
+	var errs []error
+	for _, w := range p.workers {
+		if err := w.HealthCheck(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,156 @@
+package xapp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerRestartsOnErrorAndPanic(t *testing.T) {
+	var runs atomic.Int32
+	w := NewWorker("test-restart", func(ctx context.Context) error {
+		switch runs.Add(1) {
+		case 1:
+			return errors.New("boom")
+		case 2:
+			panic("oops")
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithWorkerBackoff(time.Millisecond, 10*time.Millisecond))().(*Worker)
+
+	done := make(chan error, 1)
+	go func() { done <- w.Start() }()
+
+	deadline := time.After(time.Second)
+	for runs.Load() < 3 {
+		select {
+		case <-deadline:
+			t.Fatalf("worker restarted %d times", runs.Load())
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if err := w.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("expected healthy worker, got %v", err)
+	}
+
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("expected nil after stop, got %v", err)
+	}
+}
+
+func TestWorkerMaxRestarts(t *testing.T) {
+	var runs atomic.Int32
+	w := newWorker("test-max", 0, func(ctx context.Context) error {
+		runs.Add(1)
+		return errors.New("always fails")
+	}, WithWorkerBackoff(time.Millisecond, time.Millisecond), WithMaxRestarts(2))
+
+	err := w.Start()
+	if err == nil || !strings.Contains(err.Error(), "exceeded max restarts") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if runs.Load() != 3 {
+		t.Fatalf("expected 3 runs, got %d", runs.Load())
+	}
+	if err := w.HealthCheck(context.Background()); err == nil || !strings.Contains(err.Error(), "failed after 2 restarts") {
+		t.Fatalf("expected failed worker, got %v", err)
+	}
+}
+
+func TestWorkerZeroBackoffIsClamped(t *testing.T) {
+	w := newWorker("test-zero", 0, func(ctx context.Context) error {
+		return errors.New("always fails")
+	}, WithWorkerBackoff(0, 0), WithMaxRestarts(3))
+	if w.opts.minBackoff <= 0 || w.opts.maxBackoff < w.opts.minBackoff {
+		t.Fatalf("unexpected backoff %v ~ %v", w.opts.minBackoff, w.opts.maxBackoff)
+	}
+	start := time.Now()
+	if err := w.Start(); err == nil {
+		t.Fatal("expected max restarts error")
+	}
+	if time.Since(start) < 3*minWorkerBackoff {
+		t.Fatal("worker restarted without backoff")
+	}
+}
+
+func TestAppShutsDownWhenWorkerFails(t *testing.T) {
+	var hookCalled atomic.Bool
+	app := NewApp().
+		AddServer(NewWorker("test-app-fail", func(ctx context.Context) error {
+			return errors.New("always fails")
+		}, WithWorkerBackoff(time.Millisecond, time.Millisecond), WithMaxRestarts(1))).
+		AddShutdown("cleanup", func(ctx context.Context) error {
+			hookCalled.Store(true)
+			return nil
+		}, ShutdownPriorityResource)
+
+	err := app.Run()
+	if err == nil || !strings.Contains(err.Error(), "exceeded max restarts") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !hookCalled.Load() {
+		t.Fatal("shutdown hooks should run when a worker fails")
+	}
+
+	report := app.Health().Check(context.Background())
+	if !strings.Contains(report.Checks["worker:test-app-fail"].Error, "failed after") {
+		t.Fatalf("unexpected health report %+v", report)
+	}
+	globalChecksMu.RLock()
+	_, leaked := globalChecks["worker:test-app-fail"]
+	globalChecksMu.RUnlock()
+	if leaked {
+		t.Fatal("worker health check should not be registered globally")
+	}
+}
+
+func TestWorkerStopTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	w := newWorker("test-stuck", 0, func(ctx context.Context) error {
+		// 忽略 ctx 取消
+		<-release
+		return nil
+	}, WithWorkerStopTimeout(20*time.Millisecond))
+	go w.Start()
+	<-w.Started()
+
+	err := w.Shutdown(context.Background())
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	var seen [3]atomic.Bool
+	p := NewWorkerPool(3, "test-pool", func(ctx context.Context) error {
+		seen[WorkerIndex(ctx)].Store(true)
+		<-ctx.Done()
+		return nil
+	})().(*WorkerPool)
+
+	done := make(chan error, 1)
+	go func() { done <- p.Start() }()
+	for _, w := range p.workers {
+		<-w.Started()
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	for i := range seen {
+		if !seen[i].Load() {
+			t.Fatalf("replica %d did not run", i)
+		}
+	}
+}
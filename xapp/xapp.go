@@ -2,14 +2,18 @@ package xapp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xmetrics"
 	"github.com/jessevdk/go-flags"
 )
 
@@ -37,10 +41,17 @@ type readinessServer interface {
 	Started() <-chan struct{}
 }
 
+// healthCheckServer 由提供健康检查的 Server 实现，App 创建 Server 时通过 AddHealthCheck 注册
+type healthCheckServer interface {
+	HealthCheckName() string
+	HealthCheck(ctx context.Context) error
+}
+
 var Args struct {
-	Bind          string `long:"bind" description:"Bind address" default:"127.0.0.1:4001" env:"BIND"`
-	EnableOpenAPI bool   `long:"enable-openapi" description:"Enable OpenAPI" env:"ENABLE_OPENAPI"`
-	AppEnv        string `long:"app-env" description:"App environment" env:"APP_ENV" default:"dev"`
+	Bind          string   `long:"bind" description:"Bind address" default:"127.0.0.1:4001" env:"BIND"`
+	EnableOpenAPI bool     `long:"enable-openapi" description:"Enable OpenAPI" env:"ENABLE_OPENAPI"`
+	AppEnv        string   `long:"app-env" description:"App environment" env:"APP_ENV" default:"dev"`
+	Set           []string `long:"set" description:"Override config value, e.g. --set db.dsn=xxx"`
 }
 
 func init() {
@@ -52,11 +63,66 @@ type App struct {
 	servers      []NewServer
 	beforeStart  []BeforeStart
 	afterStarted func()
+	health       *Health
+	drainDelay   time.Duration
+
+	shutdownHooks   []ShutdownHook
+	shutdownTimeout time.Duration
+	hookTimeout     time.Duration
+	keepResources   bool
+
+	upgrade *upgradeOptions
+}
+
+func NewApp(opts ...Option) *App {
+	xlog.Debug("app args", xlog.Any("args", maskedArgs()))
+	a := &App{
+		health: NewHealth(),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Health 返回 App 的健康检查状态，可用于挂载 /healthz、/readyz 到已有路由
+func (a *App) Health() *Health {
+	return a.health
+}
+
+func (a *App) AddHealthCheck(name string, check HealthCheck) *App {
+	a.health.Register(name, check)
+	return a
+}
+
+// AddMetricsServer 在独立端口上提供 Prometheus 指标
+func (a *App) AddMetricsServer(addr string) *App {
+	return a.AddServer(NewHttp(addr, xmetrics.Handler))
+}
+
+// AddHealthServer 在独立端口上提供 /healthz、/readyz、/livez
+func (a *App) AddHealthServer(addr string) *App {
+	return a.AddServer(NewHttp(addr, a.health.Handler))
+}
+
+// SetDrainDelay 收到退出信号后，/readyz 先返回 503，等待 delay 后再停止 Server，
+// 给负载均衡摘除流量留出时间
+func (a *App) SetDrainDelay(delay time.Duration) *App {
+	a.drainDelay = delay
+	return a
 }
 
-func NewApp() *App {
-	xlog.Debug("app args", xlog.Any("args", fmt.Sprintf("%+v", Args)))
-	return &App{}
+// maskedArgs 返回用于日志输出的 Args，--set 中的敏感值及已解析的密钥会被脱敏
+func maskedArgs() string {
+	args := Args
+	args.Set = make([]string, len(Args.Set))
+	for i, kv := range Args.Set {
+		if key, value, ok := strings.Cut(kv, "="); ok && IsSecretKey(key) {
+			kv = key + "=" + MaskSecret(value)
+		}
+		args.Set[i] = kv
+	}
+	return MaskSecrets(fmt.Sprintf("%+v", args))
 }
 
 func (a *App) AddStartup(startup ...Startup) *App {
@@ -80,6 +146,10 @@ func (a *App) AfterStarted(fn func()) *App {
 }
 
 func (a *App) Run() error {
+	if a.health == nil {
+		a.health = NewHealth()
+	}
+
 	// 执行所有 Startup 函数
 	for _, startup := range a.startups {
 		if err := startup(); err != nil {
@@ -93,6 +163,20 @@ func (a *App) Run() error {
 		fn()
 	}
 
+	// 启用平滑升级时，Server 的监听器由 tableflip 提供
+	var upg upgrader
+	var upgExit <-chan struct{}
+	if a.upgrade != nil {
+		var err error
+		upg, err = newUpgrader(a.upgrade)
+		if err != nil {
+			return fmt.Errorf("graceful upgrade error: %w", err)
+		}
+		defer upg.Stop()
+		defer watchUpgrade(upg)()
+		upgExit = upg.Exit()
+	}
+
 	// 启动所有 Server
 	errChan := make(chan error, len(a.servers))
 	var wg sync.WaitGroup
@@ -106,6 +190,12 @@ func (a *App) Run() error {
 	for _, newServer := range a.servers {
 		server := newServer()
 		servers = append(servers, server)
+		if hs, ok := server.(healthCheckServer); ok {
+			a.AddHealthCheck(hs.HealthCheckName(), hs.HealthCheck)
+		}
+		if ls, ok := server.(listenerServer); ok && upg != nil {
+			ls.SetListenFunc(upg.Listen)
+		}
 
 		ready := make(chan struct{})
 		var readyOnce sync.Once
@@ -137,6 +227,19 @@ func (a *App) Run() error {
 		}(server, closeReady, isReadyByStartCall(server))
 	}
 
+	go func() {
+		if !waitForServersReady(ctx, readyChans) || startupFailed.Load() {
+			return
+		}
+		a.health.SetReady(true)
+		// 通知父进程新进程已就绪，父进程随后退出
+		if upg != nil {
+			if err := upg.Ready(); err != nil {
+				xlog.Error("upgrade ready error", xlog.Err(err))
+			}
+		}
+	}()
+
 	if a.afterStarted != nil && waitForServersReady(ctx, readyChans) && !startupFailed.Load() {
 		a.afterStarted()
 	}
@@ -149,16 +252,10 @@ func (a *App) Run() error {
 	var signalCount int
 	shutdownChan := make(chan struct{})
 
-	// 启动一个 goroutine 处理信号
+	// 启动一个 goroutine 处理信号，重复信号不再强制退出，由关闭期限兜底
 	go func() {
 		for sig := range sigChan {
 			signalCount++
-			if signalCount >= 2 {
-				xlog.Warn("received multiple signals, force exit",
-					xlog.Any("signal", sig),
-					xlog.Int("count", signalCount))
-				os.Exit(1) // 强制退出
-			}
 			xlog.Debug("received signal",
 				xlog.Any("signal", sig),
 				xlog.Int("count", signalCount))
@@ -168,29 +265,90 @@ func (a *App) Run() error {
 		}
 	}()
 
-	// 等待错误或信号
+	// 等待错误或信号，Server 出错时同样执行优雅关闭
 	select {
 	case err := <-errChan:
-		return fmt.Errorf("server error: %w", err)
+		return a.shutdownOnError(servers, &wg, err)
 	case <-shutdownChan:
 		xlog.Debug("Starting graceful shutdown...")
+	case <-upgExit:
+		xlog.Info("upgraded to new process, starting graceful shutdown...")
 	case <-ctx.Done():
 		select {
 		case err := <-errChan:
-			return fmt.Errorf("server error: %w", err)
+			return a.shutdownOnError(servers, &wg, err)
 		default:
 		}
 		xlog.Warn("Context cancelled")
 	}
 
-	// 优雅关闭
+	return a.shutdown(servers, &wg)
+}
+
+// shutdownOnError 在 Server 返回错误后关闭其余 Server 并执行关闭钩子
+func (a *App) shutdownOnError(servers []Server, wg *sync.WaitGroup, err error) error {
+	xlog.Error("server error, starting graceful shutdown...", xlog.Err(err))
+	err = fmt.Errorf("server error: %w", err)
+	if shutdownErr := a.shutdown(servers, wg); shutdownErr != nil {
+		return errors.Join(err, shutdownErr)
+	}
+	return err
+}
+
+// shutdown 依次执行：摘除流量、排空 HTTP、停止后台任务、关闭连接。
+// 超过整体关闭期限后强制退出进程
+func (a *App) shutdown(servers []Server, wg *sync.WaitGroup) error {
+	timeout := a.shutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	start := time.Now()
+	forceExit := time.AfterFunc(timeout, func() {
+		xlog.Error("graceful shutdown deadline exceeded, force exit", xlog.Duration("timeout", timeout))
+		osExit(1)
+	})
+	defer forceExit.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// 1. 摘除流量
+	a.health.Shutdown()
+	if a.drainDelay > 0 {
+		xlog.Debug("Waiting for traffic drain", xlog.Duration("delay", a.drainDelay))
+		select {
+		case <-time.After(a.drainDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	// 2. 按优先级关闭 Server 及注册的钩子
 	xlog.Debug("Shutting down servers...", xlog.Any("num", len(servers)))
-	for _, server := range servers {
-		server.Stop()
+	hooks := make([]ShutdownHook, 0, len(servers)+len(a.shutdownHooks)+3)
+	for i, server := range servers {
+		hooks = append(hooks, serverShutdownHook(i, server))
+	}
+	hooks = append(hooks, a.shutdownHooks...)
+	if !a.keepResources {
+		hooks = append(hooks, resourceShutdownHooks()...)
+	}
+	err := a.runShutdownHooks(ctx, hooks)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		xlog.Debug("All servers stopped", xlog.Duration("duration", time.Since(start)))
+	case <-ctx.Done():
+		xlog.Warn("servers did not exit before shutdown deadline")
 	}
 
-	wg.Wait()
-	xlog.Debug("All servers stopped")
+	if err != nil {
+		return fmt.Errorf("shutdown error: %w", err)
+	}
 	return nil
 }
 
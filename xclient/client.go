@@ -0,0 +1,227 @@
+package xclient
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/daodao97/xgo/xcode"
+	"github.com/daodao97/xgo/xrequest"
+)
+
+// SuccessCode 与服务端 xapp.SuccessCode 保持一致
+var SuccessCode = 0
+
+type Option func(*Client)
+
+// WithHeader 为每个请求添加固定的 header，如鉴权信息
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.headers[key] = value
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.client = client
+	}
+}
+
+// WithoutEnvelope 用于服务端使用 xapp.ProblemRenderer 的场景，成功响应体即为 data
+func WithoutEnvelope() Option {
+	return func(c *Client) {
+		c.noEnvelope = true
+	}
+}
+
+// Client 是 xapp.GenerateClient 生成代码使用的运行时
+type Client struct {
+	baseURL string
+	headers map[string]string
+	timeout time.Duration
+	client  *http.Client
+
+	noEnvelope bool
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		headers: map[string]string{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// envelope 对应 xapp.HanderFunc 输出的 {code, message, data}
+type envelope struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// problem 对应 xapp.ProblemRenderer 输出的 application/problem+json
+type problem struct {
+	Type   string `json:"type"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+	Code   int    `json:"code"`
+}
+
+// Call 按 HanderFunc 的绑定规则发送请求：uri tag 填充路径参数，header tag 作为请求头，
+// GET/DELETE/HEAD 使用 form tag 作为查询参数，其他方法以 JSON 发送请求体。
+// 响应 code 不等于 SuccessCode 时返回 *xcode.Code
+func Call[Req any, Resp any](ctx context.Context, c *Client, method, path string, req Req) (*Resp, error) {
+	v := reflect.Indirect(reflect.ValueOf(&req).Elem())
+
+	target := c.baseURL + fillPath(path, v)
+	r := xrequest.New().WithContext(ctx).SetMethod(method).SetHeaders(c.headers)
+	if c.timeout > 0 {
+		r.SetTimeout(c.timeout)
+	}
+	if c.client != nil {
+		r.SetClient(c.client)
+	}
+	for k, val := range tagValues(v, "header") {
+		r.SetHeader(k, val[0])
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodDelete, http.MethodHead:
+		if query := url.Values(tagValues(v, "form")).Encode(); query != "" {
+			target += "?" + query
+		}
+	default:
+		body, err := json.Marshal(req)
+		if err != nil {
+			return nil, fmt.Errorf("marshal request: %w", err)
+		}
+		r.SetBody(body)
+	}
+
+	resp, err := r.SetURL(target).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(resp.RawResponse.Header.Get("Content-Type"), "application/problem+json") {
+		var p problem
+		if err := resp.Scan(&p); err != nil {
+			return nil, err
+		}
+		code := &xcode.Code{Code: p.Code, HttpCode: resp.StatusCode(), Message: p.Detail}
+		if p.Type != "about:blank" {
+			code.Type = p.Type
+		}
+		return nil, code
+	}
+	if c.noEnvelope {
+		if resp.IsError() {
+			return nil, &xcode.Code{Code: resp.StatusCode(), HttpCode: resp.StatusCode(), Message: strings.TrimSpace(resp.String())}
+		}
+		if resp.StatusCode() == http.StatusNoContent || resp.BodyIsEmpty() {
+			return nil, nil
+		}
+		out := new(Resp)
+		if err := resp.Scan(out); err != nil {
+			return nil, fmt.Errorf("unmarshal response: %w", err)
+		}
+		return out, nil
+	}
+
+	var env envelope
+	if err := resp.Scan(&env); err != nil {
+		// 非 HanderFunc 的响应，如网关错误页
+		return nil, &xcode.Code{Code: resp.StatusCode(), HttpCode: resp.StatusCode(), Message: strings.TrimSpace(resp.String()), Err: err}
+	}
+	if env.Code != SuccessCode {
+		return nil, &xcode.Code{Code: env.Code, HttpCode: resp.StatusCode(), Message: env.Message}
+	}
+	if len(env.Data) == 0 || string(env.Data) == "null" {
+		return nil, nil
+	}
+
+	out := new(Resp)
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return nil, fmt.Errorf("unmarshal response data: %w", err)
+	}
+	return out, nil
+}
+
+// fillPath 将 gin 路由中的 :name、*name 替换为 uri tag 对应的字段值
+func fillPath(path string, v reflect.Value) string {
+	if !strings.ContainsAny(path, ":*") {
+		return path
+	}
+	values := tagValues(v, "uri")
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			if val, ok := values[part[1:]]; ok {
+				parts[i] = url.PathEscape(val[0])
+			}
+		} else if strings.HasPrefix(part, "*") {
+			if val, ok := values[part[1:]]; ok {
+				parts[i] = strings.TrimPrefix(val[0], "/")
+			}
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// tagValues 收集带有 tag 的非零值字段，匿名嵌入的结构体会展开
+func tagValues(v reflect.Value, tag string) map[string][]string {
+	values := map[string][]string{}
+	collectTagValues(v, tag, values)
+	return values
+}
+
+func collectTagValues(v reflect.Value, tag string, values map[string][]string) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if field.Anonymous && name == "" {
+			collectTagValues(reflect.Indirect(fv), tag, values)
+			continue
+		}
+		if name == "" || name == "-" || !field.IsExported() || fv.IsZero() {
+			continue
+		}
+		fv = reflect.Indirect(fv)
+		if fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array {
+			for j := 0; j < fv.Len(); j++ {
+				values[name] = append(values[name], formatValue(fv.Index(j)))
+			}
+			continue
+		}
+		values[name] = append(values[name], formatValue(fv))
+	}
+}
+
+func formatValue(v reflect.Value) string {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,104 @@
+package xclient
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/daodao97/xgo/xapp"
+	"github.com/daodao97/xgo/xcode"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type getUserReq struct {
+	ID    int      `uri:"id" json:"-"`
+	Token string   `header:"X-Token" json:"-"`
+	Tags  []string `form:"tag"`
+}
+
+var errNotFound = &xcode.Code{Code: 10404, HttpCode: 404, Message: "user not found"}
+
+func TestCall(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/users/:id", xapp.HanderFunc(func(c *gin.Context, req getUserReq) (*user, error) {
+		if req.ID != 1 {
+			return nil, errNotFound
+		}
+		if c.GetHeader("X-Token") != "t" || c.GetHeader("X-App") != "test" || len(req.Tags) != 2 {
+			t.Errorf("unexpected request %+v", req)
+		}
+		return &user{ID: req.ID, Name: "daodao"}, nil
+	}))
+	e.POST("/users", xapp.HanderFunc(func(c *gin.Context, req user) (*user, error) {
+		return &req, nil
+	}))
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	c := New(srv.URL, WithHeader("X-App", "test"))
+	ctx := context.Background()
+
+	got, err := Call[getUserReq, user](ctx, c, "GET", "/users/:id", getUserReq{ID: 1, Token: "t", Tags: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "daodao" {
+		t.Fatalf("unexpected response %+v", got)
+	}
+
+	created, err := Call[user, user](ctx, c, "POST", "/users", user{ID: 2, Name: "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != 2 || created.Name != "new" {
+		t.Fatalf("unexpected response %+v", created)
+	}
+
+	_, err = Call[getUserReq, user](ctx, c, "GET", "/users/:id", getUserReq{ID: 2})
+	var code *xcode.Code
+	if !errors.As(err, &code) {
+		t.Fatalf("expected *xcode.Code, got %v", err)
+	}
+	if code.Code != 10404 || code.HttpCode != 404 || code.Message != "user not found" {
+		t.Fatalf("unexpected code %+v", code)
+	}
+}
+
+func TestCallWithoutEnvelope(t *testing.T) {
+	xapp.SetRenderer(xapp.ProblemRenderer{})
+	defer xapp.SetRenderer(xapp.EnvelopeRenderer{})
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/users/:id", xapp.HanderFunc(func(c *gin.Context, req getUserReq) (*user, error) {
+		if req.ID != 1 {
+			return nil, errNotFound
+		}
+		return &user{ID: req.ID, Name: "daodao"}, nil
+	}))
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	c := New(srv.URL, WithoutEnvelope())
+	got, err := Call[getUserReq, user](context.Background(), c, "GET", "/users/:id", getUserReq{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "daodao" {
+		t.Fatalf("unexpected response %+v", got)
+	}
+
+	_, err = Call[getUserReq, user](context.Background(), c, "GET", "/users/:id", getUserReq{ID: 2})
+	var code *xcode.Code
+	if !errors.As(err, &code) || code.Code != 10404 || code.HttpCode != 404 || code.Message != "user not found" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package xcron
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes 挂载任务状态接口，通常挂载在 xadmin.GinRoute 返回的路由组上
+//
+//	GET /cron/jobs                   所有任务的状态
+//	GET /cron/jobs/:name/runs?limit= 任务最近的执行记录，默认 20 条
+//	POST /cron/jobs/:name/trigger    立即执行一次
+//	POST /cron/jobs/:name/pause      暂停调度
+//	POST /cron/jobs/:name/resume     恢复调度
+func (c *Cron) RegisterRoutes(r gin.IRoutes) {
+	r.GET("/cron/jobs", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": c.Jobs()})
+	})
+	r.GET("/cron/jobs/:name/runs", func(ctx *gin.Context) {
+		name := ctx.Param("name")
+		if !c.HasJob(name) {
+			ctx.JSON(http.StatusOK, gin.H{"code": 404, "message": "job not found: " + name})
+			return
+		}
+		limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+		runs, err := c.Runs(name, limit)
+		if err != nil {
+			ctx.JSON(http.StatusOK, gin.H{"code": 500, "message": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": runs})
+	})
+	r.POST("/cron/jobs/:name/trigger", c.action(c.Trigger))
+	r.POST("/cron/jobs/:name/pause", c.action(c.Pause))
+	r.POST("/cron/jobs/:name/resume", c.action(c.Resume))
+}
+
+func (c *Cron) action(fn func(name string) error) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if err := fn(ctx.Param("name")); errors.Is(err, ErrJobNotFound) {
+			ctx.JSON(http.StatusOK, gin.H{"code": 404, "message": err.Error()})
+			return
+		} else if err != nil {
+			ctx.JSON(http.StatusOK, gin.H{"code": 500, "message": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+	}
+}
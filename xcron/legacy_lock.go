@@ -0,0 +1,75 @@
+package xcron
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var legacyRefreshScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+var legacyUnlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// legacyLock 兼容旧版本直接以 SET NX 持有的 xcron:lock:... key，使滚动发布期间新旧实例依然互斥。
+// 值为 xredis.Lock 的 token，任务运行期间每 ttl/3 续期，释放时只删除自己写入的值
+type legacyLock struct {
+	rdb   redis.UniversalClient
+	key   string
+	token string
+	ttl   time.Duration
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// acquireLegacyLock 旧 key 已被占用时返回 false
+func acquireLegacyLock(ctx context.Context, rdb redis.UniversalClient, key, token string, ttl time.Duration) (*legacyLock, bool, error) {
+	ok, err := rdb.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	l := &legacyLock{
+		rdb:   rdb,
+		key:   key,
+		token: token,
+		ttl:   ttl,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go l.renew()
+	return l, true, nil
+}
+
+func (l *legacyLock) renew() {
+	defer close(l.done)
+	interval := l.ttl / 3
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			_ = legacyRefreshScript.Run(context.Background(), l.rdb, []string{l.key}, l.token, l.ttl.Milliseconds()).Err()
+		}
+	}
+}
+
+func (l *legacyLock) unlock(ctx context.Context) error {
+	close(l.stop)
+	<-l.done
+	return legacyUnlockScript.Run(ctx, l.rdb, []string{l.key}, l.token).Err()
+}
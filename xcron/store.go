@@ -0,0 +1,205 @@
+package xcron
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/daodao97/xgo/xdb"
+)
+
+type RunStatus string
+
+const (
+	RunRunning RunStatus = "running"
+	RunSuccess RunStatus = "success"
+	RunFailed  RunStatus = "failed"
+	RunPanic   RunStatus = "panic"
+)
+
+// Run 一次任务执行的记录
+type Run struct {
+	ID        int64         `json:"id"`
+	Cron      string        `json:"cron"`
+	Job       string        `json:"job"`
+	Instance  string        `json:"instance"`
+	Fence     int64         `json:"fence,omitempty"` // 持有分布式锁时的 fencing token
+	Status    RunStatus     `json:"status"`
+	StartedAt time.Time     `json:"started_at"`
+	EndedAt   time.Time     `json:"ended_at,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+	Stack     string        `json:"stack,omitempty"`
+}
+
+// RunStore 保存任务的执行历史
+type RunStore interface {
+	// Start 保存开始执行的记录，并设置 run.ID
+	Start(ctx context.Context, run *Run) error
+	// Finish 更新执行结果
+	Finish(ctx context.Context, run *Run) error
+	// Runs 按开始时间倒序返回最近 limit 条记录
+	Runs(ctx context.Context, cron, job string, limit int) ([]Run, error)
+}
+
+// NewMemoryStore 在内存中保存每个任务最近 limit 条记录，limit 不大于 0 时为 100
+func NewMemoryStore(limit int) RunStore {
+	if limit <= 0 {
+		limit = 100
+	}
+	return &memoryStore{limit: limit, runs: make(map[string][]*Run)}
+}
+
+type memoryStore struct {
+	mu    sync.RWMutex
+	limit int
+	seq   int64
+	runs  map[string][]*Run
+}
+
+func (s *memoryStore) Start(_ context.Context, run *Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	run.ID = s.seq
+	key := run.Cron + "/" + run.Job
+	r := *run
+	list := append(s.runs[key], &r)
+	if len(list) > s.limit {
+		list = list[len(list)-s.limit:]
+	}
+	s.runs[key] = list
+	return nil
+}
+
+func (s *memoryStore) Finish(_ context.Context, run *Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.runs[run.Cron+"/"+run.Job]
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].ID == run.ID {
+			*list[i] = *run
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) Runs(_ context.Context, cron, job string, limit int) ([]Run, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := s.runs[cron+"/"+job]
+	out := make([]Run, 0, max(min(limit, len(list)), 0))
+	for i := len(list) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, *list[i])
+	}
+	return out, nil
+}
+
+// NewDBStore 使用 xdb 连接 conn 保存执行记录，表结构见 RunStoreSchema
+func NewDBStore(conn, table string) RunStore {
+	if table == "" {
+		table = "xcron_runs"
+	}
+	return &dbStore{conn: conn, table: table}
+}
+
+// RunStoreSchema 返回执行记录表的建表语句，时间字段为毫秒时间戳
+func RunStoreSchema(driver, table string) []string {
+	columns := `cron VARCHAR(191) NOT NULL,
+	job VARCHAR(191) NOT NULL,
+	instance VARCHAR(191) NOT NULL,
+	fence BIGINT NOT NULL DEFAULT 0,
+	status VARCHAR(16) NOT NULL,
+	started_at BIGINT NOT NULL,
+	ended_at BIGINT NOT NULL DEFAULT 0,
+	error TEXT,
+	stack TEXT`
+	index := fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_job ON %s (cron, job, started_at)", table, table)
+	switch xdb.GetDialect(driver).Name() {
+	case "postgres":
+		return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\tid BIGSERIAL PRIMARY KEY,\n\t%s\n)", table, columns), index}
+	case "sqlite":
+		return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\tid INTEGER PRIMARY KEY AUTOINCREMENT,\n\t%s\n)", table, columns), index}
+	default:
+		return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\tid BIGINT AUTO_INCREMENT PRIMARY KEY,\n\t%s,\n\tKEY idx_job (cron, job, started_at)\n)", table, columns)}
+	}
+}
+
+type dbStore struct {
+	conn  string
+	table string
+}
+
+func (s *dbStore) db() (*sql.DB, xdb.Dialect, error) {
+	db, err := xdb.DB(s.conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	dialect, err := xdb.ConnDialect(s.conn)
+	return db, dialect, err
+}
+
+func (s *dbStore) Start(ctx context.Context, run *Run) error {
+	db, dialect, err := s.db()
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (cron, job, instance, fence, status, started_at) VALUES (?, ?, ?, ?, ?, ?)", s.table)
+	args := []any{run.Cron, run.Job, run.Instance, run.Fence, string(run.Status), run.StartedAt.UnixMilli()}
+	if !dialect.SupportsLastInsertId() {
+		return db.QueryRowContext(ctx, dialect.ConvertPlaceholders(dialect.InsertReturning(query, "id")), args...).Scan(&run.ID)
+	}
+	res, err := db.ExecContext(ctx, dialect.ConvertPlaceholders(query), args...)
+	if err != nil {
+		return err
+	}
+	run.ID, err = res.LastInsertId()
+	return err
+}
+
+func (s *dbStore) Finish(ctx context.Context, run *Run) error {
+	db, dialect, err := s.db()
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("UPDATE %s SET status = ?, ended_at = ?, error = ?, stack = ? WHERE id = ?", s.table)
+	_, err = db.ExecContext(ctx, dialect.ConvertPlaceholders(query),
+		string(run.Status), run.EndedAt.UnixMilli(), run.Error, run.Stack, run.ID)
+	return err
+}
+
+func (s *dbStore) Runs(ctx context.Context, cron, job string, limit int) ([]Run, error) {
+	db, dialect, err := s.db()
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT id, instance, fence, status, started_at, ended_at, error, stack FROM %s WHERE cron = ? AND job = ? ORDER BY started_at DESC, id DESC LIMIT %d", s.table, limit)
+	rows, err := db.QueryContext(ctx, dialect.ConvertPlaceholders(query), cron, job)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Run
+	for rows.Next() {
+		run := Run{Cron: cron, Job: job}
+		var status string
+		var started, ended int64
+		var errMsg, stack sql.NullString
+		if err := rows.Scan(&run.ID, &run.Instance, &run.Fence, &status, &started, &ended, &errMsg, &stack); err != nil {
+			return nil, err
+		}
+		run.Status = RunStatus(status)
+		run.StartedAt = time.UnixMilli(started)
+		if ended > 0 {
+			run.EndedAt = time.UnixMilli(ended)
+			run.Duration = run.EndedAt.Sub(run.StartedAt)
+		}
+		run.Error = errMsg.String
+		run.Stack = stack.String
+		out = append(out, run)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,100 @@
+package xcron
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRunHistory(t *testing.T) {
+	c := New2(WithName("test"), WithJobs(
+		Job{Name: "ok", Spec: "@every 1h", Func: func() {}},
+		Job{Name: "boom", Spec: "@every 1h", Func: func() { panic("boom") }},
+	))
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	for _, job := range c.jobs {
+		c.executeWithLock(job)()
+	}
+
+	runs, err := c.Runs("boom", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Status != RunPanic || runs[0].Error != "panic: boom" || runs[0].Stack == "" {
+		t.Fatalf("unexpected runs %+v", runs)
+	}
+	if runs[0].Instance == "" || runs[0].EndedAt.IsZero() {
+		t.Fatalf("run not finished %+v", runs[0])
+	}
+
+	jobs := c.Jobs()
+	if len(jobs) != 2 || jobs[0].LastRun == nil || jobs[0].LastRun.Status != RunSuccess {
+		t.Fatalf("unexpected jobs %+v", jobs)
+	}
+	if next, ok := c.NextRun("ok"); !ok || time.Until(next) > time.Hour {
+		t.Fatalf("unexpected next run %v", next)
+	}
+	if _, ok := c.NextRun("missing"); ok {
+		t.Fatal("missing job should have no next run")
+	}
+
+	dup := Job{Name: "dup", Spec: "@every 1h", Func: func() {}}
+	if err := New2(WithJobs(dup, dup)).Start(); err == nil {
+		t.Fatal("expected duplicate job name error")
+	}
+}
+
+func TestMemoryStoreLimit(t *testing.T) {
+	s := NewMemoryStore(2)
+	for i := 0; i < 3; i++ {
+		run := &Run{Cron: "c", Job: "j", Status: RunSuccess}
+		_ = s.Start(context.Background(), run)
+	}
+	runs, _ := s.Runs(context.Background(), "c", "j", 10)
+	if len(runs) != 2 || runs[0].ID != 3 || runs[1].ID != 2 {
+		t.Fatalf("unexpected runs %+v", runs)
+	}
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c := New2(WithJobs(Job{Name: "ok", Spec: "@every 1h", Func: func() {}}))
+	c.executeWithLock(c.jobs[0])()
+	r := gin.New()
+	c.RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cron/jobs/ok/runs?limit=5", nil))
+	var resp struct {
+		Code int   `json:"code"`
+		Data []Run `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != 0 || len(resp.Data) != 1 || resp.Data[0].Status != RunSuccess {
+		t.Fatalf("unexpected response %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cron/jobs/missing/runs", nil))
+	if !strings.Contains(w.Body.String(), `"code":404`) {
+		t.Fatalf("expected not found, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cron/jobs", nil))
+	if !strings.Contains(w.Body.String(), `"name":"ok"`) {
+		t.Fatalf("unexpected jobs %s", w.Body.String())
+	}
+}
@@ -2,30 +2,85 @@ package xcron
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xmetrics"
+	"github.com/daodao97/xgo/xredis"
+	"github.com/daodao97/xgo/xtrace"
 	"github.com/daodao97/xgo/xutil"
 	"github.com/redis/go-redis/v9"
 	"github.com/robfig/cron/v3"
 )
 
+// ErrJobNotFound 任务不存在
+var ErrJobNotFound = errors.New("xcron: job not found")
+
+// ErrStopped 调度器已停止
+var ErrStopped = errors.New("xcron: cron stopped")
+
+// OverlapPolicy 同一实例上任务上次执行未结束时的处理方式
+type OverlapPolicy int
+
+const (
+	OverlapAllow OverlapPolicy = iota // 允许并发执行，默认
+	OverlapSkip                       // 跳过本次执行
+	OverlapQueue                      // 等待上次执行结束后再执行
+)
+
+func (p OverlapPolicy) String() string {
+	switch p {
+	case OverlapSkip:
+		return "skip"
+	case OverlapQueue:
+		return "queue"
+	default:
+		return "allow"
+	}
+}
+
 type Job struct {
-	Name           string        // 任务名称
-	Spec           string        // 任务执行时间
-	Func           func()        // 任务执行函数
-	Immediate      bool          // 是否立即执行
-	EnableDistLock bool          // 是否启用分布式锁
-	LockTimeout    time.Duration // 锁超时时间，默认5分钟
-	LockRetryDelay time.Duration // 获取锁失败重试延迟，默认1秒
+	Name           string                          // 任务名称
+	Spec           string                          // 任务执行时间
+	Func           func()                          // 任务执行函数，Run 为空时使用
+	Run            func(ctx context.Context) error // 任务执行函数，ctx 在超时或 Stop 时取消，返回错误记为 RunFailed
+	Timeout        time.Duration                   // 单次执行超时，默认不限制
+	Overlap        OverlapPolicy                   // 上次执行未结束时的处理方式，默认 OverlapAllow
+	Immediate      bool                            // 是否立即执行
+	EnableDistLock bool                            // 是否启用分布式锁
+	LockTimeout    time.Duration                   // 锁租期，默认5分钟，任务运行期间自动续期
+	LockRetryDelay time.Duration                   // 获取锁失败重试延迟，默认1秒
 }
 
 type Cron struct {
-	name string
-	jobs []Job
-	cron *cron.Cron
-	rdb  redis.UniversalClient
+	name        string
+	jobs        []Job
+	cron        *cron.Cron
+	rdb         redis.UniversalClient
+	store       RunStore
+	instance    string
+	stopTimeout time.Duration
+
+	// ctx 是所有任务 ctx 的父 ctx，停止时取消
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.RWMutex
+	entries map[string]*entry
+	stopped bool
+}
+
+type entry struct {
+	id     cron.EntryID
+	job    Job
+	exec   func() // 经过 overlap 策略包装的执行函数
+	paused bool
 }
 
 type Option func(*Cron)
@@ -48,6 +103,27 @@ func WithCron(cron *cron.Cron) Option {
 	}
 }
 
+// WithStore 设置执行历史的存储，默认 NewMemoryStore(100)
+func WithStore(store RunStore) Option {
+	return func(c *Cron) {
+		c.store = store
+	}
+}
+
+// WithInstance 设置当前实例的名称，记录在执行历史中，默认 hostname-pid
+func WithInstance(instance string) Option {
+	return func(c *Cron) {
+		c.instance = instance
+	}
+}
+
+// WithStopTimeout 设置 Stop 等待运行中任务结束的时间，超时后取消任务 ctx，默认 30 秒
+func WithStopTimeout(d time.Duration) Option {
+	return func(c *Cron) {
+		c.stopTimeout = d
+	}
+}
+
 func WithJobs(jobs ...Job) Option {
 	return func(c *Cron) {
 		c.jobs = append(c.jobs, jobs...)
@@ -66,25 +142,41 @@ func New2(opts ...Option) *Cron {
 	for _, opt := range opts {
 		opt(c)
 	}
+	return c.init()
+}
+
+func (c *Cron) init() *Cron {
+	if c.store == nil {
+		c.store = NewMemoryStore(100)
+	}
+	if c.instance == "" {
+		host, _ := os.Hostname()
+		c.instance = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if c.stopTimeout <= 0 {
+		c.stopTimeout = 30 * time.Second
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.entries = make(map[string]*entry)
 	return c
 }
 
 func New(jobs ...Job) *Cron {
-	return &Cron{
+	return (&Cron{
 		jobs: jobs,
 		cron: cron.New(
 			cron.WithSeconds(),
 			cron.WithLogger(NewLogger()),
 			cron.WithChain(cron.Recover(NewLogger())),
 		),
-	}
+	}).init()
 }
 
 func NewWithCron(c *cron.Cron, jobs ...Job) *Cron {
-	return &Cron{
+	return (&Cron{
 		jobs: jobs,
 		cron: c,
-	}
+	}).init()
 }
 
 func NewCron(opts ...cron.Option) *cron.Cron {
@@ -101,54 +193,155 @@ func (c *Cron) Start() error {
 		xlog.Debug("add job", xlog.String("name", job.Name), xlog.String("spec", job.Spec), xlog.Bool("dist_lock", job.EnableDistLock))
 
 		// Use executeWithLock wrapper for job execution
-		jobFunc := c.executeWithLock(job)
-		_, err := c.cron.AddFunc(job.Spec, jobFunc)
+		e := &entry{job: job, exec: overlapChain(job.Overlap).Then(cron.FuncJob(c.executeWithLock(job))).Run}
+		c.mu.Lock()
+		if _, ok := c.entries[job.Name]; ok {
+			c.mu.Unlock()
+			return fmt.Errorf("xcron: duplicate job name %s", job.Name)
+		}
+		id, err := c.cron.AddFunc(job.Spec, c.scheduled(job.Name))
 		if err != nil {
+			c.mu.Unlock()
 			return err
 		}
+		e.id = id
+		c.entries[job.Name] = e
+		c.mu.Unlock()
 
 		if job.Immediate {
-			xutil.Go(context.Background(), jobFunc)
+			xutil.Go(context.Background(), e.exec)
 		}
 	}
 	return nil
 }
 
+// BackgroundWorker 标记为后台任务，加入 xapp.App 时在 HTTP 排空之后停止
+func (c *Cron) BackgroundWorker() {}
+
+// Stop 停止调度并等待运行中的任务结束，最多等待 WithStopTimeout，超时后取消任务 ctx
 func (c *Cron) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.stopTimeout)
+	defer cancel()
+	if err := c.Shutdown(ctx); err != nil {
+		xlog.Warn("cron stop timeout, running jobs canceled", xlog.String("cron", c.name), xlog.Duration("timeout", c.stopTimeout))
+	}
+}
+
+// Shutdown 停止调度并等待运行中的任务结束，ctx 截止时取消任务 ctx 并返回
+func (c *Cron) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	c.stopped = true
+	c.mu.Unlock()
 	c.cron.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	defer c.cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-// tryLock attempts to acquire a distributed lock using Redis SET NX EX
-func (c *Cron) tryLock(ctx context.Context, lockKey string, timeout time.Duration) (bool, error) {
-	if c.rdb == nil {
-		return false, fmt.Errorf("redis client not available")
+// Trigger 立即异步执行一次任务，遵循任务的 overlap 策略与分布式锁，暂停的任务也会执行
+func (c *Cron) Trigger(name string) error {
+	c.mu.RLock()
+	e, ok := c.entries[name]
+	stopped := c.stopped
+	c.mu.RUnlock()
+	if !ok {
+		return ErrJobNotFound
 	}
+	if stopped {
+		return ErrStopped
+	}
+	xutil.Go(context.Background(), e.exec)
+	return nil
+}
 
-	// Use SET with NX (only set if not exists) and EX (set expiration time)
-	result := c.rdb.SetNX(ctx, lockKey, "locked", timeout)
-	return result.Val(), result.Err()
+// Pause 暂停任务的调度，不影响正在执行的任务
+func (c *Cron) Pause(name string) error {
+	return c.setPaused(name, true)
 }
 
-// releaseLock releases the distributed lock
-func (c *Cron) releaseLock(ctx context.Context, lockKey string) error {
-	if c.rdb == nil {
-		return nil
+// Resume 恢复被暂停的任务
+func (c *Cron) Resume(name string) error {
+	return c.setPaused(name, false)
+}
+
+func (c *Cron) setPaused(name string, paused bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+	e.paused = paused
+	return nil
+}
+
+// scheduled 返回注册到 cron 的函数，跳过暂停中的任务
+func (c *Cron) scheduled(name string) func() {
+	return func() {
+		c.mu.RLock()
+		e := c.entries[name]
+		paused := e == nil || e.paused
+		c.mu.RUnlock()
+		if paused {
+			xmetrics.ObserveCronSkip(name)
+			xlog.Debug("cron job paused, skip", xlog.String("job", name))
+			return
+		}
+		e.exec()
+	}
+}
+
+func overlapChain(policy OverlapPolicy) cron.Chain {
+	switch policy {
+	case OverlapSkip:
+		return cron.NewChain(cron.SkipIfStillRunning(NewLogger()))
+	case OverlapQueue:
+		return cron.NewChain(cron.DelayIfStillRunning(NewLogger()))
+	default:
+		return cron.NewChain()
 	}
-	return c.rdb.Del(ctx, lockKey).Err()
 }
 
 // executeWithLock wraps job execution with distributed lock
 func (c *Cron) executeWithLock(job Job) func() {
 	return func() {
+		// 停止后不再开始新的执行
+		c.mu.RLock()
+		if c.stopped {
+			c.mu.RUnlock()
+			return
+		}
+		c.wg.Add(1)
+		c.mu.RUnlock()
+		defer c.wg.Done()
+
+		// 每次执行是一个新的 trace，加锁等 redis 操作作为其子 span
+		ctx, span := xtrace.Start(c.ctx, "xcron."+job.Name,
+			xtrace.WithSpanAttr("cron.name", c.name),
+			xtrace.WithSpanAttr("cron.job", job.Name),
+			xtrace.WithSpanAttr("cron.spec", job.Spec),
+		)
+		defer span.End()
+
 		if !job.EnableDistLock {
-			job.Func()
+			c.run(ctx, job, 0)
 			return
 		}
 
 		if c.rdb == nil {
-			xlog.Warn("redis client not available, executing job without distributed lock",
+			xlog.WarnC(ctx, "redis client not available, executing job without distributed lock",
 				xlog.String("job", job.Name))
-			job.Func()
+			c.run(ctx, job, 0)
 			return
 		}
 
@@ -158,42 +351,186 @@ func (c *Cron) executeWithLock(job Job) func() {
 			lockTimeout = 5 * time.Minute
 		}
 
+		// redis 中实际的 key 为 xlock:{xcron:lock:...}
 		lockKey := fmt.Sprintf("xcron:lock:%s:%s", c.name, job.Name)
-		ctx := context.Background()
 
-		// Try to obtain lock with retry
-		var acquired bool
-		var err error
-
-		acquired, err = c.tryLock(ctx, lockKey, lockTimeout)
-		if err != nil {
-			xlog.Warn("error trying to acquire lock",
+		// 锁在任务运行期间自动续期，释放时校验持有者，避免删除其他实例的锁
+		lock, err := xredis.Lock(ctx, lockKey, xredis.WithLockClient(c.rdb), xredis.WithLockTTL(lockTimeout))
+		var legacy *legacyLock
+		if err == nil {
+			// 同时持有旧版本使用的 key，滚动发布时旧实例也能看到任务正在运行
+			var ok bool
+			legacy, ok, err = acquireLegacyLock(ctx, c.rdb, lockKey, lock.Token(), lockTimeout)
+			if err == nil && !ok {
+				err = xredis.ErrLockNotAcquired
+			}
+			if err != nil {
+				_ = lock.Unlock(context.Background())
+			}
+		}
+		if errors.Is(err, xredis.ErrLockNotAcquired) {
+			span.SetAttr("cron.skipped", true)
+			xmetrics.ObserveCronSkip(job.Name)
+			xlog.DebugC(ctx, "failed to acquire distributed lock, job already running",
 				xlog.String("job", job.Name),
-				xlog.String("key", lockKey),
-				xlog.String("error", err.Error()))
+				xlog.String("key", lockKey))
 			return
 		}
-
-		if !acquired {
-			xlog.Debug("failed to acquire distributed lock, job already running",
+		if err != nil {
+			xmetrics.ObserveCronSkip(job.Name)
+			xlog.WarnC(ctx, "error trying to acquire lock",
 				xlog.String("job", job.Name),
-				xlog.String("key", lockKey))
+				xlog.String("key", lockKey),
+				xlog.String("error", err.Error()))
 			return
 		}
 
 		defer func() {
-			if err := c.releaseLock(ctx, lockKey); err != nil {
-				xlog.Warn("failed to release lock",
+			err := errors.Join(legacy.unlock(context.Background()), lock.Unlock(context.Background()))
+			if err != nil {
+				xlog.WarnC(ctx, "failed to release lock",
 					xlog.String("job", job.Name),
 					xlog.String("key", lockKey),
 					xlog.String("error", err.Error()))
 			}
 		}()
 
-		xlog.Debug("obtained distributed lock",
+		xlog.DebugC(ctx, "obtained distributed lock",
 			xlog.String("job", job.Name),
-			xlog.String("key", lockKey))
+			xlog.String("key", lockKey),
+			xlog.Int64("fence", lock.Fence()))
+
+		c.run(ctx, job, lock.Fence())
+	}
+}
+
+// run 执行任务并记录执行历史与指标，panic 被恢复并连同堆栈记录为 RunPanic
+func (c *Cron) run(ctx context.Context, job Job, fence int64) {
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	run := &Run{
+		Cron:      c.name,
+		Job:       job.Name,
+		Instance:  c.instance,
+		Fence:     fence,
+		Status:    RunRunning,
+		StartedAt: time.Now(),
+	}
+	if err := c.store.Start(context.WithoutCancel(ctx), run); err != nil {
+		xlog.WarnC(ctx, "failed to save cron run", xlog.String("job", job.Name), xlog.String("error", err.Error()))
+	}
 
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			run.Status = RunPanic
+			run.Error = err.Error()
+			run.Stack = string(debug.Stack())
+			xlog.ErrorC(ctx, "cron job panic",
+				xlog.String("job", job.Name),
+				xlog.Any("error", r),
+				xlog.String("stack", run.Stack))
+		}
+		run.EndedAt = time.Now()
+		run.Duration = run.EndedAt.Sub(run.StartedAt)
+		xtrace.SpanFromContext(ctx).SetError(err)
+		xmetrics.ObserveCronJob(job.Name, run.Duration, err)
+		if err := c.store.Finish(context.WithoutCancel(ctx), run); err != nil {
+			xlog.WarnC(ctx, "failed to save cron run", xlog.String("job", job.Name), xlog.String("error", err.Error()))
+		}
+	}()
+
+	if job.Run != nil {
+		err = job.Run(ctx)
+	} else {
 		job.Func()
 	}
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+		xlog.ErrorC(ctx, "cron job failed", xlog.String("job", job.Name), xlog.String("error", err.Error()))
+		return
+	}
+	run.Status = RunSuccess
+}
+
+// JobStatus 任务的调度状态与最近一次执行记录
+type JobStatus struct {
+	Name     string    `json:"name"`
+	Spec     string    `json:"spec"`
+	DistLock bool      `json:"dist_lock"`
+	Overlap  string    `json:"overlap"`
+	Paused   bool      `json:"paused"`
+	Next     time.Time `json:"next"`
+	Prev     time.Time `json:"prev"`
+	LastRun  *Run      `json:"last_run,omitempty"`
+}
+
+// Jobs 返回所有任务的状态，Start 之前 Next 与 Prev 为零值
+func (c *Cron) Jobs() []JobStatus {
+	out := make([]JobStatus, 0, len(c.jobs))
+	for _, job := range c.jobs {
+		status := JobStatus{Name: job.Name, Spec: job.Spec, DistLock: job.EnableDistLock, Overlap: job.Overlap.String()}
+		if entry, paused, ok := c.entry(job.Name); ok {
+			status.Next = entry.Next
+			status.Prev = entry.Prev
+			status.Paused = paused
+		}
+		runs, err := c.store.Runs(context.Background(), c.name, job.Name, 1)
+		if err != nil {
+			xlog.Warn("failed to load cron runs", xlog.String("job", job.Name), xlog.String("error", err.Error()))
+		}
+		if len(runs) > 0 {
+			status.LastRun = &runs[0]
+		}
+		out = append(out, status)
+	}
+	return out
+}
+
+// Runs 按开始时间倒序返回任务最近 limit 条执行记录，limit 不大于 0 时为 20
+func (c *Cron) Runs(job string, limit int) ([]Run, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	return c.store.Runs(context.Background(), c.name, job, limit)
+}
+
+// NextRun 返回任务的下次执行时间，任务不存在或未启动时返回 false
+func (c *Cron) NextRun(job string) (time.Time, bool) {
+	entry, _, ok := c.entry(job)
+	if !ok {
+		return time.Time{}, false
+	}
+	return entry.Next, !entry.Next.IsZero()
+}
+
+// HasJob 判断是否存在名为 name 的任务
+func (c *Cron) HasJob(name string) bool {
+	for _, job := range c.jobs {
+		if job.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Cron) entry(name string) (cron.Entry, bool, bool) {
+	c.mu.RLock()
+	e, ok := c.entries[name]
+	var paused bool
+	if ok {
+		paused = e.paused
+	}
+	c.mu.RUnlock()
+	if !ok {
+		return cron.Entry{}, false, false
+	}
+	entry := c.cron.Entry(e.id)
+	return entry, paused, entry.Valid()
 }
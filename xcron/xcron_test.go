@@ -0,0 +1,219 @@
+package xcron
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJobRunTimeout(t *testing.T) {
+	c := New2(WithJobs(Job{
+		Name:    "slow",
+		Spec:    "@every 1h",
+		Timeout: 20 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}))
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	if err := c.Trigger("slow"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		runs, _ := c.Runs("slow", 1)
+		return len(runs) == 1 && runs[0].Status == RunFailed
+	})
+	runs, _ := c.Runs("slow", 1)
+	if runs[0].Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("unexpected error %q", runs[0].Error)
+	}
+	if err := c.Trigger("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+// testRedis 连接本地 redis，不可用时跳过
+func testRedis(t *testing.T) redis.UniversalClient {
+	t.Helper()
+	c := redis.NewClient(&redis.Options{Addr: "localhost:6379", DialTimeout: 200 * time.Millisecond})
+	if err := c.Ping(context.Background()).Err(); err != nil {
+		_ = c.Close()
+		t.Skipf("redis not available: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestDistLockExcludesLegacyHolder(t *testing.T) {
+	rdb := testRedis(t)
+	ctx := context.Background()
+	legacyKey := "xcron:lock:legacy_test:job"
+	rdb.Del(ctx, legacyKey)
+	defer rdb.Del(ctx, legacyKey)
+
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c := New2(WithName("legacy_test"), WithRdb(rdb), WithJobs(Job{
+		Name:           "job",
+		Spec:           "@every 1h",
+		EnableDistLock: true,
+		Run: func(ctx context.Context) error {
+			if calls.Add(1) == 1 {
+				close(started)
+				<-release
+			}
+			return nil
+		},
+	}))
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	// 旧版本实例以 SET NX 持有锁时跳过
+	rdb.Set(ctx, legacyKey, "locked", time.Minute)
+	_ = c.Trigger("job")
+	time.Sleep(100 * time.Millisecond)
+	if calls.Load() != 0 {
+		t.Fatal("expected job to be skipped while legacy key is held")
+	}
+
+	// 运行期间旧 key 被占用，旧实例的 SET NX 会失败
+	rdb.Del(ctx, legacyKey)
+	_ = c.Trigger("job")
+	<-started
+	if ok, _ := rdb.SetNX(ctx, legacyKey, "locked", time.Minute).Result(); ok {
+		t.Fatal("expected legacy key to be held while job runs")
+	}
+	close(release)
+	waitFor(t, func() bool { return rdb.Exists(ctx, legacyKey).Val() == 0 })
+}
+
+func TestOverlapSkip(t *testing.T) {
+	var running, calls atomic.Int32
+	release := make(chan struct{})
+	c := New2(WithJobs(Job{
+		Name:    "skip",
+		Spec:    "@every 1h",
+		Overlap: OverlapSkip,
+		Run: func(ctx context.Context) error {
+			calls.Add(1)
+			running.Add(1)
+			defer running.Add(-1)
+			<-release
+			return nil
+		},
+	}))
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	_ = c.Trigger("skip")
+	waitFor(t, func() bool { return running.Load() == 1 })
+	_ = c.Trigger("skip")
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	waitFor(t, func() bool { return running.Load() == 0 })
+	if calls.Load() != 1 {
+		t.Fatalf("expected overlapping run to be skipped, got %d calls", calls.Load())
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	var calls atomic.Int32
+	c := New2(WithJobs(Job{Name: "p", Spec: "@every 1h", Func: func() { calls.Add(1) }}))
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	if err := c.Pause("p"); err != nil {
+		t.Fatal(err)
+	}
+	c.scheduled("p")()
+	if calls.Load() != 0 || !c.Jobs()[0].Paused {
+		t.Fatal("paused job should not run")
+	}
+	if err := c.Resume("p"); err != nil {
+		t.Fatal(err)
+	}
+	c.scheduled("p")()
+	if calls.Load() != 1 {
+		t.Fatal("resumed job should run")
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	c.RegisterRoutes(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/cron/jobs/p/pause", nil))
+	if !strings.Contains(w.Body.String(), `"code":0`) || !c.Jobs()[0].Paused {
+		t.Fatalf("unexpected pause response %s", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/cron/jobs/missing/trigger", nil))
+	if !strings.Contains(w.Body.String(), `"code":404`) {
+		t.Fatalf("expected not found, got %s", w.Body.String())
+	}
+}
+
+func TestStopCancelsRunningJobs(t *testing.T) {
+	canceled := make(chan struct{})
+	c := New2(WithStopTimeout(50*time.Millisecond), WithJobs(Job{
+		Name: "long",
+		Spec: "@every 1h",
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			close(canceled)
+			return ctx.Err()
+		},
+	}))
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Trigger("long")
+	waitFor(t, func() bool {
+		runs, _ := c.Runs("long", 1)
+		return len(runs) == 1
+	})
+
+	start := time.Now()
+	c.Stop()
+	if time.Since(start) > time.Second {
+		t.Fatal("stop should return after the timeout")
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("job ctx not canceled")
+	}
+	if err := c.Trigger("long"); !errors.Is(err, ErrStopped) {
+		t.Fatalf("expected ErrStopped, got %v", err)
+	}
+}
@@ -1,12 +1,16 @@
 package xdb
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/daodao97/xgo/xmetrics"
 )
 
 type Config struct {
@@ -26,6 +30,7 @@ type DbPool struct {
 	db      *sql.DB
 	conf    *Config
 	dialect Dialect
+	read    bool
 }
 
 func Inits(conns []Config) error {
@@ -47,6 +52,7 @@ func Init(conns map[string]*Config) error {
 			return err
 		}
 		pool.Store(conn, db)
+		xmetrics.RegisterDB(conn, db.db)
 		if conf.ReadDsn != "" {
 			rdb, err := NewDb(&Config{
 				DSN:             conf.ReadDsn,
@@ -59,7 +65,9 @@ func Init(conns map[string]*Config) error {
 			if err != nil {
 				return err
 			}
+			rdb.read = true
 			pool.Store(readConn(conn), rdb)
+			xmetrics.RegisterDB(readConn(conn), rdb.db)
 		}
 	}
 	return nil
@@ -67,10 +75,41 @@ func Init(conns map[string]*Config) error {
 
 func Close() {
 	pool.Range(func(key, value any) bool {
-		db := value.(*sql.DB)
-		_ = db.Close()
+		if db, ok := value.(*DbPool); ok {
+			_ = db.db.Close()
+		}
+		pool.Delete(key)
+		xmetrics.UnregisterDB(key.(string))
+		return true
+	})
+}
+
+// Ping 检查连接是否可用，配置了读库时一并检查
+func Ping(ctx context.Context, conn string) error {
+	_db, err := db(conn)
+	if err != nil {
+		return err
+	}
+	if err := _db.db.PingContext(ctx); err != nil {
+		return err
+	}
+	if rdb, err := db(readConn(conn)); err == nil {
+		return rdb.db.PingContext(ctx)
+	}
+	return nil
+}
+
+// Conns 返回已初始化的连接名，不包含读库
+func Conns() []string {
+	var conns []string
+	pool.Range(func(key, value any) bool {
+		if db, ok := value.(*DbPool); ok && !db.read {
+			conns = append(conns, key.(string))
+		}
 		return true
 	})
+	sort.Strings(conns)
+	return conns
 }
 
 func readConn(conn string) string {
@@ -92,6 +131,15 @@ func DB(conn string) (*sql.DB, error) {
 	return _db.db, nil
 }
 
+// ConnDialect 返回连接对应的数据库方言
+func ConnDialect(conn string) (Dialect, error) {
+	_db, err := db(conn)
+	if err != nil {
+		return nil, err
+	}
+	return _db.dialect, nil
+}
+
 func NewDb(conf *Config) (*DbPool, error) {
 	driver := conf.Driver
 	if driver == "" {
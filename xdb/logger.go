@@ -9,6 +9,8 @@ import (
 	"github.com/spf13/cast"
 
 	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xmetrics"
+	"github.com/daodao97/xgo/xtrace"
 )
 
 func Info(msg string, kv ...any) {
@@ -27,7 +29,7 @@ func Error(msg string, kv ...any) {
 	xlog.Error(msg, kv...)
 }
 
-func dbLog(ctx context.Context, prefix string, start time.Time, err *error, kv *[]any) {
+func dbLog(ctx context.Context, conn, prefix string, start time.Time, err *error, kv *[]any) {
 	tc := time.Since(start).Milliseconds()
 
 	_log := []any{
@@ -69,6 +71,20 @@ func dbLog(ctx context.Context, prefix string, start time.Time, err *error, kv *
 		_log = removeLogFields(_log, "sql", "args")
 	}
 
+	xmetrics.ObserveDBQuery(conn, prefix, time.Since(start), *err)
+
+	// 请求链路中已有 span 时补记一个子 span
+	_, span := xtrace.StartChild(ctx, "xdb."+prefix,
+		xtrace.WithSpanKind(xtrace.SpanKindClient),
+		xtrace.WithStartTime(start),
+		xtrace.WithSpanAttr("db.operation", prefix),
+	)
+	if hasSQL {
+		span.SetAttr("db.statement", sqlStmt)
+	}
+	span.SetError(*err)
+	span.End()
+
 	if *err != nil {
 		_log = append(_log, xlog.Any("error", *err))
 		xlog.ErrorC(ctx, "query", _log...)
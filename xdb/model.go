@@ -181,7 +181,7 @@ func (m *model) PrimaryKey() string {
 func (m *model) Select(opt ...Option) (rows *Rows) {
 	var kv []any
 	var err error
-	defer dbLog(m.ctx, "Select", time.Now(), &err, &kv)
+	defer dbLog(m.ctx, m.connection, "Select", time.Now(), &err, &kv)
 
 	if m.err != nil {
 		err = m.err
@@ -381,7 +381,7 @@ func (m *model) Insert(record Record) (lastId int64, err error) {
 	}
 
 	var kv []any
-	defer dbLog(m.ctx, "Insert", time.Now(), &err, &kv)
+	defer dbLog(m.ctx, m.connection, "Insert", time.Now(), &err, &kv)
 
 	_record := record
 	if len(_record) == 0 {
@@ -452,7 +452,7 @@ func (m *model) InsertBatch(records []Record) (lastId int64, err error) {
 	}
 
 	var kv []any
-	defer dbLog(m.ctx, "InsertBatch", time.Now(), &err, &kv)
+	defer dbLog(m.ctx, m.connection, "InsertBatch", time.Now(), &err, &kv)
 
 	if len(records) == 0 {
 		return 0, errors.New("没有记录可插入")
@@ -545,7 +545,7 @@ func (m *model) Update(record Record, opt ...Option) (ok bool, err error) {
 	}
 
 	var kv []any
-	defer dbLog(m.ctx, "Update", time.Now(), &err, &kv)
+	defer dbLog(m.ctx, m.connection, "Update", time.Now(), &err, &kv)
 
 	_record := record
 	if len(_record) == 0 {
@@ -612,7 +612,7 @@ func (m *model) InsertOrUpdate(record Record, updateFields ...string) (affected
 	}
 
 	var kv []any
-	defer dbLog(m.ctx, "InsertOrUpdate", time.Now(), &err, &kv)
+	defer dbLog(m.ctx, m.connection, "InsertOrUpdate", time.Now(), &err, &kv)
 
 	if len(record) == 0 {
 		return 0, errors.New("空记录无法插入或更新")
@@ -711,7 +711,7 @@ func (m *model) InsertIgnore(record Record) (affected int64, err error) {
 	}
 
 	var kv []any
-	defer dbLog(m.ctx, "InsertIgnore", time.Now(), &err, &kv)
+	defer dbLog(m.ctx, m.connection, "InsertIgnore", time.Now(), &err, &kv)
 
 	if len(record) == 0 {
 		return 0, errors.New("空记录无法插入")
@@ -779,7 +779,7 @@ func (m *model) Delete(opt ...Option) (ok bool, err error) {
 	}
 
 	var kv []any
-	defer dbLog(m.ctx, "Delete", time.Now(), &err, &kv)
+	defer dbLog(m.ctx, m.connection, "Delete", time.Now(), &err, &kv)
 
 	_sql, args := DeleteBuilder(opt...)
 
@@ -876,7 +876,7 @@ func (m *model) DelCache(opt ...Option) {
 }
 
 func (m *model) Exec(query string, args ...any) (res sql.Result, err error) {
-	defer dbLog(m.ctx, "Exec", time.Now(), &err, &args)
+	defer dbLog(m.ctx, m.connection, "Exec", time.Now(), &err, &args)
 	if m.tx != nil {
 		return execTx(m.tx, query, args...)
 	}
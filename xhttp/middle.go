@@ -45,6 +45,8 @@ var (
 )
 
 // PrometheusMiddleware implements mux.MiddlewareFunc.
+//
+// Deprecated: use xmetrics.Middleware, which adds method and status labels.
 func PrometheusMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		route := mux.CurrentRoute(r)
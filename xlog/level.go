@@ -0,0 +1,230 @@
+package xlog
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type levelEntry struct {
+	v slog.LevelVar
+	// set 为 false 时使用上级名称或全局 logger 的级别
+	set       bool
+	expiresAt time.Time
+	revert    *time.Timer
+}
+
+var (
+	levelsMu sync.RWMutex
+	levels   = map[string]*levelEntry{}
+)
+
+func getLevelEntry(name string) *levelEntry {
+	e, ok := levels[name]
+	if !ok {
+		e = &levelEntry{}
+		levels[name] = e
+	}
+	return e
+}
+
+// LevelVar 返回 name 对应的 LevelVar，可传给 WithLevel 使 logger 的级别也能在运行时调整
+//
+//	xlog.SetLogger(xlog.StdoutJson(xlog.WithLevel(xlog.LevelVar("root"))))
+func LevelVar(name string) *slog.LevelVar {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	e := getLevelEntry(name)
+	e.set = true
+	return &e.v
+}
+
+// SetLevel 设置 name 的级别，ttl 大于 0 时到期后恢复为之前的设置
+func SetLevel(name string, level slog.Level, ttl time.Duration) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	e := getLevelEntry(name)
+	if e.revert != nil {
+		e.revert.Stop()
+		e.revert = nil
+	}
+	prevLevel, prevSet := e.v.Level(), e.set
+	e.v.Set(level)
+	e.set = true
+	e.expiresAt = time.Time{}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+		var timer *time.Timer
+		timer = time.AfterFunc(ttl, func() {
+			levelsMu.Lock()
+			defer levelsMu.Unlock()
+			// 期间被再次设置时由新的定时器负责
+			if e.revert != timer {
+				return
+			}
+			e.v.Set(prevLevel)
+			e.set = prevSet
+			e.expiresAt = time.Time{}
+			e.revert = nil
+		})
+		e.revert = timer
+	}
+}
+
+// ResetLevel 取消 name 的级别设置，恢复继承上级名称或全局 logger 的级别
+func ResetLevel(name string) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	if e, ok := levels[name]; ok {
+		if e.revert != nil {
+			e.revert.Stop()
+			e.revert = nil
+		}
+		e.set = false
+		e.expiresAt = time.Time{}
+	}
+}
+
+type LevelInfo struct {
+	Name      string     `json:"name"`
+	Level     string     `json:"level,omitempty"` // 为空表示继承
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Levels 返回所有已注册名称的级别
+func Levels() []LevelInfo {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	infos := make([]LevelInfo, 0, len(levels))
+	for name, e := range levels {
+		info := LevelInfo{Name: name}
+		if e.set {
+			info.Level = e.v.Level().String()
+		}
+		if !e.expiresAt.IsZero() {
+			expiresAt := e.expiresAt
+			info.ExpiresAt = &expiresAt
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// lookupLevel 按 a.b.c、a.b、a 的顺序查找已设置的级别
+func lookupLevel(name string) (slog.Level, bool) {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	for {
+		if e, ok := levels[name]; ok && e.set {
+			return e.v.Level(), true
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+// Named 返回带 logger=name 属性的子 logger，级别可通过 SetLevel(name) 或 LevelHandler 在运行时调整，
+// 未设置时继承上级名称（以 "." 分隔）或全局 logger 的级别。日志写入当前的全局 logger
+func Named(name string) *slog.Logger {
+	levelsMu.Lock()
+	getLevelEntry(name)
+	levelsMu.Unlock()
+
+	return slog.New(&namedHandler{
+		name: name,
+		ops: []func(slog.Handler) slog.Handler{
+			func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String("logger", name)})
+			},
+		},
+	})
+}
+
+// namedHandler 每次使用时基于当前的全局 logger 构建，SetLogger 后依然生效
+type namedHandler struct {
+	name string
+	ops  []func(slog.Handler) slog.Handler
+
+	mu      sync.Mutex
+	base    slog.Handler
+	derived slog.Handler
+}
+
+func (h *namedHandler) handler() slog.Handler {
+	base := GetLogger().Handler()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.derived == nil || h.base != base {
+		derived := base
+		for _, op := range h.ops {
+			derived = op(derived)
+		}
+		h.base, h.derived = base, derived
+	}
+	return h.derived
+}
+
+func (h *namedHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if min, ok := lookupLevel(h.name); ok {
+		return level >= min
+	}
+	return h.handler().Enabled(ctx, level)
+}
+
+// Handle 直接调用底层 handler，不再经过其级别判断，使调低的级别可以生效。
+// 设置了级别时通过 ctx 传递，Multi、Sink 等组合 handler 据此跳过子 handler 的级别判断
+func (h *namedHandler) Handle(ctx context.Context, r slog.Record) error {
+	if _, ok := lookupLevel(h.name); ok {
+		ctx = context.WithValue(ctx, namedLevelKey{}, true)
+	}
+	return h.handler().Handle(ctx, r)
+}
+
+type namedLevelKey struct{}
+
+// SinkLeveler 由有独立最低级别的 sink 实现，Named 设置的级别不会绕过该级别
+type SinkLeveler interface {
+	MinLevel() slog.Level
+}
+
+// handlerEnabled 在 Named 设置了级别时只检查 sink 的最低级别，跳过 handler 自身的级别
+func handlerEnabled(ctx context.Context, h slog.Handler, level slog.Level) bool {
+	if named, _ := ctx.Value(namedLevelKey{}).(bool); !named {
+		return h.Enabled(ctx, level)
+	}
+	switch h := h.(type) {
+	case *MultiHandler, *SinkHandler, *ThrottleHandler:
+		return h.Enabled(ctx, level)
+	case SinkLeveler:
+		return level >= h.MinLevel()
+	}
+	return true
+}
+
+func (h *namedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler {
+		return next.WithAttrs(attrs)
+	})
+}
+
+func (h *namedHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler {
+		return next.WithGroup(name)
+	})
+}
+
+func (h *namedHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, 0, len(h.ops)+1)
+	ops = append(ops, h.ops...)
+	ops = append(ops, op)
+	return &namedHandler{name: h.name, ops: ops}
+}
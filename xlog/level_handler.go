@@ -0,0 +1,79 @@
+package xlog
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type levelRequest struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+	TTL   string `json:"ttl"` // 如 10m，为空表示不自动恢复
+}
+
+// LevelHandler 提供运行时查看与调整日志级别的接口，可挂载到 xhttp 或通过 gin.WrapH 挂载到 gin：
+//
+//	GET    列出所有名称及级别
+//	PUT    {"name":"xdb","level":"debug","ttl":"10m"} 设置级别，也支持同名 query 参数
+//	DELETE ?name=xdb 恢复继承
+//
+// name 不能为空，根级别不通过该接口修改，
+// 该接口可以修改线上日志输出，需要放在鉴权之后
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var req levelRequest
+			if r.ContentLength > 0 {
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					writeLevelError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+					return
+				}
+			} else {
+				q := r.URL.Query()
+				req = levelRequest{Name: q.Get("name"), Level: q.Get("level"), TTL: q.Get("ttl")}
+			}
+			if req.Name == "" {
+				writeLevelError(w, http.StatusBadRequest, "name is required")
+				return
+			}
+			var level slog.Level
+			if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+				writeLevelError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			var ttl time.Duration
+			if req.TTL != "" {
+				var err error
+				if ttl, err = time.ParseDuration(req.TTL); err != nil {
+					writeLevelError(w, http.StatusBadRequest, "invalid ttl: "+err.Error())
+					return
+				}
+			}
+			SetLevel(req.Name, level, ttl)
+			Info("log level changed", String("name", req.Name), String("level", level.String()), Duration("ttl", ttl))
+		case http.MethodDelete:
+			name := r.URL.Query().Get("name")
+			if name == "" {
+				writeLevelError(w, http.StatusBadRequest, "name is required")
+				return
+			}
+			ResetLevel(name)
+			Info("log level reset", String("name", name))
+		default:
+			writeLevelError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Levels())
+	})
+}
+
+func writeLevelError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
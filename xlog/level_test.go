@@ -0,0 +1,134 @@
+package xlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNamedLevel(t *testing.T) {
+	var buf bytes.Buffer
+	original := GetLogger()
+	SetLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	defer SetLogger(original)
+	defer ResetLevel("test.db")
+
+	l := Named("test.db.query").With("table", "users")
+	l.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("debug should inherit info level, got %s", buf.String())
+	}
+
+	// 设置上级名称的级别对子名称生效
+	SetLevel("test.db", slog.LevelDebug, 0)
+	l.Debug("visible")
+	if !strings.Contains(buf.String(), `"logger":"test.db.query"`) || !strings.Contains(buf.String(), `"table":"users"`) {
+		t.Fatalf("unexpected output %s", buf.String())
+	}
+
+	// SetLogger 之后写入新的 logger
+	var next bytes.Buffer
+	SetLogger(slog.New(slog.NewJSONHandler(&next, nil)))
+	l.Debug("after set logger")
+	if !strings.Contains(next.String(), "after set logger") {
+		t.Fatalf("named logger did not follow SetLogger: %s", next.String())
+	}
+}
+
+func TestNamedLevelOverMulti(t *testing.T) {
+	var console, alerts bytes.Buffer
+	original := GetLogger()
+	SetLogger(Multi(
+		Sink(slog.NewJSONHandler(&console, &slog.HandlerOptions{Level: slog.LevelInfo}), SinkLevel(slog.LevelDebug)),
+		Sink(slog.NewJSONHandler(&alerts, nil), SinkLevel(slog.LevelWarn)),
+	))
+	defer SetLogger(original)
+	defer ResetLevel("test.multi")
+
+	l := Named("test.multi")
+	l.Debug("hidden")
+	if console.Len() != 0 {
+		t.Fatalf("debug should inherit info level, got %s", console.String())
+	}
+
+	SetLevel("test.multi", slog.LevelDebug, 0)
+	l.Debug("visible")
+	if !strings.Contains(console.String(), "visible") {
+		t.Fatalf("named level ignored by multi handler: %q", console.String())
+	}
+	// sink 自身的最低级别仍然生效
+	if alerts.Len() != 0 {
+		t.Fatalf("sink level bypassed: %s", alerts.String())
+	}
+}
+
+func TestSetLevelTTL(t *testing.T) {
+	defer ResetLevel("test.ttl")
+	SetLevel("test.ttl", slog.LevelWarn, 0)
+	SetLevel("test.ttl", slog.LevelDebug, 30*time.Millisecond)
+	if lv, _ := lookupLevel("test.ttl"); lv != slog.LevelDebug {
+		t.Fatalf("expected debug, got %s", lv)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if lv, ok := lookupLevel("test.ttl"); !ok || lv != slog.LevelWarn {
+		t.Fatalf("expected revert to warn, got %s %v", lv, ok)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	defer ResetLevel("test.http")
+	h := LevelHandler()
+
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"name":"test.http","level":"debug","ttl":"1m"}`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d %s", w.Code, w.Body.String())
+	}
+	var infos []LevelInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &infos); err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, info := range infos {
+		if info.Name == "test.http" {
+			found = info.Level == "DEBUG" && info.ExpiresAt != nil
+		}
+	}
+	if !found {
+		t.Fatalf("level not set: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/?name=test.http&level=loud", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"debug"}`)),
+		httptest.NewRequest(http.MethodPut, "/?level=debug", nil),
+		httptest.NewRequest(http.MethodDelete, "/", nil),
+	} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for empty name on %s %s, got %d", req.Method, req.URL, w.Code)
+		}
+	}
+	if _, ok := lookupLevel(""); ok {
+		t.Fatal("empty name should not set a level")
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/?name=test.http", nil))
+	if _, ok := lookupLevel("test.http"); ok {
+		t.Fatal("expected level reset")
+	}
+}
@@ -22,12 +22,17 @@ func init() {
 		return nil
 	})
 
-	// 默认 extractor：traceid（xtrace 包内部维护 ctx key）
+	// 默认 extractor：traceid 及当前 spanid（xtrace 包内部维护 ctx key）
 	RegisterCtxExtractor(func(ctx context.Context) []slog.Attr {
-		if tid := xtrace.FromTraceId(ctx); tid != "" {
-			return []slog.Attr{slog.String("traceid", tid)}
+		tid := xtrace.FromTraceId(ctx)
+		if tid == "" {
+			return nil
 		}
-		return nil
+		attrs := []slog.Attr{slog.String("traceid", tid)}
+		if span := xtrace.SpanFromContext(ctx); span != nil {
+			attrs = append(attrs, slog.String("spanid", span.SpanContext().SpanID.String()))
+		}
+		return attrs
 	})
 }
 
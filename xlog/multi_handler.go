@@ -0,0 +1,120 @@
+package xlog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Multi 将日志同时写入多个 handler，每个 handler 可用 Sink 设置最低级别与过滤条件
+//
+//	xlog.SetLogger(xlog.Multi(
+//		xlog.Sink(xlog.StdoutTextPretty().Handler(), xlog.SinkLevel(slog.LevelDebug)),
+//		xlog.Sink(xlog.FileJson("app.log").Handler(), xlog.SinkLevel(slog.LevelInfo)),
+//		xnotify.LogSink("lark://bot_id"),
+//	))
+func Multi(handlers ...slog.Handler) *slog.Logger {
+	return slog.New(NewMultiHandler(handlers...))
+}
+
+type MultiHandler struct {
+	handlers []slog.Handler
+}
+
+func NewMultiHandler(handlers ...slog.Handler) *MultiHandler {
+	return &MultiHandler{handlers: handlers}
+}
+
+func (h *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handlerEnabled(ctx, handler, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *MultiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if !handlerEnabled(ctx, handler, r.Level) {
+			continue
+		}
+		// Record 内部共享 attr 切片，每个 handler 使用独立副本
+		if err := handler.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &MultiHandler{handlers: handlers}
+}
+
+func (h *MultiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &MultiHandler{handlers: handlers}
+}
+
+type SinkOption func(*SinkHandler)
+
+// SinkLevel 设置 sink 的最低级别，与 handler 自身的级别同时生效
+func SinkLevel(level slog.Leveler) SinkOption {
+	return func(h *SinkHandler) {
+		h.level = level
+	}
+}
+
+// SinkFilter 返回 false 的日志不会写入该 sink
+func SinkFilter(filter func(ctx context.Context, r slog.Record) bool) SinkOption {
+	return func(h *SinkHandler) {
+		h.filters = append(h.filters, filter)
+	}
+}
+
+// Sink 为 handler 增加最低级别与过滤条件
+func Sink(handler slog.Handler, opts ...SinkOption) slog.Handler {
+	h := &SinkHandler{next: handler}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+type SinkHandler struct {
+	next    slog.Handler
+	level   slog.Leveler
+	filters []func(ctx context.Context, r slog.Record) bool
+}
+
+func (h *SinkHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.level != nil && level < h.level.Level() {
+		return false
+	}
+	return handlerEnabled(ctx, h.next, level)
+}
+
+func (h *SinkHandler) Handle(ctx context.Context, r slog.Record) error {
+	for _, filter := range h.filters {
+		if !filter(ctx, r) {
+			return nil
+		}
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *SinkHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SinkHandler{next: h.next.WithAttrs(attrs), level: h.level, filters: h.filters}
+}
+
+func (h *SinkHandler) WithGroup(name string) slog.Handler {
+	return &SinkHandler{next: h.next.WithGroup(name), level: h.level, filters: h.filters}
+}
@@ -0,0 +1,34 @@
+package xlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestMulti(t *testing.T) {
+	var debugBuf, infoBuf, auditBuf bytes.Buffer
+	l := Multi(
+		Sink(slog.NewJSONHandler(&debugBuf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		Sink(slog.NewJSONHandler(&infoBuf, &slog.HandlerOptions{Level: slog.LevelDebug}), SinkLevel(slog.LevelInfo)),
+		Sink(slog.NewJSONHandler(&auditBuf, nil), SinkFilter(func(ctx context.Context, r slog.Record) bool {
+			return strings.HasPrefix(r.Message, "audit")
+		})),
+	).With("service", "api")
+
+	l.Debug("debug msg")
+	l.Info("info msg")
+	l.Info("audit login")
+
+	if n := strings.Count(debugBuf.String(), "\n"); n != 3 {
+		t.Fatalf("debug sink expected 3 records, got %d", n)
+	}
+	if strings.Contains(infoBuf.String(), "debug msg") || !strings.Contains(infoBuf.String(), "info msg") {
+		t.Fatalf("unexpected info sink output %s", infoBuf.String())
+	}
+	if strings.Contains(auditBuf.String(), "info msg") || !strings.Contains(auditBuf.String(), `"service":"api"`) {
+		t.Fatalf("unexpected audit sink output %s", auditBuf.String())
+	}
+}
@@ -0,0 +1,236 @@
+package xlog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ThrottleOption func(*throttleState)
+
+// WithSampling 对指定级别采样：每个 interval 内前 first 条全部输出，之后每 thereafter 条输出一条。
+// thereafter 为 0 时丢弃超出部分
+func WithSampling(level slog.Level, interval time.Duration, first, thereafter int) ThrottleOption {
+	return func(s *throttleState) {
+		s.sampling[level] = &sampleCounter{interval: interval, first: first, thereafter: thereafter}
+	}
+}
+
+// WithDedup 在 window 内按 message 与 keys 对应的 attr 值去重，重复的日志只输出第一条，
+// 窗口结束时输出一条带 repeated 次数的汇总
+func WithDedup(window time.Duration, keys ...string) ThrottleOption {
+	return func(s *throttleState) {
+		s.dedupWindow = window
+		s.dedupKeys = keys
+	}
+}
+
+// WithByteBudget 限制每秒输出的日志字节数（按 message 与 attr 估算），超出部分丢弃，
+// 下一秒输出被丢弃的条数
+func WithByteBudget(bytesPerSecond int) ThrottleOption {
+	return func(s *throttleState) {
+		s.budget = bytesPerSecond
+	}
+}
+
+// Throttle 为 logger 增加采样、去重与字节预算，可包装 StdoutJson、FileJson、StdoutTextPretty 等
+func Throttle(l *slog.Logger, opts ...ThrottleOption) *slog.Logger {
+	return slog.New(NewThrottleHandler(l.Handler(), opts...))
+}
+
+// NewThrottleHandler 包装 next，依次执行采样、去重、字节预算
+func NewThrottleHandler(next slog.Handler, opts ...ThrottleOption) slog.Handler {
+	s := &throttleState{
+		sampling: map[slog.Level]*sampleCounter{},
+		seen:     map[string]*dedupEntry{},
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return &ThrottleHandler{next: next, state: s}
+}
+
+type ThrottleHandler struct {
+	next  slog.Handler
+	state *throttleState
+	// attrs 记录 WithAttrs 添加的属性，参与去重 key 的计算
+	attrs []slog.Attr
+}
+
+type throttleState struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	sampling map[slog.Level]*sampleCounter
+
+	dedupWindow time.Duration
+	dedupKeys   []string
+	seen        map[string]*dedupEntry
+
+	budget      int
+	budgetStart time.Time
+	budgetUsed  int
+	dropped     int
+}
+
+type sampleCounter struct {
+	interval   time.Duration
+	first      int
+	thereafter int
+	start      time.Time
+	count      int
+}
+
+type dedupEntry struct {
+	count int
+}
+
+func (h *ThrottleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return handlerEnabled(ctx, h.next, level)
+}
+
+func (h *ThrottleHandler) Handle(ctx context.Context, r slog.Record) error {
+	s := h.state
+	s.mu.Lock()
+	if !s.sample(r.Level) {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.dedupWindow > 0 {
+		key := h.dedupKey(r)
+		if entry, ok := s.seen[key]; ok {
+			entry.count++
+			s.mu.Unlock()
+			return nil
+		}
+		s.seen[key] = &dedupEntry{}
+		summary := r.Clone()
+		time.AfterFunc(s.dedupWindow, func() {
+			h.flushDedup(key, summary)
+		})
+	}
+	dropped, ok := s.consumeBudget(recordSize(r))
+	s.mu.Unlock()
+
+	if dropped > 0 {
+		h.emitDropped(ctx, dropped)
+	}
+	if !ok {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *ThrottleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ThrottleHandler{
+		next:  h.next.WithAttrs(attrs),
+		state: h.state,
+		attrs: append(append([]slog.Attr{}, h.attrs...), attrs...),
+	}
+}
+
+func (h *ThrottleHandler) WithGroup(name string) slog.Handler {
+	return &ThrottleHandler{next: h.next.WithGroup(name), state: h.state, attrs: h.attrs}
+}
+
+// sample 判断该级别的日志是否通过采样，调用方持有锁
+func (s *throttleState) sample(level slog.Level) bool {
+	c, ok := s.sampling[level]
+	if !ok {
+		return true
+	}
+	now := s.now()
+	if now.Sub(c.start) >= c.interval {
+		c.start = now
+		c.count = 0
+	}
+	c.count++
+	if c.count <= c.first {
+		return true
+	}
+	return c.thereafter > 0 && (c.count-c.first)%c.thereafter == 0
+}
+
+// consumeBudget 返回上一秒被丢弃的条数以及本条是否可以输出，调用方持有锁
+func (s *throttleState) consumeBudget(size int) (int, bool) {
+	if s.budget <= 0 {
+		return 0, true
+	}
+	var dropped int
+	now := s.now()
+	if now.Sub(s.budgetStart) >= time.Second {
+		dropped = s.dropped
+		s.budgetStart = now
+		s.budgetUsed = 0
+		s.dropped = 0
+	}
+	if s.budgetUsed+size > s.budget {
+		s.dropped++
+		return dropped, false
+	}
+	s.budgetUsed += size
+	return dropped, true
+}
+
+func (h *ThrottleHandler) dedupKey(r slog.Record) string {
+	var b strings.Builder
+	b.WriteString(r.Level.String())
+	b.WriteByte('|')
+	b.WriteString(r.Message)
+	for _, key := range h.state.dedupKeys {
+		b.WriteByte('|')
+		b.WriteString(key)
+		b.WriteByte('=')
+		for _, a := range h.attrs {
+			if a.Key == key {
+				b.WriteString(a.Value.String())
+			}
+		}
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == key {
+				b.WriteString(a.Value.String())
+			}
+			return true
+		})
+	}
+	return b.String()
+}
+
+func (h *ThrottleHandler) flushDedup(key string, r slog.Record) {
+	s := h.state
+	s.mu.Lock()
+	entry := s.seen[key]
+	delete(s.seen, key)
+	s.mu.Unlock()
+
+	if entry == nil || entry.count == 0 {
+		return
+	}
+	summary := slog.NewRecord(s.now(), r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		summary.AddAttrs(a)
+		return true
+	})
+	summary.AddAttrs(slog.Int("repeated", entry.count), slog.Duration("window", s.dedupWindow))
+	_ = h.next.Handle(context.Background(), summary)
+}
+
+func (h *ThrottleHandler) emitDropped(ctx context.Context, dropped int) {
+	r := slog.NewRecord(h.state.now(), slog.LevelWarn, fmt.Sprintf("xlog dropped %d records over byte budget", dropped), 0)
+	r.AddAttrs(slog.Int("dropped", dropped), slog.Int("budget", h.state.budget))
+	_ = h.next.Handle(ctx, r)
+}
+
+// recordSize 估算日志序列化后的字节数
+func recordSize(r slog.Record) int {
+	size := len(r.Message) + 48 // 时间、级别等固定开销
+	r.Attrs(func(a slog.Attr) bool {
+		size += len(a.Key) + len(a.Value.String()) + 4
+		return true
+	})
+	return size
+}
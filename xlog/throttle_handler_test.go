@@ -0,0 +1,127 @@
+package xlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer 供定时器 goroutine 并发写入
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newThrottleLogger(buf *bytes.Buffer, now *time.Time, opts ...ThrottleOption) *slog.Logger {
+	h := NewThrottleHandler(slog.NewJSONHandler(buf, nil), opts...).(*ThrottleHandler)
+	h.state.now = func() time.Time { return *now }
+	return slog.New(h)
+}
+
+func countLines(buf *bytes.Buffer, msg string) int {
+	return strings.Count(buf.String(), `"msg":"`+msg+`"`)
+}
+
+func TestThrottleSampling(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Now()
+	l := newThrottleLogger(&buf, &now, WithSampling(slog.LevelError, time.Second, 3, 5))
+
+	for i := 0; i < 23; i++ {
+		l.Error("boom")
+	}
+	// 前 3 条，之后第 5、10、15、20 条
+	if n := countLines(&buf, "boom"); n != 7 {
+		t.Fatalf("expected 7 sampled records, got %d", n)
+	}
+
+	// 其他级别不受影响
+	for i := 0; i < 10; i++ {
+		l.Info("info")
+	}
+	if n := countLines(&buf, "info"); n != 10 {
+		t.Fatalf("expected 10 info records, got %d", n)
+	}
+
+	now = now.Add(time.Second)
+	buf.Reset()
+	l.Error("boom")
+	if n := countLines(&buf, "boom"); n != 1 {
+		t.Fatalf("expected counter reset after interval, got %d", n)
+	}
+}
+
+func TestThrottleDedup(t *testing.T) {
+	var buf syncBuffer
+	l := slog.New(NewThrottleHandler(slog.NewJSONHandler(&buf, nil), WithDedup(50*time.Millisecond, "table")))
+
+	for i := 0; i < 10; i++ {
+		l.Error("query failed", "table", "users", "attempt", i)
+	}
+	l.Error("query failed", "table", "orders")
+
+	if n := strings.Count(buf.String(), `"msg":"query failed"`); n != 2 {
+		t.Fatalf("expected 2 records before summary, got %d: %s", n, buf.String())
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected one summary record, got %v", lines)
+	}
+	var summary map[string]any
+	if err := json.Unmarshal([]byte(lines[2]), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary["repeated"] != float64(9) || summary["table"] != "users" {
+		t.Fatalf("unexpected summary %v", summary)
+	}
+}
+
+func TestThrottleByteBudget(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Now()
+	l := newThrottleLogger(&buf, &now, WithByteBudget(200))
+
+	for i := 0; i < 20; i++ {
+		l.Info("payload", "data", strings.Repeat("x", 20))
+	}
+	kept := countLines(&buf, "payload")
+	if kept == 0 || kept >= 20 {
+		t.Fatalf("expected budget to drop some records, kept %d", kept)
+	}
+
+	now = now.Add(time.Second)
+	buf.Reset()
+	l.Info("next")
+	if !strings.Contains(buf.String(), "over byte budget") || countLines(&buf, "next") != 1 {
+		t.Fatalf("expected dropped summary, got %s", buf.String())
+	}
+}
+
+func TestThrottleComposesWithPretty(t *testing.T) {
+	var buf bytes.Buffer
+	pretty := slog.New(NewPrettyHandler(&buf, PrettyHandlerOptions{}))
+	l := Throttle(pretty, WithSampling(slog.LevelInfo, time.Minute, 1, 0))
+	l.Info("hello")
+	l.Info("hello")
+	if n := strings.Count(buf.String(), "hello"); n != 1 {
+		t.Fatalf("expected 1 record, got %d", n)
+	}
+}
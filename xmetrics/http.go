@@ -0,0 +1,101 @@
+package xmetrics
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/mux"
+)
+
+// UnmatchedRoute 未匹配到路由的请求使用的 route 标签，避免按实际路径产生过多序列
+const UnmatchedRoute = "unmatched"
+
+// Gin 记录请求数、耗时与并发数，route 标签为 gin 的路由模板，如 /users/:id
+func Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m := get()
+		m.httpInFlight.Inc()
+		defer m.httpInFlight.Dec()
+
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = UnmatchedRoute
+		}
+		ObserveHTTP(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// Middleware 用于 net/http 与 gorilla/mux（xhttp），route 标签取 mux 的路由模板，
+// 不在 mux 中使用时请用 WrapHandler 指定 route
+func Middleware(next http.Handler) http.Handler {
+	return instrument(func(r *http.Request) string {
+		if route := mux.CurrentRoute(r); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				return tpl
+			}
+		}
+		return UnmatchedRoute
+	}, next)
+}
+
+// WrapHandler 以固定的 route 标签记录 h 的请求
+//
+//	http.Handle("/users/", xmetrics.WrapHandler("/users/", usersHandler))
+func WrapHandler(route string, h http.Handler) http.Handler {
+	return instrument(func(*http.Request) string { return route }, h)
+}
+
+func instrument(route func(*http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := get()
+		m.httpInFlight.Inc()
+		defer m.httpInFlight.Dec()
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		ObserveHTTP(r.Method, route(r), sw.status, time.Since(start))
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,252 @@
+package xmetrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultNamespace 默认的指标前缀
+const DefaultNamespace = "xgo"
+
+type config struct {
+	namespace string
+	registry  *prometheus.Registry
+	buckets   []float64
+}
+
+type Option func(*config)
+
+// WithNamespace 设置指标前缀，默认 xgo
+func WithNamespace(ns string) Option {
+	return func(c *config) {
+		c.namespace = ns
+	}
+}
+
+// WithRegistry 使用独立的 Registry，默认注册到 prometheus.DefaultRegisterer
+func WithRegistry(reg *prometheus.Registry) Option {
+	return func(c *config) {
+		c.registry = reg
+	}
+}
+
+// WithBuckets 设置耗时直方图的分桶，单位秒，默认 prometheus.DefBuckets
+func WithBuckets(buckets ...float64) Option {
+	return func(c *config) {
+		c.buckets = buckets
+	}
+}
+
+type metrics struct {
+	registerer prometheus.Registerer
+	collectors []prometheus.Collector
+	handler    http.Handler
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	httpInFlight prometheus.Gauge
+
+	dbQueries *prometheus.HistogramVec
+
+	redisCommands *prometheus.HistogramVec
+
+	queueMessages  *prometheus.HistogramVec
+	queuePublished *prometheus.CounterVec
+	queueJobs      *prometheus.HistogramVec
+
+	cronRuns *prometheus.HistogramVec
+
+	limiterRequests *prometheus.CounterVec
+}
+
+var (
+	current     atomic.Pointer[metrics]
+	setupMu     sync.Mutex
+	defaultOnce sync.Once
+)
+
+// Setup 创建并注册所有指标，应在使用前调用；未调用时首次使用按默认配置创建。
+// 重复调用会注销之前的指标
+func Setup(opts ...Option) error {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	m := newMetrics(cfg)
+
+	setupMu.Lock()
+	defer setupMu.Unlock()
+	if old := current.Load(); old != nil {
+		old.unregister()
+	}
+	for _, c := range m.collectors {
+		if err := m.registerer.Register(c); err != nil {
+			m.unregister()
+			return err
+		}
+	}
+	current.Store(m)
+	return nil
+}
+
+func get() *metrics {
+	if m := current.Load(); m != nil {
+		return m
+	}
+	defaultOnce.Do(func() {
+		if err := Setup(); err != nil {
+			// 注册失败时依然可以记录，只是不会输出
+			current.CompareAndSwap(nil, newMetrics(defaultConfig()))
+		}
+	})
+	return current.Load()
+}
+
+func defaultConfig() *config {
+	return &config{namespace: DefaultNamespace, buckets: prometheus.DefBuckets}
+}
+
+func newMetrics(cfg *config) *metrics {
+	ns := cfg.namespace
+	m := &metrics{
+		registerer: prometheus.DefaultRegisterer,
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Subsystem: "http", Name: "requests_total",
+			Help: "Total number of HTTP requests.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Subsystem: "http", Name: "request_duration_seconds",
+			Help: "Duration of HTTP requests.", Buckets: cfg.buckets,
+		}, []string{"method", "route", "status"}),
+		httpInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: ns, Subsystem: "http", Name: "requests_in_flight",
+			Help: "Number of HTTP requests being served.",
+		}),
+		dbQueries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Subsystem: "db", Name: "query_duration_seconds",
+			Help: "Duration of xdb statements.", Buckets: cfg.buckets,
+		}, []string{"conn", "operation", "status"}),
+		redisCommands: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Subsystem: "redis", Name: "command_duration_seconds",
+			Help: "Duration of xredis commands.", Buckets: cfg.buckets,
+		}, []string{"client", "command", "status"}),
+		queueMessages: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Subsystem: "queue", Name: "handle_duration_seconds",
+			Help: "Duration of xqueue handlers.", Buckets: cfg.buckets,
+		}, []string{"topic", "status"}),
+		queuePublished: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Subsystem: "queue", Name: "published_total",
+			Help: "Total number of published xqueue messages.",
+		}, []string{"topic", "status"}),
+		queueJobs: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Subsystem: "queue", Name: "job_duration_seconds",
+			Help: "Duration of typed xqueue jobs.", Buckets: cfg.buckets,
+		}, []string{"topic", "status"}),
+		cronRuns: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Subsystem: "cron", Name: "job_duration_seconds",
+			Help: "Duration of xcron job runs.", Buckets: cfg.buckets,
+		}, []string{"job", "status"}),
+		limiterRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Subsystem: "limiter", Name: "requests_total",
+			Help: "Total number of limiter decisions.",
+		}, []string{"limiter", "result"}),
+	}
+	var gatherer prometheus.Gatherer = prometheus.DefaultGatherer
+	if cfg.registry != nil {
+		m.registerer, gatherer = cfg.registry, cfg.registry
+	}
+	m.handler = promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+	m.collectors = []prometheus.Collector{
+		m.httpRequests, m.httpDuration, m.httpInFlight,
+		m.dbQueries, newDBStatsCollector(ns),
+		m.redisCommands, newRedisStatsCollector(ns),
+		m.queueMessages, m.queuePublished, m.queueJobs,
+		m.cronRuns,
+		m.limiterRequests,
+	}
+	return m
+}
+
+func (m *metrics) unregister() {
+	for _, c := range m.collectors {
+		m.registerer.Unregister(c)
+	}
+}
+
+// Handler 输出 Prometheus 格式的指标，Setup 更换 Registry 后依然生效
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		get().handler.ServeHTTP(w, r)
+	})
+}
+
+// RegisterRoutes 在 gin 路由上挂载 GET /metrics
+func RegisterRoutes(r gin.IRoutes) {
+	r.GET("/metrics", gin.WrapH(Handler()))
+}
+
+func status(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// ObserveHTTP 记录一次 HTTP 请求，route 应为路由模板而非实际路径
+func ObserveHTTP(method, route string, code int, d time.Duration) {
+	m := get()
+	status := strconv.Itoa(code)
+	m.httpRequests.WithLabelValues(method, route, status).Inc()
+	m.httpDuration.WithLabelValues(method, route, status).Observe(d.Seconds())
+}
+
+// ObserveDBQuery 记录 conn 连接上一次 xdb 语句的耗时
+func ObserveDBQuery(conn, operation string, d time.Duration, err error) {
+	get().dbQueries.WithLabelValues(conn, operation, status(err)).Observe(d.Seconds())
+}
+
+// ObserveRedis 记录 client 上一次 redis 命令的耗时，pipeline 的 command 为 pipeline
+func ObserveRedis(client, command string, d time.Duration, err error) {
+	get().redisCommands.WithLabelValues(client, command, status(err)).Observe(d.Seconds())
+}
+
+// ObserveQueue 记录一次队列消息的处理耗时，err 不为 nil 时计为失败
+func ObserveQueue(topic string, d time.Duration, err error) {
+	get().queueMessages.WithLabelValues(topic, status(err)).Observe(d.Seconds())
+}
+
+// ObservePublish 记录一次消息发布
+func ObservePublish(topic string, err error) {
+	get().queuePublished.WithLabelValues(topic, status(err)).Inc()
+}
+
+// ObserveJob 记录一次 xqueue.Register 注册的任务的处理耗时
+func ObserveJob(topic string, d time.Duration, err error) {
+	get().queueJobs.WithLabelValues(topic, status(err)).Observe(d.Seconds())
+}
+
+// ObserveCronJob 记录一次定时任务执行，err 不为 nil 时计为失败
+func ObserveCronJob(job string, d time.Duration, err error) {
+	get().cronRuns.WithLabelValues(job, status(err)).Observe(d.Seconds())
+}
+
+// ObserveCronSkip 记录一次因未获得锁等原因跳过的定时任务
+func ObserveCronSkip(job string) {
+	get().cronRuns.WithLabelValues(job, "skipped").Observe(0)
+}
+
+// ObserveLimiter 记录一次限流判断，allowed 为 false 时计为拒绝
+func ObserveLimiter(limiter string, allowed bool) {
+	result := "allowed"
+	if !allowed {
+		result = "rejected"
+	}
+	get().limiterRequests.WithLabelValues(limiter, result).Inc()
+}
@@ -0,0 +1,134 @@
+package xmetrics
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("not supported")
+}
+
+func init() {
+	sql.Register("xmetrics-fake", fakeDriver{})
+}
+
+func setupTest(t *testing.T) *prometheus.Registry {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+	if err := Setup(WithNamespace("test"), WithRegistry(reg)); err != nil {
+		t.Fatal(err)
+	}
+	return reg
+}
+
+func scrape(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	return string(body)
+}
+
+func TestGin(t *testing.T) {
+	setupTest(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Gin())
+	RegisterRoutes(r)
+	r.GET("/users/:id", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	m := get()
+	if n := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/users/:id", "201")); n != 2 {
+		t.Fatalf("expected 2 requests, got %v", n)
+	}
+	if n := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", UnmatchedRoute, "404")); n != 1 {
+		t.Fatalf("expected 1 unmatched request, got %v", n)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(w.Body.String(), `test_http_request_duration_seconds_count{method="GET",route="/users/:id",status="201"} 2`) {
+		t.Fatalf("unexpected metrics output:\n%s", w.Body.String())
+	}
+}
+
+func TestWrapHandler(t *testing.T) {
+	setupTest(t)
+	h := WrapHandler("/files/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.WriteHeader(http.StatusOK)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/files/a.txt", nil))
+
+	if n := testutil.ToFloat64(get().httpRequests.WithLabelValues("POST", "/files/", "418")); n != 1 {
+		t.Fatalf("expected first status to be recorded, got %v", n)
+	}
+}
+
+func TestSubsystems(t *testing.T) {
+	setupTest(t)
+	db, err := sql.Open("xmetrics-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(7)
+	RegisterDB("main", db)
+	defer UnregisterDB("main")
+
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	defer rdb.Close()
+	RegisterRedis("cache", rdb)
+	defer UnregisterRedis("cache")
+
+	ObserveDBQuery("main", "Select", time.Millisecond, nil)
+	ObserveDBQuery("main", "Insert", time.Millisecond, errors.New("duplicate"))
+	ObserveRedis("cache", "get", time.Millisecond, nil)
+	ObserveQueue("orders", time.Millisecond, errors.New("panic"))
+	ObservePublish("orders", nil)
+	ObserveCronJob("report", time.Second, nil)
+	ObserveCronSkip("report")
+	ObserveLimiter("sliding_window", false)
+
+	out := scrape(t)
+	for _, want := range []string{
+		`test_db_pool_max_open_connections{db="main"} 7`,
+		`test_db_query_duration_seconds_count{conn="main",operation="Insert",status="error"} 1`,
+		`test_redis_command_duration_seconds_count{client="cache",command="get",status="ok"} 1`,
+		`test_redis_pool_total_connections{client="cache"} 0`,
+		`test_queue_handle_duration_seconds_count{status="error",topic="orders"} 1`,
+		`test_queue_published_total{status="ok",topic="orders"} 1`,
+		`test_cron_job_duration_seconds_count{job="report",status="skipped"} 1`,
+		`test_limiter_requests_total{limiter="sliding_window",result="rejected"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %s in:\n%s", want, out)
+		}
+	}
+
+	// 重新 Setup 后旧的指标被注销，新的 Registry 从零开始
+	setupTest(t)
+	if strings.Contains(scrape(t), "test_limiter_requests_total{") {
+		t.Fatal("expected fresh registry after Setup")
+	}
+}
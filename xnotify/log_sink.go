@@ -0,0 +1,256 @@
+package xnotify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type LogSinkOption func(*logSinkState)
+
+// WithSinkLevel 设置转发的最低级别，默认 Error
+func WithSinkLevel(level slog.Level) LogSinkOption {
+	return func(s *logSinkState) {
+		s.level = level
+	}
+}
+
+// WithSinkInterval 同一条 message 在 interval 内只通知一次，默认 5 分钟。
+// 期间被抑制的次数会附在下一次通知中，超过 interval 未再出现的 message 会被清理，
+// 清理时单独发送一条抑制次数的汇总
+func WithSinkInterval(interval time.Duration) LogSinkOption {
+	return func(s *logSinkState) {
+		s.interval = interval
+	}
+}
+
+// WithSinkMaxPerMinute 限制每分钟的通知总数，默认 20
+func WithSinkMaxPerMinute(n int) LogSinkOption {
+	return func(s *logSinkState) {
+		s.maxPerMinute = n
+	}
+}
+
+// WithSinkBuffer 设置异步队列长度，队列满时丢弃，默认 100
+func WithSinkBuffer(size int) LogSinkOption {
+	return func(s *logSinkState) {
+		s.buffer = size
+	}
+}
+
+// WithSinkNotifyOptions 设置发送时的 NotifyOption，如 WithMentions
+func WithSinkNotifyOptions(opts ...NotifyOption) LogSinkOption {
+	return func(s *logSinkState) {
+		s.notifyOpts = append(s.notifyOpts, opts...)
+	}
+}
+
+var sinkNotify = NotifyWithOptions
+
+var (
+	sinksMu sync.Mutex
+	sinks   []*logSinkState
+)
+
+// LogSink 返回异步转发日志到 botID 的 slog.Handler，配合 xlog.Multi 使用，
+// 用于在出现新的错误时通知值班人员。退出前调用 FlushLogSinks 发送队列中的通知
+func LogSink(botID string, opts ...LogSinkOption) slog.Handler {
+	s := &logSinkState{
+		botID:        botID,
+		level:        slog.LevelError,
+		interval:     5 * time.Minute,
+		maxPerMinute: 20,
+		buffer:       100,
+		last:         map[string]*sinkEntry{},
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.queue = make(chan string, s.buffer)
+	sinksMu.Lock()
+	sinks = append(sinks, s)
+	sinksMu.Unlock()
+	return &logSinkHandler{state: s}
+}
+
+// FlushLogSinks 等待所有 LogSink 队列中的通知发送完成，可直接作为 xapp 的关闭钩子
+func FlushLogSinks(ctx context.Context) error {
+	sinksMu.Lock()
+	list := append([]*logSinkState(nil), sinks...)
+	sinksMu.Unlock()
+	for _, s := range list {
+		if err := s.flush(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type logSinkState struct {
+	botID        string
+	level        slog.Level
+	interval     time.Duration
+	maxPerMinute int
+	buffer       int
+	notifyOpts   []NotifyOption
+
+	once    sync.Once
+	queue   chan string
+	pending atomic.Int64 // 已入队但未发送完成的通知数
+
+	mu          sync.Mutex
+	now         func() time.Time
+	last        map[string]*sinkEntry
+	lastSweep   time.Time
+	windowStart time.Time
+	windowSent  int
+}
+
+type sinkEntry struct {
+	sentAt     time.Time
+	suppressed int
+}
+
+type logSinkHandler struct {
+	state  *logSinkState
+	attrs  []slog.Attr
+	prefix string
+}
+
+func (h *logSinkHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.state.level
+}
+
+// MinLevel 实现 xlog.SinkLeveler，Named 调低的级别不会使通知发送更多日志
+func (h *logSinkHandler) MinLevel() slog.Level {
+	return h.state.level
+}
+
+func (h *logSinkHandler) Handle(ctx context.Context, r slog.Record) error {
+	s := h.state
+	suppressed, ok := s.allow(r.Level.String() + "|" + r.Message)
+	if !ok {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s\n", r.Level, r.Message)
+	for _, a := range h.attrs {
+		fmt.Fprintf(&b, "%s=%v\n", a.Key, a.Value)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		fmt.Fprintf(&b, "%s%s=%v\n", h.prefix, a.Key, a.Value)
+		return true
+	})
+	if suppressed > 0 {
+		fmt.Fprintf(&b, "(suppressed %d times)\n", suppressed)
+	}
+	b.WriteString(r.Time.Format(time.DateTime))
+	s.enqueue(b.String())
+	return nil
+}
+
+func (h *logSinkHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := &logSinkHandler{state: h.state, prefix: h.prefix}
+	next.attrs = append(next.attrs, h.attrs...)
+	for _, a := range attrs {
+		next.attrs = append(next.attrs, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
+	}
+	return next
+}
+
+func (h *logSinkHandler) WithGroup(name string) slog.Handler {
+	return &logSinkHandler{state: h.state, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+// allow 判断是否发送，返回上次发送后被抑制的次数
+func (s *logSinkState) allow(key string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+
+	entry, ok := s.last[key]
+	if ok && now.Sub(entry.sentAt) < s.interval {
+		entry.suppressed++
+		return 0, false
+	}
+
+	if now.Sub(s.windowStart) >= time.Minute {
+		s.windowStart = now
+		s.windowSent = 0
+	}
+	if s.maxPerMinute > 0 && s.windowSent >= s.maxPerMinute {
+		if ok {
+			entry.suppressed++
+		}
+		return 0, false
+	}
+	s.windowSent++
+
+	var suppressed int
+	if ok {
+		suppressed = entry.suppressed
+	}
+	s.last[key] = &sinkEntry{sentAt: now}
+	s.sweep(now)
+	return suppressed, true
+}
+
+// sweep 每个 interval 清理一次过期的 message，被抑制过的发送汇总，避免 last 无限增长
+func (s *logSinkState) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.interval {
+		return
+	}
+	s.lastSweep = now
+	for key, entry := range s.last {
+		if now.Sub(entry.sentAt) < s.interval {
+			continue
+		}
+		delete(s.last, key)
+		if entry.suppressed > 0 {
+			level, msg, _ := strings.Cut(key, "|")
+			s.enqueue(fmt.Sprintf("[%s] %s\n(suppressed %d times)\n%s", level, msg, entry.suppressed, now.Format(time.DateTime)))
+		}
+	}
+}
+
+func (s *logSinkState) enqueue(msg string) {
+	s.once.Do(func() {
+		go s.run()
+	})
+	s.pending.Add(1)
+	select {
+	case s.queue <- msg:
+	default:
+		// 队列已满，丢弃
+		s.pending.Add(-1)
+	}
+}
+
+func (s *logSinkState) run() {
+	for msg := range s.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		// 发送失败不能再写错误日志，否则会再次进入 sink
+		_ = sinkNotify(ctx, s.botID, msg, s.notifyOpts...)
+		cancel()
+		s.pending.Add(-1)
+	}
+}
+
+func (s *logSinkState) flush(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for s.pending.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
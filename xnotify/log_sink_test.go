@@ -0,0 +1,120 @@
+package xnotify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLogSink(t *testing.T) {
+	sent := make(chan string, 10)
+	original := sinkNotify
+	sinkNotify = func(ctx context.Context, botID, message string, opts ...NotifyOption) error {
+		sent <- botID + " " + message
+		return nil
+	}
+	defer func() { sinkNotify = original }()
+
+	h := LogSink("lark://bot", WithSinkInterval(time.Hour))
+	l := slog.New(h).With("service", "api")
+
+	l.Info("ignored")
+	l.Error("db down", "err", "timeout")
+	l.Error("db down", "err", "timeout")
+	l.Error("cache down")
+
+	var msgs []string
+	timeout := time.After(time.Second)
+	for len(msgs) < 2 {
+		select {
+		case msg := <-sent:
+			msgs = append(msgs, msg)
+		case <-timeout:
+			t.Fatalf("expected 2 notifications, got %v", msgs)
+		}
+	}
+	select {
+	case msg := <-sent:
+		t.Fatalf("unexpected notification %s", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if !strings.HasPrefix(msgs[0], "lark://bot [ERROR] db down") || !strings.Contains(msgs[0], "service=api") || !strings.Contains(msgs[0], "err=timeout") {
+		t.Fatalf("unexpected message %q", msgs[0])
+	}
+	if !strings.Contains(msgs[1], "cache down") {
+		t.Fatalf("unexpected message %q", msgs[1])
+	}
+}
+
+func TestLogSinkRateLimit(t *testing.T) {
+	now := time.Now()
+	s := LogSink("bark://key", WithSinkInterval(time.Minute), WithSinkMaxPerMinute(2)).(*logSinkHandler).state
+	s.now = func() time.Time { return now }
+
+	if _, ok := s.allow("a"); !ok {
+		t.Fatal("first a should be sent")
+	}
+	if _, ok := s.allow("a"); ok {
+		t.Fatal("repeated a should be suppressed")
+	}
+	if _, ok := s.allow("b"); !ok {
+		t.Fatal("first b should be sent")
+	}
+	if _, ok := s.allow("c"); ok {
+		t.Fatal("c should exceed max per minute")
+	}
+
+	now = now.Add(time.Minute)
+	suppressed, ok := s.allow("a")
+	if !ok || suppressed != 1 {
+		t.Fatalf("expected a to be sent with 1 suppressed, got %d %v", suppressed, ok)
+	}
+}
+
+func TestLogSinkEvictsAndFlushes(t *testing.T) {
+	var sent []string
+	var mu sync.Mutex
+	original := sinkNotify
+	sinkNotify = func(ctx context.Context, botID, message string, opts ...NotifyOption) error {
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		sent = append(sent, message)
+		mu.Unlock()
+		return nil
+	}
+	defer func() { sinkNotify = original }()
+
+	now := time.Now()
+	s := LogSink("bark://key", WithSinkInterval(time.Minute), WithSinkMaxPerMinute(0)).(*logSinkHandler).state
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < 100; i++ {
+		s.allow(fmt.Sprintf("ERROR|error %d", i))
+	}
+	s.allow("ERROR|error 0")
+
+	now = now.Add(2 * time.Minute)
+	s.allow("ERROR|new")
+	s.mu.Lock()
+	size := len(s.last)
+	s.mu.Unlock()
+	if size != 1 {
+		t.Fatalf("expected expired entries to be evicted, %d left", size)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := FlushLogSinks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 || !strings.Contains(sent[0], "error 0\n(suppressed 1 times)") {
+		t.Fatalf("expected suppressed summary, got %q", sent)
+	}
+}
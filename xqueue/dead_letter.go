@@ -0,0 +1,204 @@
+package xqueue
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 死信条目中的附加字段
+const (
+	errorField    = "error"
+	originField   = "origin_id"
+	failedAtField = "failed_at"
+)
+
+// DeadLetter 超过最大尝试次数的消息
+type DeadLetter struct {
+	ID       string    `json:"id"`
+	Topic    string    `json:"topic"`
+	Data     string    `json:"data"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	History  []Attempt `json:"history"`
+	OriginID string    `json:"origin_id"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// DeadLetterQueue 由支持死信的 Queue 实现
+type DeadLetterQueue interface {
+	// DeadLetters 按时间顺序返回 id 大于 after 的最多 count 条死信，after 为空时从头开始
+	DeadLetters(ctx context.Context, after string, count int64) ([]DeadLetter, error)
+	// DeadLetter 返回指定的死信，不存在时返回 ErrDeadLetterNotFound
+	DeadLetter(ctx context.Context, id string) (*DeadLetter, error)
+	// ReplayDeadLetters 把死信重新发布到原队列并重置尝试次数，ids 为空时重放全部
+	ReplayDeadLetters(ctx context.Context, ids ...string) (int, error)
+	// PurgeDeadLetters 删除死信，ids 为空时删除全部
+	PurgeDeadLetters(ctx context.Context, ids ...string) (int, error)
+}
+
+var ErrDeadLetterNotFound = errors.New("xqueue: dead letter not found")
+
+func deadLetterQueue(topic string) (DeadLetterQueue, error) {
+	q := GetQueue(topic)
+	if q == nil {
+		return nil, errors.New("queue not found: " + topic)
+	}
+	dq, ok := q.(DeadLetterQueue)
+	if !ok {
+		return nil, errors.New("queue does not support dead letters: " + topic)
+	}
+	return dq, nil
+}
+
+// ListDeadLetters 列出 topic 的死信
+func ListDeadLetters(ctx context.Context, topic, after string, count int64) ([]DeadLetter, error) {
+	dq, err := deadLetterQueue(topic)
+	if err != nil {
+		return nil, err
+	}
+	return dq.DeadLetters(ctx, after, count)
+}
+
+// InspectDeadLetter 查看 topic 的单条死信
+func InspectDeadLetter(ctx context.Context, topic, id string) (*DeadLetter, error) {
+	dq, err := deadLetterQueue(topic)
+	if err != nil {
+		return nil, err
+	}
+	return dq.DeadLetter(ctx, id)
+}
+
+// ReplayDeadLetters 重放 topic 的死信，ids 为空时重放全部
+func ReplayDeadLetters(ctx context.Context, topic string, ids ...string) (int, error) {
+	dq, err := deadLetterQueue(topic)
+	if err != nil {
+		return 0, err
+	}
+	return dq.ReplayDeadLetters(ctx, ids...)
+}
+
+// PurgeDeadLetters 删除 topic 的死信，ids 为空时删除全部
+func PurgeDeadLetters(ctx context.Context, topic string, ids ...string) (int, error) {
+	dq, err := deadLetterQueue(topic)
+	if err != nil {
+		return 0, err
+	}
+	return dq.PurgeDeadLetters(ctx, ids...)
+}
+
+func (q *StreamQueue) deadKey() string { return q.stream + ":dead" }
+
+func (q *StreamQueue) toDeadLetter(msg redis.XMessage) DeadLetter {
+	d := DeadLetter{
+		ID:       msg.ID,
+		Topic:    q.topic,
+		Attempts: attemptOf(msg),
+		History:  historyOf(msg),
+	}
+	d.Data, _ = msg.Values[streamField].(string)
+	d.Error, _ = msg.Values[errorField].(string)
+	d.OriginID, _ = msg.Values[originField].(string)
+	if s, ok := msg.Values[failedAtField].(string); ok {
+		if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+			d.FailedAt = time.UnixMilli(ms)
+		}
+	}
+	return d
+}
+
+func (q *StreamQueue) DeadLetters(ctx context.Context, after string, count int64) ([]DeadLetter, error) {
+	if err := q.init(); err != nil {
+		return nil, err
+	}
+	start := "-"
+	if after != "" {
+		start = "(" + after
+	}
+	if count <= 0 {
+		count = 100
+	}
+	msgs, err := q.redis.XRangeN(ctx, q.deadKey(), start, "+", count).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]DeadLetter, 0, len(msgs))
+	for _, msg := range msgs {
+		out = append(out, q.toDeadLetter(msg))
+	}
+	return out, nil
+}
+
+func (q *StreamQueue) DeadLetter(ctx context.Context, id string) (*DeadLetter, error) {
+	if err := q.init(); err != nil {
+		return nil, err
+	}
+	msgs, err := q.redis.XRangeN(ctx, q.deadKey(), id, id, 1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, ErrDeadLetterNotFound
+	}
+	d := q.toDeadLetter(msgs[0])
+	return &d, nil
+}
+
+func (q *StreamQueue) ReplayDeadLetters(ctx context.Context, ids ...string) (int, error) {
+	if err := q.init(); err != nil {
+		return 0, err
+	}
+	var msgs []redis.XMessage
+	if len(ids) == 0 {
+		all, err := q.redis.XRange(ctx, q.deadKey(), "-", "+").Result()
+		if err != nil {
+			return 0, err
+		}
+		msgs = all
+	} else {
+		for _, id := range ids {
+			found, err := q.redis.XRangeN(ctx, q.deadKey(), id, id, 1).Result()
+			if err != nil {
+				return 0, err
+			}
+			msgs = append(msgs, found...)
+		}
+	}
+
+	replayed := 0
+	for _, msg := range msgs {
+		// 保留失败历史便于排查，尝试次数从 1 重新计算
+		values := []any{streamField, msg.Values[streamField]}
+		if h, ok := msg.Values[historyField]; ok {
+			values = append(values, historyField, h)
+		}
+		_, err := q.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.XAdd(ctx, q.xaddArgs(q.stream, values))
+			pipe.XDel(ctx, q.deadKey(), msg.ID)
+			return nil
+		})
+		if err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+func (q *StreamQueue) PurgeDeadLetters(ctx context.Context, ids ...string) (int, error) {
+	if err := q.init(); err != nil {
+		return 0, err
+	}
+	if len(ids) > 0 {
+		n, err := q.redis.XDel(ctx, q.deadKey(), ids...).Result()
+		return int(n), err
+	}
+	n, err := q.redis.XLen(ctx, q.deadKey()).Result()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), q.redis.Del(ctx, q.deadKey()).Err()
+}
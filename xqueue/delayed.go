@@ -0,0 +1,115 @@
+package xqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/daodao97/xgo/xmetrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// DelayedQueue 由支持延迟消息的 Queue 实现
+type DelayedQueue interface {
+	// PublishAt 在 at 时刻投递消息，返回可用于 Cancel 的 id
+	PublishAt(data string, at time.Time) (string, error)
+	// PublishDelayed 在 delay 之后投递消息
+	PublishDelayed(data string, delay time.Duration) (string, error)
+	// Cancel 取消尚未投递的消息，已投递或不存在时返回 false
+	Cancel(id string) (bool, error)
+}
+
+func delayedQueue(topic string) (DelayedQueue, error) {
+	q := GetQueue(topic)
+	if q == nil {
+		return nil, errors.New("queue not found: " + topic)
+	}
+	dq, ok := q.(DelayedQueue)
+	if !ok {
+		return nil, errors.New("queue does not support delayed messages: " + topic)
+	}
+	return dq, nil
+}
+
+// PublishAt 向 topic 发布在 at 时刻投递的消息
+//
+//	id, err := xqueue.PublishAt("reminder", data, tomorrow9am)
+func PublishAt(topic, data string, at time.Time) (string, error) {
+	dq, err := delayedQueue(topic)
+	if err != nil {
+		return "", err
+	}
+	return dq.PublishAt(data, at)
+}
+
+// PublishDelayed 向 topic 发布 delay 之后投递的消息，例如订单超时关闭
+func PublishDelayed(topic, data string, delay time.Duration) (string, error) {
+	dq, err := delayedQueue(topic)
+	if err != nil {
+		return "", err
+	}
+	return dq.PublishDelayed(data, delay)
+}
+
+// Cancel 取消 topic 中尚未投递的延迟消息
+func Cancel(topic, id string) (bool, error) {
+	dq, err := delayedQueue(topic)
+	if err != nil {
+		return false, err
+	}
+	return dq.Cancel(id)
+}
+
+func newMessageID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// PublishAt 消息保存在延迟集合中，到期后由订阅中的实例写入 stream，
+// 多个实例同时运行时由 Lua 脚本保证只投递一次
+func (q *StreamQueue) PublishAt(data string, at time.Time) (string, error) {
+	if err := q.init(); err != nil {
+		return "", err
+	}
+	id := newMessageID()
+	payload, err := json.Marshal(map[string]string{streamField: data})
+	if err != nil {
+		return "", err
+	}
+	ctx := context.Background()
+	_, err = q.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, q.delayedDataKey(), id, payload)
+		pipe.ZAdd(ctx, q.delayedKey(), redis.Z{Score: float64(at.UnixMilli()), Member: id})
+		return nil
+	})
+	xmetrics.ObservePublish(q.topic, err)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (q *StreamQueue) PublishDelayed(data string, delay time.Duration) (string, error) {
+	return q.PublishAt(data, time.Now().Add(delay))
+}
+
+func (q *StreamQueue) Cancel(id string) (bool, error) {
+	if err := q.init(); err != nil {
+		return false, err
+	}
+	ctx := context.Background()
+	var removed *redis.IntCmd
+	_, err := q.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		removed = pipe.ZRem(ctx, q.delayedKey(), id)
+		pipe.HDel(ctx, q.delayedDataKey(), id)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return removed.Val() > 0, nil
+}
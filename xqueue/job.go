@@ -0,0 +1,253 @@
+package xqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/daodao97/xgo/xredis"
+	"github.com/daodao97/xgo/xtrace"
+	"github.com/redis/go-redis/v9"
+)
+
+// Envelope 任务消息的元信息，随载荷一起编码
+type Envelope struct {
+	ID          string    `json:"id"`
+	Topic       string    `json:"topic"`
+	TraceID     string    `json:"trace_id,omitempty"`
+	Traceparent string    `json:"traceparent,omitempty"`
+	EnqueuedAt  time.Time `json:"enqueued_at"`
+	// Attempt 当前是第几次处理，由消费端填充
+	Attempt int `json:"-"`
+}
+
+type jobMessage[T any] struct {
+	Envelope
+	Payload T `json:"payload"`
+}
+
+// JobHandler 经过中间件处理的任务函数，载荷已解码
+type JobHandler func(ctx context.Context, env Envelope) error
+
+// Middleware 包装任务处理，可用于日志、指标、恢复 panic 等
+type Middleware func(next JobHandler) JobHandler
+
+// Backend 为 Register 创建底层队列，handler 接收编码后的消息
+type Backend func(topic string, handler func(ctx context.Context, data string) error, workers int) Queue
+
+// StreamBackend 使用 AddStreamQueueE 创建队列，rdb 为 nil 时在首次 Publish/Subscribe 时使用 xredis.Get()，
+// 因此 Register 可以在包级变量中调用
+func StreamBackend(rdb redis.UniversalClient, opts ...StreamOption) Backend {
+	return func(topic string, handler func(ctx context.Context, data string) error, workers int) Queue {
+		return AddStreamQueueE(rdb, topic, handler, workers, opts...)
+	}
+}
+
+type jobOptions struct {
+	codec       xredis.Codec
+	timeout     time.Duration
+	middlewares []Middleware
+	workers     int
+	backend     Backend
+}
+
+type JobOption func(*jobOptions)
+
+// WithJobCodec 设置载荷的编码，默认 xredis.JSONCodec，生产与消费两端需一致
+func WithJobCodec(c xredis.Codec) JobOption {
+	return func(o *jobOptions) {
+		o.codec = c
+	}
+}
+
+// WithJobTimeout 单次处理的超时时间，超时后 ctx 被取消，默认不限制
+func WithJobTimeout(d time.Duration) JobOption {
+	return func(o *jobOptions) {
+		o.timeout = d
+	}
+}
+
+// WithJobMiddleware 添加中间件，按添加顺序由外到内执行
+func WithJobMiddleware(mw ...Middleware) JobOption {
+	return func(o *jobOptions) {
+		o.middlewares = append(o.middlewares, mw...)
+	}
+}
+
+// WithJobWorkers 并发处理的 worker 数量，默认 1
+func WithJobWorkers(n int) JobOption {
+	return func(o *jobOptions) {
+		o.workers = n
+	}
+}
+
+// WithJobBackend 设置底层队列，默认 StreamBackend(nil)
+func WithJobBackend(b Backend) JobOption {
+	return func(o *jobOptions) {
+		o.backend = b
+	}
+}
+
+// Job 通过 Register 注册的类型化任务
+type Job[T any] struct {
+	topic   string
+	opts    *jobOptions
+	handler func(ctx context.Context, payload T) error
+	queue   Queue
+}
+
+// Register 注册 topic 的类型化任务，消息携带 Envelope，消费时恢复上游的 trace，
+// 底层队列注册到 GetQueue 中，需要加入 QueueWorker 才会开始消费。
+// topic 已注册队列时 panic，否则 handler 不会被调用
+//
+//	var sendMail = xqueue.Register("mail", func(ctx context.Context, m Mail) error {
+//		return mailer.Send(ctx, m)
+//	}, xqueue.WithJobTimeout(10*time.Second), xqueue.WithJobMiddleware(xqueue.Recover(), xqueue.Logging()))
+//
+//	sendMail.Publish(ctx, Mail{To: "a@b.c"})
+func Register[T any](topic string, handler func(ctx context.Context, payload T) error, opts ...JobOption) *Job[T] {
+	o := &jobOptions{
+		codec:   xredis.JSONCodec,
+		workers: 1,
+		backend: StreamBackend(nil),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if GetQueue(topic) != nil {
+		panic("xqueue: topic already registered: " + topic)
+	}
+	j := &Job[T]{topic: topic, opts: o, handler: handler}
+	j.queue = o.backend(topic, j.handle, o.workers)
+	return j
+}
+
+func (j *Job[T]) Topic() string { return j.topic }
+
+// Queue 返回底层队列
+func (j *Job[T]) Queue() Queue { return j.queue }
+
+func (j *Job[T]) encode(ctx context.Context, payload T) (string, string, error) {
+	m := jobMessage[T]{
+		Envelope: Envelope{
+			ID:         newMessageID(),
+			Topic:      j.topic,
+			EnqueuedAt: time.Now(),
+		},
+		Payload: payload,
+	}
+	if ctx != nil {
+		m.TraceID = xtrace.FromTraceId(ctx)
+		if sc := xtrace.SpanContextFromContext(ctx); sc.IsValid() {
+			m.Traceparent = sc.Traceparent()
+		}
+	}
+	b, err := j.opts.codec.Marshal(m)
+	if err != nil {
+		return "", "", err
+	}
+	return string(b), m.ID, nil
+}
+
+// Publish 发布任务，返回消息 id
+func (j *Job[T]) Publish(ctx context.Context, payload T) (string, error) {
+	data, id, err := j.encode(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+	return id, j.queue.Publish(data)
+}
+
+func (j *Job[T]) delayed() (DelayedQueue, error) {
+	dq, ok := j.queue.(DelayedQueue)
+	if !ok {
+		return nil, errors.New("queue does not support delayed messages: " + j.topic)
+	}
+	return dq, nil
+}
+
+// Scheduled PublishAt 的结果
+type Scheduled struct {
+	// ID 消息的 Envelope.ID，与 Publish 返回的 id 相同，消费时可通过 EnvelopeFromContext 获取
+	ID string
+	// CancelID 底层队列生成的延迟消息 id，用于 Cancel，SQLBackend 中为任务表的主键
+	CancelID string
+}
+
+// PublishAt 在 at 时刻投递任务
+func (j *Job[T]) PublishAt(ctx context.Context, payload T, at time.Time) (Scheduled, error) {
+	dq, err := j.delayed()
+	if err != nil {
+		return Scheduled{}, err
+	}
+	data, id, err := j.encode(ctx, payload)
+	if err != nil {
+		return Scheduled{}, err
+	}
+	cancelID, err := dq.PublishAt(data, at)
+	if err != nil {
+		return Scheduled{}, err
+	}
+	return Scheduled{ID: id, CancelID: cancelID}, nil
+}
+
+// PublishDelayed 在 delay 之后投递任务
+func (j *Job[T]) PublishDelayed(ctx context.Context, payload T, delay time.Duration) (Scheduled, error) {
+	return j.PublishAt(ctx, payload, time.Now().Add(delay))
+}
+
+// Cancel 按 Scheduled.CancelID 取消尚未投递的延迟任务
+func (j *Job[T]) Cancel(id string) (bool, error) {
+	dq, err := j.delayed()
+	if err != nil {
+		return false, err
+	}
+	return dq.Cancel(id)
+}
+
+func (j *Job[T]) handle(ctx context.Context, data string) error {
+	var m jobMessage[T]
+	if err := j.opts.codec.Unmarshal([]byte(data), &m); err != nil {
+		return fmt.Errorf("xqueue: decode %s message: %w", j.topic, err)
+	}
+	m.Attempt = attemptFromContext(ctx)
+
+	if m.TraceID != "" {
+		ctx = xtrace.SetTraceId(ctx, m.TraceID)
+	}
+	parent, _ := xtrace.ParseTraceparent(m.Traceparent)
+	ctx, span := xtrace.Start(ctx, "xqueue.job."+j.topic,
+		xtrace.WithParent(parent),
+		xtrace.WithSpanKind(xtrace.SpanKindConsumer),
+		xtrace.WithSpanAttr("messaging.destination.name", j.topic),
+		xtrace.WithSpanAttr("messaging.message.id", m.ID),
+		xtrace.WithSpanAttr("xqueue.attempt", m.Attempt),
+	)
+	defer span.End()
+
+	if j.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.opts.timeout)
+		defer cancel()
+	}
+	ctx = context.WithValue(ctx, ctxEnvelopeKey{}, m.Envelope)
+
+	h := JobHandler(func(ctx context.Context, _ Envelope) error {
+		return j.handler(ctx, m.Payload)
+	})
+	for i := len(j.opts.middlewares) - 1; i >= 0; i-- {
+		h = j.opts.middlewares[i](h)
+	}
+	err := h(ctx, m.Envelope)
+	span.SetError(err)
+	return err
+}
+
+type ctxEnvelopeKey struct{}
+
+// EnvelopeFromContext 在任务处理函数中获取当前消息的 Envelope
+func EnvelopeFromContext(ctx context.Context) (Envelope, bool) {
+	env, ok := ctx.Value(ctxEnvelopeKey{}).(Envelope)
+	return env, ok
+}
@@ -0,0 +1,148 @@
+package xqueue
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daodao97/xgo/xredis"
+	"github.com/daodao97/xgo/xtrace"
+	"github.com/redis/go-redis/v9"
+)
+
+// syncQueue 在 Publish 时同步调用 handler，便于测试 Job
+type syncQueue struct {
+	handler func(ctx context.Context, data string) error
+	err     error
+}
+
+func (q *syncQueue) Publish(data string) error {
+	q.err = q.handler(withAttempt(context.Background(), 2), data)
+	return nil
+}
+func (q *syncQueue) Subscribe() error { return nil }
+func (q *syncQueue) Close() error     { return nil }
+
+func syncBackend(q *syncQueue) Backend {
+	return func(_ string, handler func(ctx context.Context, data string) error, _ int) Queue {
+		q.handler = handler
+		return q
+	}
+}
+
+type mail struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+}
+
+func TestRegister(t *testing.T) {
+	for name, codec := range map[string]xredis.Codec{"json": xredis.JSONCodec, "msgpack": xredis.MsgpackCodec} {
+		t.Run(name, func(t *testing.T) {
+			q := &syncQueue{}
+			var got mail
+			var env Envelope
+			var traceID string
+			var order []string
+			mw := func(name string) Middleware {
+				return func(next JobHandler) JobHandler {
+					return func(ctx context.Context, env Envelope) error {
+						order = append(order, name)
+						return next(ctx, env)
+					}
+				}
+			}
+			job := Register("test_mail", func(ctx context.Context, m mail) error {
+				got = m
+				env, _ = EnvelopeFromContext(ctx)
+				traceID = xtrace.SpanContextFromContext(ctx).TraceID.String()
+				if _, ok := ctx.Deadline(); !ok {
+					return errors.New("expected job timeout")
+				}
+				return nil
+			}, WithJobCodec(codec), WithJobBackend(syncBackend(q)), WithJobTimeout(time.Second),
+				WithJobMiddleware(mw("a"), mw("b"), Recover(), Metrics()))
+
+			ctx, span := xtrace.Start(context.Background(), "producer")
+			id, err := job.Publish(ctx, mail{To: "a@b.c", Subject: "hi"})
+			span.End()
+			if err != nil || q.err != nil {
+				t.Fatal(err, q.err)
+			}
+			if got.To != "a@b.c" || got.Subject != "hi" {
+				t.Fatalf("unexpected payload %+v", got)
+			}
+			if env.ID != id || env.Topic != "test_mail" || env.Attempt != 2 || env.EnqueuedAt.IsZero() {
+				t.Fatalf("unexpected envelope %+v", env)
+			}
+			if env.TraceID != xtrace.FromTraceId(ctx) || traceID != span.SpanContext().TraceID.String() {
+				t.Fatalf("trace should propagate: %+v %s", env, traceID)
+			}
+			if strings.Join(order, ",") != "a,b" {
+				t.Fatalf("unexpected middleware order %v", order)
+			}
+		})
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	q := &syncQueue{}
+	job := Register("test_panic", func(ctx context.Context, m mail) error {
+		panic("boom")
+	}, WithJobBackend(syncBackend(q)), WithJobMiddleware(Recover(), Logging()))
+	if _, err := job.Publish(context.Background(), mail{}); err != nil {
+		t.Fatal(err)
+	}
+	if q.err == nil || q.err.Error() != "panic: boom" {
+		t.Fatalf("expected recovered panic, got %v", q.err)
+	}
+
+	if err := q.handler(context.Background(), "not json"); err == nil {
+		t.Fatal("expected decode error")
+	}
+	if _, err := job.PublishDelayed(context.Background(), mail{}, time.Second); err == nil {
+		t.Fatal("sync queue does not support delayed messages")
+	}
+}
+
+func TestRegisterDefaultBackendBeforeRedisInit(t *testing.T) {
+	if xredis.Get() != nil {
+		t.Skip("default redis client already initialized")
+	}
+
+	// 与包级变量中的 Register 相同，此时 xredis 还未初始化
+	got := make(chan mail, 1)
+	job := Register("test_lazy_default", func(ctx context.Context, m mail) error {
+		got <- m
+		return nil
+	})
+	defer job.Queue().Close()
+
+	if _, err := job.Publish(context.Background(), mail{To: "x"}); !errors.Is(err, errNoRedis) {
+		t.Fatalf("expected errNoRedis, got %v", err)
+	}
+	if err := job.Queue().Subscribe(); !errors.Is(err, errNoRedis) {
+		t.Fatalf("expected errNoRedis, got %v", err)
+	}
+
+	if err := xredis.Init(&redis.Options{Addr: "localhost:6379", DialTimeout: 200 * time.Millisecond}); err != nil {
+		_ = xredis.Close()
+		t.Skipf("redis not available: %v", err)
+	}
+	defer xredis.Close()
+	xredis.Get().Del(context.Background(), "xqueue:{test_lazy_default}")
+
+	go job.Queue().Subscribe()
+	if _, err := job.Publish(context.Background(), mail{To: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-got:
+		if m.To != "x" {
+			t.Fatalf("unexpected payload %+v", m)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("job not handled")
+	}
+}
@@ -0,0 +1,195 @@
+package xqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xmetrics"
+)
+
+var (
+	// ErrQueueClosed 队列已关闭
+	ErrQueueClosed = errors.New("xqueue: queue closed")
+	// ErrQueueFull 内存队列的缓冲已满
+	ErrQueueFull = errors.New("xqueue: queue full")
+)
+
+type MemoryOption func(*MemoryQueue)
+
+// WithBuffer 设置缓冲的消息数量，缓冲满时 Publish 返回 ErrQueueFull，默认 1024
+func WithBuffer(n int) MemoryOption {
+	return func(q *MemoryQueue) {
+		q.buffer = n
+	}
+}
+
+// AddMemoryQueue 创建进程内的队列，用法与 AddQueue 相同，适用于测试和单实例部署，
+// 消息不持久化，进程退出时缓冲中的消息会丢失
+func AddMemoryQueue(topic string, handler func(data string), workers int, opts ...MemoryOption) Queue {
+	return AddMemoryQueueE(topic, func(_ context.Context, data string) error {
+		handler(data)
+		return nil
+	}, workers, opts...)
+}
+
+// AddMemoryQueueE 与 AddMemoryQueue 相同，handler 返回的错误只记录日志与指标，不重试
+func AddMemoryQueueE(topic string, handler func(ctx context.Context, data string) error, workers int, opts ...MemoryOption) Queue {
+	queueLock.Lock()
+	defer queueLock.Unlock()
+	if q, ok := queueContainer[topic]; ok {
+		return q
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	queue := &MemoryQueue{
+		topic:   topic,
+		handler: handler,
+		ctx:     ctx,
+		cancel:  cancel,
+		workers: workers,
+		buffer:  1024,
+		timers:  make(map[string]*time.Timer),
+	}
+	for _, opt := range opts {
+		opt(queue)
+	}
+	queue.jobs = make(chan string, queue.buffer)
+	queueContainer[topic] = queue
+	return queue
+}
+
+type MemoryQueue struct {
+	topic   string
+	handler func(ctx context.Context, data string) error
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	workers int
+	buffer  int
+	jobs    chan string
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+
+	subscribed atomic.Bool
+}
+
+func (q *MemoryQueue) Publish(data string) error {
+	err := q.push(data)
+	xmetrics.ObservePublish(q.topic, err)
+	return err
+}
+
+func (q *MemoryQueue) push(data string) error {
+	if q.ctx.Err() != nil {
+		return ErrQueueClosed
+	}
+	select {
+	case q.jobs <- data:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Subscribe 启动 workers 并阻塞到队列关闭，之前发布的消息保存在缓冲中
+func (q *MemoryQueue) Subscribe() error {
+	xlog.Debug("start subscribe", xlog.String("topic", q.topic), xlog.Any("workers", q.workers))
+	if q.ctx.Err() != nil {
+		return nil
+	}
+	if !q.subscribed.CompareAndSwap(false, true) {
+		return errors.New("queue already subscribed: " + q.topic)
+	}
+	defer q.subscribed.Store(false)
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for {
+				select {
+				case <-q.ctx.Done():
+					return
+				case msg := <-q.jobs:
+					_ = process(q.ctx, "memory", q.topic, func(ctx context.Context) error {
+						return q.handler(ctx, msg)
+					})
+				}
+			}
+		}()
+	}
+	<-q.ctx.Done()
+	return nil
+}
+
+// Healthy 未订阅时返回错误
+func (q *MemoryQueue) Healthy() error {
+	if !q.subscribed.Load() {
+		return errors.New("queue not subscribed: " + q.topic)
+	}
+	return nil
+}
+
+// PublishAt 使用定时器在 at 时刻投递，进程退出时未到期的消息会丢失
+func (q *MemoryQueue) PublishAt(data string, at time.Time) (string, error) {
+	if q.ctx.Err() != nil {
+		return "", ErrQueueClosed
+	}
+	id := newMessageID()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.timers[id] = time.AfterFunc(time.Until(at), func() {
+		q.mu.Lock()
+		delete(q.timers, id)
+		q.mu.Unlock()
+		if err := q.push(data); err != nil {
+			xlog.Error("xqueue delayed publish error", xlog.String("topic", q.topic), xlog.String("id", id), xlog.Any("error", err))
+		}
+	})
+	xmetrics.ObservePublish(q.topic, nil)
+	return id, nil
+}
+
+func (q *MemoryQueue) PublishDelayed(data string, delay time.Duration) (string, error) {
+	return q.PublishAt(data, time.Now().Add(delay))
+}
+
+func (q *MemoryQueue) Cancel(id string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t, ok := q.timers[id]
+	if !ok {
+		return false, nil
+	}
+	delete(q.timers, id)
+	return t.Stop(), nil
+}
+
+func (q *MemoryQueue) Close() error {
+	q.cancel()
+	q.mu.Lock()
+	for id, t := range q.timers {
+		t.Stop()
+		delete(q.timers, id)
+	}
+	q.mu.Unlock()
+	q.wg.Wait()
+
+	queueLock.Lock()
+	delete(queueContainer, q.topic)
+	queueLock.Unlock()
+	return nil
+}
+
+// MemoryBackend 使用 AddMemoryQueueE 创建 Register 的底层队列
+func MemoryBackend(opts ...MemoryOption) Backend {
+	return func(topic string, handler func(ctx context.Context, data string) error, workers int) Queue {
+		return AddMemoryQueueE(topic, handler, workers, opts...)
+	}
+}
@@ -0,0 +1,125 @@
+package xqueue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMemoryQueue(t *testing.T) {
+	var handled atomic.Int32
+	q := AddMemoryQueue("test_memory", func(data string) {
+		handled.Add(1)
+	}, 2, WithBuffer(2))
+
+	// 订阅前发布的消息保存在缓冲中
+	if err := q.Publish("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Publish("b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Publish("c"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if err := Healthy("test_memory"); err == nil {
+		t.Fatal("expected unhealthy before subscribe")
+	}
+	go q.Subscribe()
+	waitFor(t, func() bool { return handled.Load() == 2 })
+	if err := Healthy("test_memory"); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := PublishDelayed("test_memory", "later", 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	canceled, _ := PublishDelayed("test_memory", "canceled", 20*time.Millisecond)
+	if ok, _ := Cancel("test_memory", canceled); !ok {
+		t.Fatal("expected cancel")
+	}
+	waitFor(t, func() bool { return handled.Load() == 3 })
+	if ok, _ := Cancel("test_memory", id); ok {
+		t.Fatal("delivered message should not be cancelable")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if handled.Load() != 3 {
+		t.Fatal("canceled message should not be delivered")
+	}
+
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Publish("d"); !errors.Is(err, ErrQueueClosed) {
+		t.Fatalf("expected ErrQueueClosed, got %v", err)
+	}
+}
+
+func TestRegisterMemoryBackend(t *testing.T) {
+	got := make(chan mail, 1)
+	ids := make(chan string, 1)
+	job := Register("test_memory_job", func(ctx context.Context, m mail) error {
+		if env, ok := EnvelopeFromContext(ctx); ok && m.To == "later" {
+			ids <- env.ID
+		}
+		got <- m
+		return nil
+	}, WithJobBackend(MemoryBackend()))
+	defer job.Queue().Close()
+	go job.Queue().Subscribe()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic on duplicate topic")
+			}
+		}()
+		Register("test_memory_job", func(ctx context.Context, m mail) error { return nil })
+	}()
+
+	if _, err := job.Publish(context.Background(), mail{To: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-got:
+		if m.To != "x" {
+			t.Fatalf("unexpected payload %+v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("job not handled")
+	}
+
+	canceled, err := job.PublishDelayed(context.Background(), mail{To: "never"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := job.Cancel(canceled.CancelID); !ok {
+		t.Fatal("expected delayed job canceled")
+	}
+	s, err := job.PublishDelayed(context.Background(), mail{To: "later"}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case id := <-ids:
+		if id != s.ID {
+			t.Fatalf("envelope id %q, want %q", id, s.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("delayed job not handled")
+	}
+}
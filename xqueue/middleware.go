@@ -0,0 +1,65 @@
+package xqueue
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xmetrics"
+)
+
+// Recover 将任务中的 panic 转为错误，使其按失败重试
+func Recover() Middleware {
+	return func(next JobHandler) JobHandler {
+		return func(ctx context.Context, env Envelope) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("panic: %v", r)
+					xlog.ErrorC(ctx, "xqueue job panic",
+						xlog.String("topic", env.Topic),
+						xlog.String("id", env.ID),
+						xlog.Any("error", r),
+						xlog.String("stack", string(debug.Stack())))
+				}
+			}()
+			return next(ctx, env)
+		}
+	}
+}
+
+// Logging 记录每次处理的结果与耗时
+func Logging() Middleware {
+	return func(next JobHandler) JobHandler {
+		return func(ctx context.Context, env Envelope) error {
+			start := time.Now()
+			err := next(ctx, env)
+			args := []any{
+				xlog.String("topic", env.Topic),
+				xlog.String("id", env.ID),
+				xlog.Int("attempt", env.Attempt),
+				xlog.Duration("duration", time.Since(start)),
+				xlog.Duration("queued", start.Sub(env.EnqueuedAt)),
+			}
+			if err != nil {
+				xlog.ErrorC(ctx, "xqueue job failed", append(args, xlog.Any("error", err))...)
+			} else {
+				xlog.InfoC(ctx, "xqueue job done", args...)
+			}
+			return err
+		}
+	}
+}
+
+// Metrics 记录任务耗时到 xmetrics 的 queue_job_duration_seconds
+func Metrics() Middleware {
+	return func(next JobHandler) JobHandler {
+		return func(ctx context.Context, env Envelope) error {
+			start := time.Now()
+			err := next(ctx, env)
+			xmetrics.ObserveJob(env.Topic, time.Since(start), err)
+			return err
+		}
+	}
+}
@@ -1,6 +1,17 @@
 package xqueue
 
-import "github.com/daodao97/xgo/xlog"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xmetrics"
+	"github.com/daodao97/xgo/xtrace"
+)
 
 type Queue interface {
 	Publish(data string) error
@@ -8,6 +19,23 @@ type Queue interface {
 	Close() error
 }
 
+// HealthChecker 由能够报告订阅状态的 Queue 实现
+type HealthChecker interface {
+	Healthy() error
+}
+
+// Healthy 检查 topic 对应队列的订阅状态
+func Healthy(topic string) error {
+	q := GetQueue(topic)
+	if q == nil {
+		return errors.New("queue not found: " + topic)
+	}
+	if hc, ok := q.(HealthChecker); ok {
+		return hc.Healthy()
+	}
+	return nil
+}
+
 type QueueWorker struct {
 	queues []Queue
 }
@@ -30,8 +58,80 @@ func (w *QueueWorker) Start() error {
 	return nil
 }
 
+// BackgroundWorker 标记为后台任务，加入 xapp.App 时在 HTTP 排空之后停止
+func (w *QueueWorker) BackgroundWorker() {}
+
+// HealthCheckName 实现 xapp 的 healthCheckServer，加入 App 时注册订阅状态检查
+func (w *QueueWorker) HealthCheckName() string {
+	queueLock.RLock()
+	defer queueLock.RUnlock()
+	topics := make([]string, 0, len(w.queues))
+	for _, q := range w.queues {
+		for topic, registered := range queueContainer {
+			if registered == q {
+				topics = append(topics, topic)
+				break
+			}
+		}
+	}
+	return "xqueue:" + strings.Join(topics, ",")
+}
+
+// HealthCheck 任一队列未订阅时返回错误
+func (w *QueueWorker) HealthCheck(ctx context.Context) error {
+	var errs []error
+	for _, q := range w.queues {
+		if hc, ok := q.(HealthChecker); ok {
+			if err := hc.Healthy(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (w *QueueWorker) Stop() {
 	for _, queue := range w.queues {
 		queue.Close()
 	}
 }
+
+// process 在消费 span 中执行 handler，记录耗时指标，handler panic 时转为错误返回。
+// ctx 为队列的生命周期，关闭队列时取消
+func process(ctx context.Context, system, topic string, handler func(ctx context.Context) error) (err error) {
+	ctx, span := xtrace.Start(ctx, "xqueue."+topic,
+		xtrace.WithSpanKind(xtrace.SpanKindConsumer),
+		xtrace.WithSpanAttr("messaging.system", system),
+		xtrace.WithSpanAttr("messaging.destination.name", topic),
+	)
+	defer span.End()
+	start := time.Now()
+	defer func() {
+		xmetrics.ObserveQueue(topic, time.Since(start), err)
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			xlog.Error("xqueue recover panic in handler",
+				xlog.String("topic", topic),
+				xlog.Any("error", r),
+				xlog.String("stack", string(debug.Stack())))
+		}
+		span.SetError(err)
+	}()
+	return handler(ctx)
+}
+
+type ctxAttemptKey struct{}
+
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, ctxAttemptKey{}, attempt)
+}
+
+// attemptFromContext 返回当前是第几次处理，不支持重试的队列始终为 1
+func attemptFromContext(ctx context.Context) int {
+	if n, ok := ctx.Value(ctxAttemptKey{}).(int); ok {
+		return n
+	}
+	return 1
+}
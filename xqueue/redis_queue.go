@@ -2,10 +2,12 @@ package xqueue
 
 import (
 	"context"
-	"runtime/debug"
+	"errors"
 	"sync"
+	"sync/atomic"
 
 	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xmetrics"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -58,6 +60,8 @@ type RedisQueue struct {
 	wg      sync.WaitGroup
 	workers int         // 添加 workers 配置
 	jobs    chan string // 添加任务队列
+
+	subscribed atomic.Bool // 订阅是否处于活跃状态
 }
 
 func (q *RedisQueue) startWorkers() {
@@ -79,16 +83,10 @@ func (q *RedisQueue) startWorkers() {
 						xlog.String("data", msg),
 						xlog.Int("workerID", workerID))
 
-					func() {
-						defer func() {
-							if r := recover(); r != nil {
-								xlog.Error("RedisQueue recover panic in handler",
-									xlog.Any("error", r),
-									xlog.String("stack", string(debug.Stack())))
-							}
-						}()
+					_ = process(q.ctx, "redis", q.topic, func(context.Context) error {
 						q.handler(msg)
-					}()
+						return nil
+					})
 				}
 			}
 		}(i)
@@ -96,7 +94,9 @@ func (q *RedisQueue) startWorkers() {
 }
 
 func (q *RedisQueue) Publish(data string) error {
-	return q.redis.Publish(context.Background(), q.topic, data).Err()
+	err := q.redis.Publish(context.Background(), q.topic, data).Err()
+	xmetrics.ObservePublish(q.topic, err)
+	return err
 }
 
 func (q *RedisQueue) Subscribe() error {
@@ -108,6 +108,8 @@ func (q *RedisQueue) Subscribe() error {
 	if _, err := pubsub.Receive(context.Background()); err != nil {
 		return err
 	}
+	q.subscribed.Store(true)
+	defer q.subscribed.Store(false)
 
 	ch := pubsub.Channel()
 	for {
@@ -129,6 +131,14 @@ func (q *RedisQueue) Subscribe() error {
 	}
 }
 
+// Healthy 订阅未建立或已断开时返回错误
+func (q *RedisQueue) Healthy() error {
+	if !q.subscribed.Load() {
+		return errors.New("queue not subscribed: " + q.topic)
+	}
+	return nil
+}
+
 func (q *RedisQueue) Close() error {
 	// 1. 停止接收新消息
 	q.cancel()
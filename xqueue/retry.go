@@ -0,0 +1,168 @@
+package xqueue
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/daodao97/xgo/xlog"
+	"github.com/redis/go-redis/v9"
+)
+
+// stream 条目中的重试信息字段
+const (
+	attemptField = "attempt"
+	historyField = "history"
+)
+
+// moveInterval 检查到期的重试与延迟消息的间隔，也是延迟消息的投递精度
+const moveInterval = time.Second
+
+// Attempt 一次失败的处理记录
+type Attempt struct {
+	Attempt int       `json:"attempt"`
+	Error   string    `json:"error"`
+	At      time.Time `json:"at"`
+}
+
+func (q *StreamQueue) delayedKey() string     { return q.stream + ":delayed" }
+func (q *StreamQueue) delayedDataKey() string { return q.stream + ":delayed:data" }
+
+func attemptOf(msg redis.XMessage) int {
+	s, _ := msg.Values[attemptField].(string)
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return n
+	}
+	return 1
+}
+
+func historyOf(msg redis.XMessage) []Attempt {
+	var history []Attempt
+	if s, ok := msg.Values[historyField].(string); ok && s != "" {
+		_ = json.Unmarshal([]byte(s), &history)
+	}
+	return history
+}
+
+// backoff 第 attempt 次失败后的等待时间
+func (q *StreamQueue) backoff(attempt int) time.Duration {
+	return retryBackoff(q.minBackoff, q.maxBackoff, attempt)
+}
+
+func retryBackoff(minBackoff, maxBackoff time.Duration, attempt int) time.Duration {
+	d := minBackoff
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// fail 处理失败的消息：未达到最大次数时放入延迟集合等待重试，否则移入死信，最后确认原消息
+func (q *StreamQueue) fail(ctx context.Context, msg redis.XMessage, data string, cause error) error {
+	attempt := attemptOf(msg)
+	history := append(historyOf(msg), Attempt{Attempt: attempt, Error: cause.Error(), At: time.Now()})
+	historyJSON, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+
+	if attempt >= q.maxAttempts {
+		xlog.Warn("xqueue message moved to dead letter",
+			xlog.String("topic", q.topic),
+			xlog.String("id", msg.ID),
+			xlog.Int("attempts", attempt),
+			xlog.Any("error", cause))
+		_, err = q.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			// 死信不裁剪，由运维通过 PurgeDeadLetters 清理
+			pipe.XAdd(ctx, &redis.XAddArgs{Stream: q.deadKey(), Values: []any{
+				streamField, data,
+				attemptField, strconv.Itoa(attempt),
+				historyField, string(historyJSON),
+				errorField, cause.Error(),
+				originField, msg.ID,
+				failedAtField, strconv.FormatInt(time.Now().UnixMilli(), 10),
+			}})
+			pipe.XAck(ctx, q.stream, q.group, msg.ID)
+			return nil
+		})
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		streamField:  data,
+		attemptField: strconv.Itoa(attempt + 1),
+		historyField: string(historyJSON),
+	})
+	if err != nil {
+		return err
+	}
+	delay := q.backoff(attempt)
+	xlog.Debug("xqueue message retry scheduled",
+		xlog.String("topic", q.topic),
+		xlog.String("id", msg.ID),
+		xlog.Int("attempt", attempt),
+		xlog.Any("delay", delay),
+		xlog.Any("error", cause))
+	_, err = q.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, q.delayedDataKey(), msg.ID, payload)
+		pipe.ZAdd(ctx, q.delayedKey(), redis.Z{Score: float64(time.Now().Add(delay).UnixMilli()), Member: msg.ID})
+		pipe.XAck(ctx, q.stream, q.group, msg.ID)
+		return nil
+	})
+	return err
+}
+
+// moveLoop 定期把到期的重试与延迟消息写入 stream，多个实例同时运行时由 Lua 脚本保证每条消息只移动一次
+func (q *StreamQueue) moveLoop() {
+	ticker := time.NewTicker(moveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := q.moveDue(q.ctx); err != nil && q.ctx.Err() == nil {
+				xlog.Warn("xqueue move delayed messages error", xlog.String("topic", q.topic), xlog.Any("error", err))
+			}
+		}
+	}
+}
+
+func (q *StreamQueue) moveDue(ctx context.Context) error {
+	const batch = 100
+	for {
+		n, err := moveDueScript.Run(ctx, q.redis,
+			[]string{q.delayedKey(), q.delayedDataKey(), q.stream},
+			time.Now().UnixMilli(), batch, q.maxLen,
+		).Int()
+		if err != nil || n < batch {
+			return err
+		}
+	}
+}
+
+// 把 score 不大于当前时间的消息按保存的字段写回 stream，返回处理的数量
+var moveDueScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, id in ipairs(ids) do
+	local payload = redis.call('HGET', KEYS[2], id)
+	if payload then
+		local args = {KEYS[3]}
+		if tonumber(ARGV[3]) > 0 then
+			table.insert(args, 'MAXLEN')
+			table.insert(args, '~')
+			table.insert(args, ARGV[3])
+		end
+		table.insert(args, '*')
+		for k, v in pairs(cjson.decode(payload)) do
+			table.insert(args, k)
+			table.insert(args, v)
+		end
+		redis.call('XADD', unpack(args))
+		redis.call('HDEL', KEYS[2], id)
+	end
+	redis.call('ZREM', KEYS[1], id)
+end
+return #ids
+`)
@@ -0,0 +1,632 @@
+package xqueue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/daodao97/xgo/xdb"
+	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xmetrics"
+)
+
+type SQLOption func(*SQLQueue)
+
+// WithSQLTable 设置任务表名，默认 xqueue_jobs，多个 topic 可以共用一张表
+func WithSQLTable(table string) SQLOption {
+	return func(q *SQLQueue) {
+		q.table = table
+	}
+}
+
+// WithSQLDB 直接指定数据库连接与驱动名，不使用 xdb 中注册的连接
+func WithSQLDB(db *sql.DB, driver string) SQLOption {
+	return func(q *SQLQueue) {
+		q.db = db
+		q.dialect = xdb.GetDialect(driver)
+	}
+}
+
+// WithSQLPollInterval 没有可执行的任务时的轮询间隔，默认 1s
+func WithSQLPollInterval(d time.Duration) SQLOption {
+	return func(q *SQLQueue) {
+		q.pollInterval = d
+	}
+}
+
+// WithSQLLease 任务被领取后的租期，超过租期仍未完成（例如进程崩溃）会被重新领取，默认 5 分钟，
+// 需大于 handler 的最长执行时间
+func WithSQLLease(d time.Duration) SQLOption {
+	return func(q *SQLQueue) {
+		q.lease = d
+	}
+}
+
+// WithSQLMaxAttempts 包含首次处理在内的最大尝试次数，超过后标记为死信，默认 5
+func WithSQLMaxAttempts(n int) SQLOption {
+	return func(q *SQLQueue) {
+		q.maxAttempts = n
+	}
+}
+
+// WithSQLRetryBackoff 失败重试的间隔，第 n 次重试等待 min*2^(n-1)，不超过 max，默认 1s ~ 5m
+func WithSQLRetryBackoff(min, max time.Duration) SQLOption {
+	return func(q *SQLQueue) {
+		q.minBackoff = min
+		q.maxBackoff = max
+	}
+}
+
+// WithSQLAutoCreate 订阅前执行 SQLQueueSchema 创建任务表
+func WithSQLAutoCreate() SQLOption {
+	return func(q *SQLQueue) {
+		q.autoCreate = true
+	}
+}
+
+// AddSQLQueue 创建基于 xdb 连接的持久化队列，用法与 AddQueue 相同。
+// MySQL 8 与 PostgreSQL 使用 SELECT ... FOR UPDATE SKIP LOCKED 领取任务，SQLite 使用条件更新轮询，
+// 任务在 handler 成功后删除，失败时按退避时间重试
+//
+//	xqueue.AddSQLQueue("default", "mail", send, 2, xqueue.WithSQLAutoCreate())
+func AddSQLQueue(conn, topic string, handler func(data string), workers int, opts ...SQLOption) Queue {
+	return AddSQLQueueE(conn, topic, func(_ context.Context, data string) error {
+		handler(data)
+		return nil
+	}, workers, opts...)
+}
+
+// AddSQLQueueE 与 AddSQLQueue 相同，handler 返回错误时重试，超过最大次数后标记为死信
+func AddSQLQueueE(conn, topic string, handler func(ctx context.Context, data string) error, workers int, opts ...SQLOption) Queue {
+	queueLock.Lock()
+	defer queueLock.Unlock()
+	if q, ok := queueContainer[topic]; ok {
+		return q
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	queue := &SQLQueue{
+		conn:         conn,
+		table:        "xqueue_jobs",
+		topic:        topic,
+		handler:      handler,
+		ctx:          ctx,
+		cancel:       cancel,
+		workers:      workers,
+		jobs:         make(chan sqlJob, workers),
+		pollInterval: time.Second,
+		lease:        5 * time.Minute,
+		maxAttempts:  5,
+		minBackoff:   time.Second,
+		maxBackoff:   5 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(queue)
+	}
+	queue.startWorkers()
+	queueContainer[topic] = queue
+	return queue
+}
+
+// SQLBackend 使用 AddSQLQueueE 创建 Register 的底层队列
+func SQLBackend(conn string, opts ...SQLOption) Backend {
+	return func(topic string, handler func(ctx context.Context, data string) error, workers int) Queue {
+		return AddSQLQueueE(conn, topic, handler, workers, opts...)
+	}
+}
+
+// SQLQueueSchema 返回任务表的建表语句，run_at 等时间字段为毫秒时间戳
+func SQLQueueSchema(driver, table string) []string {
+	columns := `topic VARCHAR(191) NOT NULL,
+	payload %s NOT NULL,
+	attempt INT NOT NULL DEFAULT 0,
+	run_at BIGINT NOT NULL,
+	created_at BIGINT NOT NULL,
+	dead SMALLINT NOT NULL DEFAULT 0,
+	last_error TEXT,
+	history TEXT`
+	switch xdb.GetDialect(driver).Name() {
+	case "postgres":
+		return []string{
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\tid BIGSERIAL PRIMARY KEY,\n\t%s\n)", table, fmt.Sprintf(columns, "TEXT")),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_run ON %s (topic, dead, run_at)", table, table),
+		}
+	case "sqlite":
+		return []string{
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\tid INTEGER PRIMARY KEY AUTOINCREMENT,\n\t%s\n)", table, fmt.Sprintf(columns, "TEXT")),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_run ON %s (topic, dead, run_at)", table, table),
+		}
+	default:
+		return []string{
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\tid BIGINT AUTO_INCREMENT PRIMARY KEY,\n\t%s,\n\tKEY idx_run (topic, dead, run_at)\n)", table, fmt.Sprintf(columns, "MEDIUMTEXT")),
+		}
+	}
+}
+
+type sqlJob struct {
+	id      int64
+	payload string
+	attempt int
+	history string
+}
+
+type SQLQueue struct {
+	conn    string
+	db      *sql.DB
+	dialect xdb.Dialect
+	table   string
+	topic   string
+	handler func(ctx context.Context, data string) error
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	workers int
+	jobs    chan sqlJob
+
+	pollInterval time.Duration
+	lease        time.Duration
+	maxAttempts  int
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	autoCreate   bool
+
+	initOnce   sync.Once
+	initErr    error
+	subscribed atomic.Bool
+}
+
+// init 延迟获取 xdb 连接，允许在 xdb.Init 之前注册队列
+func (q *SQLQueue) init() error {
+	q.initOnce.Do(func() {
+		if q.db != nil {
+			return
+		}
+		if q.db, q.initErr = xdb.DB(q.conn); q.initErr != nil {
+			return
+		}
+		q.dialect, q.initErr = xdb.ConnDialect(q.conn)
+	})
+	return q.initErr
+}
+
+func (q *SQLQueue) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return q.db.ExecContext(ctx, q.dialect.ConvertPlaceholders(query), args...)
+}
+
+func inClause(ids []int64) (string, []any) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return "(" + strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",") + ")", args
+}
+
+func parseIDs(ids []string) ([]int64, error) {
+	out := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("xqueue: invalid job id %q", id)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func (q *SQLQueue) Publish(data string) error {
+	_, err := q.insert(data, time.Now())
+	xmetrics.ObservePublish(q.topic, err)
+	return err
+}
+
+func (q *SQLQueue) insert(data string, runAt time.Time) (int64, error) {
+	if err := q.init(); err != nil {
+		return 0, err
+	}
+	ctx := context.Background()
+	query := fmt.Sprintf("INSERT INTO %s (topic, payload, attempt, run_at, created_at, dead) VALUES (?, ?, 0, ?, ?, 0)", q.table)
+	args := []any{q.topic, data, runAt.UnixMilli(), time.Now().UnixMilli()}
+	if !q.dialect.SupportsLastInsertId() {
+		var id int64
+		err := q.db.QueryRowContext(ctx, q.dialect.ConvertPlaceholders(q.dialect.InsertReturning(query, "id")), args...).Scan(&id)
+		return id, err
+	}
+	res, err := q.exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// PublishAt 在 at 时刻投递，返回任务 id
+func (q *SQLQueue) PublishAt(data string, at time.Time) (string, error) {
+	id, err := q.insert(data, at)
+	xmetrics.ObservePublish(q.topic, err)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+func (q *SQLQueue) PublishDelayed(data string, delay time.Duration) (string, error) {
+	return q.PublishAt(data, time.Now().Add(delay))
+}
+
+// Cancel 删除尚未被领取过的任务
+func (q *SQLQueue) Cancel(id string) (bool, error) {
+	ids, err := parseIDs([]string{id})
+	if err != nil {
+		return false, err
+	}
+	if err := q.init(); err != nil {
+		return false, err
+	}
+	res, err := q.exec(context.Background(),
+		fmt.Sprintf("DELETE FROM %s WHERE id = ? AND topic = ? AND dead = 0 AND attempt = 0", q.table), ids[0], q.topic)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (q *SQLQueue) startWorkers() {
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for {
+				select {
+				case <-q.ctx.Done():
+					return
+				case job, ok := <-q.jobs:
+					if !ok {
+						return
+					}
+					q.handle(job)
+				}
+			}
+		}()
+	}
+}
+
+func (q *SQLQueue) handle(job sqlJob) {
+	err := process(withAttempt(q.ctx, job.attempt), "sql", q.topic, func(ctx context.Context) error {
+		return q.handler(ctx, job.payload)
+	})
+	ctx := context.Background()
+	switch {
+	case err == nil:
+		_, err = q.exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = ?", q.table), job.id)
+	case q.ctx.Err() != nil:
+		// 关闭队列时被取消的任务立即释放，不计入尝试次数
+		_, err = q.exec(ctx, fmt.Sprintf("UPDATE %s SET run_at = ?, attempt = attempt - 1 WHERE id = ?", q.table),
+			time.Now().UnixMilli(), job.id)
+	default:
+		err = q.fail(ctx, job, err)
+	}
+	if err != nil {
+		// 租期结束后任务会被重新领取
+		xlog.Error("xqueue sql update error", xlog.String("topic", q.topic), xlog.Any("id", job.id), xlog.Any("error", err))
+	}
+}
+
+func (q *SQLQueue) fail(ctx context.Context, job sqlJob, cause error) error {
+	var history []Attempt
+	if job.history != "" {
+		_ = json.Unmarshal([]byte(job.history), &history)
+	}
+	history = append(history, Attempt{Attempt: job.attempt, Error: cause.Error(), At: time.Now()})
+	historyJSON, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	if job.attempt >= q.maxAttempts {
+		xlog.Warn("xqueue message moved to dead letter",
+			xlog.String("topic", q.topic),
+			xlog.Any("id", job.id),
+			xlog.Int("attempts", job.attempt),
+			xlog.Any("error", cause))
+		// 死信的 run_at 记录失败时间
+		_, err = q.exec(ctx, fmt.Sprintf("UPDATE %s SET dead = 1, run_at = ?, last_error = ?, history = ? WHERE id = ?", q.table),
+			time.Now().UnixMilli(), cause.Error(), string(historyJSON), job.id)
+		return err
+	}
+	runAt := time.Now().Add(retryBackoff(q.minBackoff, q.maxBackoff, job.attempt))
+	_, err = q.exec(ctx, fmt.Sprintf("UPDATE %s SET run_at = ?, last_error = ?, history = ? WHERE id = ?", q.table),
+		runAt.UnixMilli(), cause.Error(), string(historyJSON), job.id)
+	return err
+}
+
+// claim 领取最多 n 个到期的任务，并把 run_at 推迟到租期结束
+func (q *SQLQueue) claim(ctx context.Context, n int) ([]sqlJob, error) {
+	now := time.Now()
+	selectSQL := fmt.Sprintf("SELECT id, payload, attempt, history, run_at FROM %s WHERE topic = ? AND dead = 0 AND run_at <= ? ORDER BY run_at, id LIMIT %d", q.table, n)
+	leaseUntil := now.Add(q.lease).UnixMilli()
+
+	if q.dialect.Name() == "sqlite" {
+		// SQLite 不支持 SKIP LOCKED，以 run_at 作为版本号条件更新，更新成功才算领取
+		candidates, err := q.selectJobs(ctx, q.db, selectSQL, q.topic, now.UnixMilli())
+		if err != nil {
+			return nil, err
+		}
+		var claimed []sqlJob
+		for _, c := range candidates {
+			res, err := q.exec(ctx, fmt.Sprintf("UPDATE %s SET run_at = ?, attempt = attempt + 1 WHERE id = ? AND run_at = ? AND dead = 0", q.table),
+				leaseUntil, c.job.id, c.runAt)
+			if err != nil {
+				return claimed, err
+			}
+			if affected, _ := res.RowsAffected(); affected == 1 {
+				c.job.attempt++
+				claimed = append(claimed, c.job)
+			}
+		}
+		return claimed, nil
+	}
+
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	candidates, err := q.selectJobs(ctx, tx, selectSQL+" FOR UPDATE SKIP LOCKED", q.topic, now.UnixMilli())
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+	ids := make([]int64, len(candidates))
+	jobs := make([]sqlJob, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.job.id
+		jobs[i] = c.job
+		jobs[i].attempt++
+	}
+	in, args := inClause(ids)
+	_, err = tx.ExecContext(ctx, q.dialect.ConvertPlaceholders(fmt.Sprintf("UPDATE %s SET run_at = ?, attempt = attempt + 1 WHERE id IN %s", q.table, in)),
+		append([]any{leaseUntil}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	return jobs, tx.Commit()
+}
+
+type sqlCandidate struct {
+	job   sqlJob
+	runAt int64
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (q *SQLQueue) selectJobs(ctx context.Context, db queryer, query string, args ...any) ([]sqlCandidate, error) {
+	rows, err := db.QueryContext(ctx, q.dialect.ConvertPlaceholders(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []sqlCandidate
+	for rows.Next() {
+		var c sqlCandidate
+		var history sql.NullString
+		if err := rows.Scan(&c.job.id, &c.job.payload, &c.job.attempt, &history, &c.runAt); err != nil {
+			return nil, err
+		}
+		c.job.history = history.String
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// Subscribe 轮询任务表，领取到期的任务交给 workers，
+// 关闭队列时已领取但还未处理的任务立即释放，不计入尝试次数
+func (q *SQLQueue) Subscribe() error {
+	xlog.Debug("start subscribe", xlog.String("topic", q.topic), xlog.String("table", q.table), xlog.Any("workers", q.workers))
+	if q.ctx.Err() != nil {
+		return nil
+	}
+	// Close 等待释放完成
+	q.wg.Add(1)
+	defer q.wg.Done()
+	defer close(q.jobs)
+	var pending []sqlJob
+	defer func() { q.release(append(pending, q.drain()...)) }()
+	defer q.subscribed.Store(false)
+	if err := q.init(); err != nil {
+		return err
+	}
+	if q.autoCreate {
+		for _, stmt := range SQLQueueSchema(q.dialect.Name(), q.table) {
+			if _, err := q.db.ExecContext(q.ctx, stmt); err != nil {
+				return err
+			}
+		}
+	}
+
+	for q.ctx.Err() == nil {
+		jobs, err := q.claim(q.ctx, q.workers)
+		if err != nil && q.ctx.Err() == nil {
+			q.subscribed.Store(false)
+			xlog.Warn("xqueue sql claim error", xlog.String("topic", q.topic), xlog.Any("error", err))
+		} else {
+			q.subscribed.Store(true)
+		}
+		for i, job := range jobs {
+			select {
+			case <-q.ctx.Done():
+				pending = jobs[i:]
+				return nil
+			case q.jobs <- job:
+			}
+		}
+		// 领满一批时可能还有积压，立即继续领取
+		if err == nil && len(jobs) == q.workers {
+			continue
+		}
+		select {
+		case <-q.ctx.Done():
+		case <-time.After(q.pollInterval):
+		}
+	}
+	return nil
+}
+
+// drain 取出已交给 workers 但还未被处理的任务
+func (q *SQLQueue) drain() []sqlJob {
+	var jobs []sqlJob
+	for {
+		select {
+		case job := <-q.jobs:
+			jobs = append(jobs, job)
+		default:
+			return jobs
+		}
+	}
+}
+
+// release 把领取的任务放回队列，与 handle 中被取消的任务相同，撤销领取时增加的 attempt
+func (q *SQLQueue) release(jobs []sqlJob) {
+	if len(jobs) == 0 {
+		return
+	}
+	ids := make([]int64, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.id
+	}
+	in, args := inClause(ids)
+	_, err := q.exec(context.Background(), fmt.Sprintf("UPDATE %s SET run_at = ?, attempt = attempt - 1 WHERE id IN %s", q.table, in),
+		append([]any{time.Now().UnixMilli()}, args...)...)
+	if err != nil {
+		// 租期结束后任务会被重新领取
+		xlog.Error("xqueue sql release error", xlog.String("topic", q.topic), xlog.Any("ids", ids), xlog.Any("error", err))
+	}
+}
+
+// Healthy 未订阅或领取任务出错时返回错误
+func (q *SQLQueue) Healthy() error {
+	if !q.subscribed.Load() {
+		return errors.New("queue not subscribed: " + q.topic)
+	}
+	return nil
+}
+
+func (q *SQLQueue) DeadLetters(ctx context.Context, after string, count int64) ([]DeadLetter, error) {
+	if err := q.init(); err != nil {
+		return nil, err
+	}
+	var afterID int64
+	if after != "" {
+		ids, err := parseIDs([]string{after})
+		if err != nil {
+			return nil, err
+		}
+		afterID = ids[0]
+	}
+	if count <= 0 {
+		count = 100
+	}
+	return q.deadLetters(ctx, fmt.Sprintf("AND id > ? ORDER BY id LIMIT %d", count), afterID)
+}
+
+func (q *SQLQueue) DeadLetter(ctx context.Context, id string) (*DeadLetter, error) {
+	ids, err := parseIDs([]string{id})
+	if err != nil {
+		return nil, err
+	}
+	if err := q.init(); err != nil {
+		return nil, err
+	}
+	list, err := q.deadLetters(ctx, "AND id = ?", ids[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrDeadLetterNotFound
+	}
+	return &list[0], nil
+}
+
+func (q *SQLQueue) deadLetters(ctx context.Context, cond string, args ...any) ([]DeadLetter, error) {
+	query := fmt.Sprintf("SELECT id, payload, attempt, last_error, history, run_at FROM %s WHERE topic = ? AND dead = 1 %s", q.table, cond)
+	rows, err := q.db.QueryContext(ctx, q.dialect.ConvertPlaceholders(query), append([]any{q.topic}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []DeadLetter
+	for rows.Next() {
+		var (
+			id             int64
+			lastErr, hist  sql.NullString
+			failedAtMillis int64
+			d              = DeadLetter{Topic: q.topic}
+		)
+		if err := rows.Scan(&id, &d.Data, &d.Attempts, &lastErr, &hist, &failedAtMillis); err != nil {
+			return nil, err
+		}
+		d.ID = strconv.FormatInt(id, 10)
+		d.OriginID = d.ID
+		d.Error = lastErr.String
+		d.FailedAt = time.UnixMilli(failedAtMillis)
+		if hist.String != "" {
+			_ = json.Unmarshal([]byte(hist.String), &d.History)
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// ReplayDeadLetters 重置尝试次数并立即重新投递，保留失败历史
+func (q *SQLQueue) ReplayDeadLetters(ctx context.Context, ids ...string) (int, error) {
+	if err := q.init(); err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf("UPDATE %s SET dead = 0, attempt = 0, run_at = ? WHERE topic = ? AND dead = 1", q.table)
+	return q.updateDead(ctx, query, []any{time.Now().UnixMilli(), q.topic}, ids)
+}
+
+func (q *SQLQueue) PurgeDeadLetters(ctx context.Context, ids ...string) (int, error) {
+	if err := q.init(); err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE topic = ? AND dead = 1", q.table)
+	return q.updateDead(ctx, query, []any{q.topic}, ids)
+}
+
+func (q *SQLQueue) updateDead(ctx context.Context, query string, args []any, ids []string) (int, error) {
+	if len(ids) > 0 {
+		parsed, err := parseIDs(ids)
+		if err != nil {
+			return 0, err
+		}
+		in, idArgs := inClause(parsed)
+		query += " AND id IN " + in
+		args = append(args, idArgs...)
+	}
+	res, err := q.exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (q *SQLQueue) Close() error {
+	q.cancel()
+	q.wg.Wait()
+	xlog.Debug("all messages processed", xlog.String("topic", q.topic))
+
+	queueLock.Lock()
+	delete(queueContainer, q.topic)
+	queueLock.Unlock()
+	return nil
+}
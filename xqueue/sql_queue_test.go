@@ -0,0 +1,204 @@
+package xqueue
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLQueueSchema(t *testing.T) {
+	for driver, want := range map[string]string{
+		"mysql":    "AUTO_INCREMENT",
+		"postgres": "BIGSERIAL",
+		"sqlite3":  "AUTOINCREMENT",
+	} {
+		stmts := SQLQueueSchema(driver, "jobs")
+		if !strings.Contains(stmts[0], want) || !strings.Contains(strings.Join(stmts, ";"), "(topic, dead, run_at)") {
+			t.Fatalf("%s: unexpected schema %v", driver, stmts)
+		}
+	}
+}
+
+// testMySQL 连接本地 mysql，不可用时跳过
+func testMySQL(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("mysql", "root@tcp(127.0.0.1:3306)/fly_test?timeout=200ms")
+	if err != nil {
+		t.Skipf("mysql not available: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		t.Skipf("mysql not available: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestSQLQueue(t *testing.T) {
+	db := testMySQL(t)
+	_, _ = db.Exec("DROP TABLE IF EXISTS xqueue_test_jobs")
+	defer db.Exec("DROP TABLE IF EXISTS xqueue_test_jobs")
+
+	var calls, done atomic.Int32
+	q := AddSQLQueueE("", "test_sql", func(_ context.Context, data string) error {
+		if calls.Add(1) == 1 {
+			return errors.New("first attempt fails")
+		}
+		done.Add(1)
+		return nil
+	}, 2, WithSQLDB(db, "mysql"), WithSQLTable("xqueue_test_jobs"), WithSQLAutoCreate(),
+		WithSQLPollInterval(20*time.Millisecond), WithSQLRetryBackoff(10*time.Millisecond, 10*time.Millisecond), WithSQLMaxAttempts(2))
+	defer q.Close()
+	go q.Subscribe()
+	waitFor(t, func() bool { return Healthy("test_sql") == nil })
+
+	if err := q.Publish("a"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return done.Load() == 1 })
+	if calls.Load() != 2 {
+		t.Fatalf("expected one retry, got %d calls", calls.Load())
+	}
+
+	id, err := PublishDelayed("test_sql", "later", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := Cancel("test_sql", id); err != nil || !ok {
+		t.Fatalf("expected cancel, got %v %v", ok, err)
+	}
+}
+
+func TestSQLQueueSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "jobs.db")+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var retries, done atomic.Int32
+	var healed atomic.Bool
+	q := AddSQLQueueE("", "test_sqlite", func(_ context.Context, data string) error {
+		switch {
+		case data == "retry" && retries.Add(1) == 1:
+			return errors.New("first attempt fails")
+		case data == "bad" && !healed.Load(), data == "purge":
+			return errors.New("always fails")
+		}
+		done.Add(1)
+		return nil
+	}, 2, WithSQLDB(db, "sqlite"), WithSQLAutoCreate(),
+		WithSQLPollInterval(20*time.Millisecond), WithSQLRetryBackoff(10*time.Millisecond, 10*time.Millisecond), WithSQLMaxAttempts(2))
+	defer q.Close()
+	go q.Subscribe()
+	waitFor(t, func() bool { return Healthy("test_sqlite") == nil })
+
+	// 领取与重试
+	if err := q.Publish("retry"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return done.Load() == 1 })
+	if retries.Load() != 2 {
+		t.Fatalf("expected one retry, got %d calls", retries.Load())
+	}
+
+	// 超过最大次数后进入死信
+	if err := q.Publish("bad"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	var dead []DeadLetter
+	waitFor(t, func() bool {
+		dead, err = ListDeadLetters(ctx, "test_sqlite", "", 10)
+		return err == nil && len(dead) == 1
+	})
+	d, err := InspectDeadLetter(ctx, "test_sqlite", dead[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Data != "bad" || d.Attempts != 2 || len(d.History) != 2 || d.Error != "always fails" {
+		t.Fatalf("unexpected dead letter %+v", d)
+	}
+
+	// 重放后重新投递
+	healed.Store(true)
+	if n, err := ReplayDeadLetters(ctx, "test_sqlite"); err != nil || n != 1 {
+		t.Fatalf("expected 1 replayed, got %d %v", n, err)
+	}
+	waitFor(t, func() bool { return done.Load() == 2 })
+	if _, err := InspectDeadLetter(ctx, "test_sqlite", dead[0].ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Fatalf("expected replayed dead letter to be gone, got %v", err)
+	}
+
+	// 删除死信
+	if err := q.Publish("purge"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		dead, err = ListDeadLetters(ctx, "test_sqlite", "", 10)
+		return err == nil && len(dead) == 1
+	})
+	if n, err := PurgeDeadLetters(ctx, "test_sqlite"); err != nil || n != 1 {
+		t.Fatalf("expected 1 purged, got %d %v", n, err)
+	}
+	if dead, err := ListDeadLetters(ctx, "test_sqlite", "", 10); err != nil || len(dead) != 0 {
+		t.Fatalf("expected no dead letters, got %v %v", dead, err)
+	}
+
+	// 延迟任务可以取消
+	id, err := PublishDelayed("test_sqlite", "later", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := Cancel("test_sqlite", id); err != nil || !ok {
+		t.Fatalf("expected cancel, got %v %v", ok, err)
+	}
+	if ok, _ := Cancel("test_sqlite", id); ok {
+		t.Fatal("expected second cancel to miss")
+	}
+}
+
+func TestSQLQueueCloseReleasesClaimedJobs(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "jobs.db")+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// handler 一直阻塞到关闭，其余任务停留在 jobs 缓冲或 Subscribe 的批次中
+	q := AddSQLQueueE("", "test_sqlite_close", func(ctx context.Context, _ string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, 2, WithSQLDB(db, "sqlite"), WithSQLAutoCreate(), WithSQLPollInterval(20*time.Millisecond))
+	go q.Subscribe()
+	waitFor(t, func() bool { return Healthy("test_sqlite_close") == nil })
+
+	for i := 0; i < 5; i++ {
+		if err := q.Publish("job"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func(cond string, args ...any) int {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM xqueue_jobs WHERE topic = 'test_sqlite_close' AND "+cond, args...).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	waitFor(t, func() bool { return count("attempt = 1") == 5 })
+
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("attempt = 0 AND run_at <= ?", time.Now().UnixMilli()); n != 5 {
+		t.Fatalf("expected 5 released jobs, got %d", n)
+	}
+}
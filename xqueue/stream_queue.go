@@ -0,0 +1,469 @@
+package xqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/daodao97/xgo/xlog"
+	"github.com/daodao97/xgo/xmetrics"
+	"github.com/daodao97/xgo/xredis"
+	"github.com/redis/go-redis/v9"
+)
+
+// streamField 消息内容在 stream 条目中的字段名
+const streamField = "data"
+
+type StreamOption func(*StreamQueue)
+
+// WithStreamKey 设置 stream 的 key，默认 xqueue:{<topic>}，
+// 重试与死信使用以它为前缀的 key，集群模式下需要带 hash tag 以位于同一个 slot
+func WithStreamKey(key string) StreamOption {
+	return func(q *StreamQueue) {
+		q.stream = key
+	}
+}
+
+// WithGroup 设置消费组，同一组内每条消息只被一个消费者处理，默认 xqueue
+func WithGroup(group string) StreamOption {
+	return func(q *StreamQueue) {
+		q.group = group
+	}
+}
+
+// WithConsumer 设置消费者名称，默认为环境变量 POD_NAME 或 hostname，重启后保持不变可以先处理自己未确认的消息。
+// 同一主机上运行多个进程时需分别设置不同的名称
+func WithConsumer(consumer string) StreamOption {
+	return func(q *StreamQueue) {
+		q.consumer = consumer
+	}
+}
+
+// WithMaxLen 发布时近似裁剪 stream 长度，默认 100000，0 表示不裁剪
+func WithMaxLen(n int64) StreamOption {
+	return func(q *StreamQueue) {
+		q.maxLen = n
+	}
+}
+
+// WithClaimIdle 未确认超过该时长的消息会被 XAUTOCLAIM 转移给当前消费者重新处理，默认 1 分钟，
+// 需大于 handler 的最长执行时间
+func WithClaimIdle(d time.Duration) StreamOption {
+	return func(q *StreamQueue) {
+		q.claimIdle = d
+	}
+}
+
+// WithBlock XREADGROUP 的最长阻塞时间，默认 5s
+func WithBlock(d time.Duration) StreamOption {
+	return func(q *StreamQueue) {
+		q.block = d
+	}
+}
+
+// WithMaxAttempts 包含首次处理在内的最大尝试次数，超过后移入死信，默认 5
+func WithMaxAttempts(n int) StreamOption {
+	return func(q *StreamQueue) {
+		q.maxAttempts = n
+	}
+}
+
+// WithRetryBackoff 失败重试的间隔，第 n 次重试等待 min*2^(n-1)，不超过 max，默认 1s ~ 5m
+func WithRetryBackoff(min, max time.Duration) StreamOption {
+	return func(q *StreamQueue) {
+		q.minBackoff = min
+		q.maxBackoff = max
+	}
+}
+
+// AddStreamQueue 创建基于 Redis Streams 的可靠队列，与 AddQueue 用法相同。
+// 消息在 handler 成功返回后才 ACK，handler panic 时按 WithRetryBackoff 重试，
+// 进程退出时未确认的消息会在 claimIdle 之后被其他消费者通过 XAUTOCLAIM 认领重新处理，因此 handler 需要幂等
+//
+//	xqueue.AddStreamQueue(rdb, "order", handle, 4, xqueue.WithGroup("order-service"))
+func AddStreamQueue(rdb redis.UniversalClient, topic string, handler func(data string), workers int, opts ...StreamOption) Queue {
+	return AddStreamQueueE(rdb, topic, func(_ context.Context, data string) error {
+		handler(data)
+		return nil
+	}, workers, opts...)
+}
+
+// AddStreamQueueE 与 AddStreamQueue 相同，handler 返回错误时按指数退避重试，
+// 达到 WithMaxAttempts 后连同每次的错误移入死信，可通过 DeadLetters、ReplayDeadLetters 处理。
+// rdb 为 nil 时在首次使用时取 xredis.Get()
+func AddStreamQueueE(rdb redis.UniversalClient, topic string, handler func(ctx context.Context, data string) error, workers int, opts ...StreamOption) Queue {
+	queueLock.Lock()
+	defer queueLock.Unlock()
+	if q, ok := queueContainer[topic]; ok {
+		return q
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	queue := &StreamQueue{
+		redis:     rdb,
+		topic:     topic,
+		stream:    "xqueue:{" + topic + "}",
+		group:     "xqueue",
+		consumer:  defaultConsumer(),
+		maxLen:    100000,
+		claimIdle: time.Minute,
+		block:     5 * time.Second,
+		handler:   handler,
+		ctx:       ctx,
+		cancel:    cancel,
+		workers:   workers,
+		jobs:      make(chan redis.XMessage, workers),
+
+		maxAttempts: 5,
+		minBackoff:  time.Second,
+		maxBackoff:  5 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(queue)
+	}
+
+	queue.startWorkers()
+	queueContainer[topic] = queue
+	return queue
+}
+
+func defaultConsumer() string {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name
+	}
+	if host, _ := os.Hostname(); host != "" {
+		return host
+	}
+	return "xqueue"
+}
+
+type StreamQueue struct {
+	redis     redis.UniversalClient
+	topic     string
+	stream    string
+	group     string
+	consumer  string
+	maxLen    int64
+	claimIdle time.Duration
+	block     time.Duration
+	handler   func(ctx context.Context, data string) error
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	workers   int
+	jobs      chan redis.XMessage
+
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+
+	subscribed atomic.Bool
+
+	initMu sync.Mutex
+	ready  atomic.Bool
+}
+
+// errNoRedis 未指定客户端且 xredis 默认客户端尚未初始化
+var errNoRedis = errors.New("xqueue: redis client not initialized")
+
+// init 未指定客户端时在首次使用时取 xredis.Get()，Register 可以早于 xredis.Inits 调用
+func (q *StreamQueue) init() error {
+	if q.ready.Load() {
+		return nil
+	}
+	q.initMu.Lock()
+	defer q.initMu.Unlock()
+	if q.redis == nil {
+		q.redis = xredis.Get()
+	}
+	if q.redis == nil {
+		return errNoRedis
+	}
+	q.ready.Store(true)
+	return nil
+}
+
+func (q *StreamQueue) startWorkers() {
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go func(workerID int) {
+			defer q.wg.Done()
+			for {
+				select {
+				case <-q.ctx.Done():
+					xlog.Debug("worker stopping", xlog.String("topic", q.topic), xlog.Int("workerID", workerID))
+					return
+				case msg, ok := <-q.jobs:
+					if !ok {
+						return
+					}
+					q.handle(msg)
+				}
+			}
+		}(i)
+	}
+}
+
+func (q *StreamQueue) handle(msg redis.XMessage) {
+	data, _ := msg.Values[streamField].(string)
+	err := process(withAttempt(q.ctx, attemptOf(msg)), "redis_stream", q.topic, func(ctx context.Context) error {
+		return q.handler(ctx, data)
+	})
+	// 使用独立的 ctx，关闭队列时已处理完成的消息仍然能确认
+	ctx := context.Background()
+	if err == nil {
+		if err := q.redis.XAck(ctx, q.stream, q.group, msg.ID).Err(); err != nil {
+			xlog.Error("xqueue ack error", xlog.String("topic", q.topic), xlog.String("id", msg.ID), xlog.Any("error", err))
+		}
+		return
+	}
+	if q.ctx.Err() != nil {
+		// 关闭队列时被取消的消息不计入重试次数，保持未确认，重启后重新处理
+		return
+	}
+	if err := q.fail(ctx, msg, data, err); err != nil {
+		// 未确认的消息会在 claimIdle 之后重新投递
+		xlog.Error("xqueue retry error", xlog.String("topic", q.topic), xlog.String("id", msg.ID), xlog.Any("error", err))
+	}
+}
+
+func (q *StreamQueue) Publish(data string) error {
+	if err := q.init(); err != nil {
+		xmetrics.ObservePublish(q.topic, err)
+		return err
+	}
+	err := q.redis.XAdd(context.Background(), q.xaddArgs(q.stream, []any{streamField, data})).Err()
+	xmetrics.ObservePublish(q.topic, err)
+	return err
+}
+
+func (q *StreamQueue) xaddArgs(stream string, values []any) *redis.XAddArgs {
+	args := &redis.XAddArgs{Stream: stream, Values: values}
+	if q.maxLen > 0 {
+		args.MaxLen = q.maxLen
+		args.Approx = true
+	}
+	return args
+}
+
+// ensureGroup 创建消费组，从 stream 起点开始消费，保证创建组之前发布的消息也会被处理
+func (q *StreamQueue) ensureGroup(ctx context.Context) error {
+	err := q.redis.XGroupCreateMkStream(ctx, q.stream, q.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+func (q *StreamQueue) Subscribe() error {
+	xlog.Debug("start subscribe", xlog.String("topic", q.topic), xlog.String("stream", q.stream), xlog.Any("workers", q.workers))
+	if err := q.init(); err != nil {
+		return err
+	}
+	defer close(q.jobs)
+	defer q.subscribed.Store(false)
+
+	go q.moveLoop()
+
+	// 先从头读取本消费者上次退出时未确认的消息，读完后再读新消息
+	pendingID := "0"
+	grouped := false
+	var lastClaim time.Time
+	for q.ctx.Err() == nil {
+		msgs, err := q.read(&grouped, &lastClaim, pendingID)
+		if err != nil {
+			if q.ctx.Err() != nil {
+				return nil
+			}
+			q.subscribed.Store(false)
+			xlog.Warn("xqueue read error", xlog.String("topic", q.topic), xlog.Any("error", err))
+			// stream 被删除后消费组也随之消失，需要重新创建
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				grouped = false
+			}
+			select {
+			case <-q.ctx.Done():
+				return nil
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		q.subscribed.Store(true)
+
+		if pendingID != ">" {
+			if len(msgs) == 0 {
+				pendingID = ">"
+			} else {
+				pendingID = msgs[len(msgs)-1].ID
+			}
+		}
+		if !q.dispatch(msgs) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (q *StreamQueue) read(grouped *bool, lastClaim *time.Time, id string) ([]redis.XMessage, error) {
+	if !*grouped {
+		if err := q.ensureGroup(q.ctx); err != nil {
+			return nil, err
+		}
+		*grouped = true
+	}
+	if time.Since(*lastClaim) >= q.claimIdle/2 {
+		*lastClaim = time.Now()
+		if err := q.claim(); err != nil {
+			return nil, err
+		}
+	}
+	streams, err := q.redis.XReadGroup(q.ctx, &redis.XReadGroupArgs{
+		Group:    q.group,
+		Consumer: q.consumer,
+		Streams:  []string{q.stream, id},
+		Count:    int64(q.workers),
+		Block:    q.block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(streams) == 0 {
+		return nil, nil
+	}
+	return streams[0].Messages, nil
+}
+
+// claim 认领其他消费者超时未确认的消息
+func (q *StreamQueue) claim() error {
+	start := "0-0"
+	for {
+		msgs, next, err := q.redis.XAutoClaim(q.ctx, &redis.XAutoClaimArgs{
+			Stream:   q.stream,
+			Group:    q.group,
+			Consumer: q.consumer,
+			MinIdle:  q.claimIdle,
+			Start:    start,
+			Count:    int64(q.workers),
+		}).Result()
+		if err != nil {
+			return err
+		}
+		if len(msgs) > 0 {
+			xlog.Info("xqueue claimed pending messages", xlog.String("topic", q.topic), xlog.Int("count", len(msgs)))
+		}
+		if !q.dispatch(msgs) {
+			return nil
+		}
+		if next == "0-0" || next == "" {
+			q.removeIdleConsumers()
+			return nil
+		}
+		start = next
+	}
+}
+
+// removeIdleConsumers 删除没有未确认消息且空闲超过 claimIdle 的其他消费者，避免实例更换名称后残留在消费组中
+func (q *StreamQueue) removeIdleConsumers() {
+	consumers, err := q.consumers()
+	if err != nil {
+		xlog.Warn("xqueue list consumers error", xlog.String("topic", q.topic), xlog.Any("error", err))
+		return
+	}
+	for _, c := range consumers {
+		if c.name == q.consumer || c.pending > 0 || c.idle < q.claimIdle {
+			continue
+		}
+		if err := q.redis.XGroupDelConsumer(q.ctx, q.stream, q.group, c.name).Err(); err != nil {
+			xlog.Warn("xqueue remove consumer error", xlog.String("topic", q.topic), xlog.String("consumer", c.name), xlog.Any("error", err))
+			continue
+		}
+		xlog.Info("xqueue removed idle consumer", xlog.String("topic", q.topic), xlog.String("consumer", c.name))
+	}
+}
+
+type streamConsumer struct {
+	name    string
+	pending int64
+	idle    time.Duration
+}
+
+// consumers 执行 XINFO CONSUMERS，兼容 RESP2、RESP3 以及 Redis 7.2 新增的 inactive 字段
+func (q *StreamQueue) consumers() ([]streamConsumer, error) {
+	items, err := q.redis.Do(q.ctx, "XINFO", "CONSUMERS", q.stream, q.group).Slice()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]streamConsumer, 0, len(items))
+	for _, item := range items {
+		fields := map[string]any{}
+		switch v := item.(type) {
+		case map[any]any:
+			for k, val := range v {
+				fields[fmt.Sprint(k)] = val
+			}
+		case []any:
+			for i := 0; i+1 < len(v); i += 2 {
+				fields[fmt.Sprint(v[i])] = v[i+1]
+			}
+		}
+		c := streamConsumer{}
+		c.name, _ = fields["name"].(string)
+		c.pending, _ = fields["pending"].(int64)
+		idle, _ := fields["idle"].(int64)
+		c.idle = time.Duration(idle) * time.Millisecond
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (q *StreamQueue) dispatch(msgs []redis.XMessage) bool {
+	for _, msg := range msgs {
+		select {
+		case <-q.ctx.Done():
+			return false
+		case q.jobs <- msg:
+		}
+	}
+	return true
+}
+
+// Healthy 消费组未建立或读取出错时返回错误
+func (q *StreamQueue) Healthy() error {
+	if !q.subscribed.Load() {
+		return errors.New("queue not subscribed: " + q.topic)
+	}
+	return nil
+}
+
+// Pending 返回消费组中已投递但未确认的消息数
+func (q *StreamQueue) Pending(ctx context.Context) (int64, error) {
+	if err := q.init(); err != nil {
+		return 0, err
+	}
+	p, err := q.redis.XPending(ctx, q.stream, q.group).Result()
+	if err != nil {
+		return 0, err
+	}
+	return p.Count, nil
+}
+
+func (q *StreamQueue) Close() error {
+	q.cancel()
+	q.wg.Wait()
+	xlog.Debug("all messages processed", xlog.String("topic", q.topic))
+
+	queueLock.Lock()
+	delete(queueContainer, q.topic)
+	queueLock.Unlock()
+	return nil
+}
@@ -0,0 +1,273 @@
+package xqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// testRedis 连接本地 redis，不可用时跳过
+func testRedis(t *testing.T) redis.UniversalClient {
+	t.Helper()
+	c := redis.NewClient(&redis.Options{Addr: "localhost:6379", DialTimeout: 200 * time.Millisecond})
+	if err := c.Ping(context.Background()).Err(); err != nil {
+		_ = c.Close()
+		t.Skipf("redis not available: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestStreamQueueUnavailable(t *testing.T) {
+	c := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer c.Close()
+
+	q := AddStreamQueue(c, "test_stream_down", func(string) {}, 1)
+	if GetQueue("test_stream_down") != q {
+		t.Fatal("queue should be registered")
+	}
+	if err := q.Publish("x"); err == nil {
+		t.Fatal("expected publish error")
+	}
+	go q.Subscribe()
+	time.Sleep(100 * time.Millisecond)
+	if err := Healthy("test_stream_down"); err == nil {
+		t.Fatal("expected unhealthy queue")
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if GetQueue("test_stream_down") != nil {
+		t.Fatal("queue should be removed after close")
+	}
+}
+
+func TestStreamQueue(t *testing.T) {
+	c := testRedis(t)
+	ctx := context.Background()
+	c.Del(ctx, "xqueue:{test_stream}")
+
+	var calls, done atomic.Int32
+	q := AddStreamQueue(c, "test_stream", func(data string) {
+		// 第一次处理 panic，按退避时间重试
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+		done.Add(1)
+	}, 2, WithClaimIdle(200*time.Millisecond), WithBlock(50*time.Millisecond), WithRetryBackoff(10*time.Millisecond, 10*time.Millisecond))
+
+	// 订阅之前发布的消息不会丢失
+	if err := q.Publish("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Publish("b"); err != nil {
+		t.Fatal(err)
+	}
+	go q.Subscribe()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for done.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if done.Load() != 2 {
+		t.Fatalf("expected 2 messages handled, got %d", done.Load())
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n, err := q.(*StreamQueue).Pending(ctx); err != nil || n != 0 {
+		t.Fatalf("expected no pending messages, got %d %v", n, err)
+	}
+	_ = q.Close()
+	c.Del(ctx, "xqueue:{test_stream}")
+}
+
+func TestDefaultConsumerIsStable(t *testing.T) {
+	t.Setenv("POD_NAME", "worker-0")
+	if got := defaultConsumer(); got != "worker-0" {
+		t.Fatalf("unexpected consumer %q", got)
+	}
+	t.Setenv("POD_NAME", "")
+	if defaultConsumer() != defaultConsumer() {
+		t.Fatal("default consumer should not change between calls")
+	}
+}
+
+func TestStreamQueueRemovesIdleConsumers(t *testing.T) {
+	c := testRedis(t)
+	ctx := context.Background()
+	stream := "xqueue:{test_idle_consumer}"
+	c.Del(ctx, stream)
+	defer c.Del(ctx, stream)
+
+	q := AddStreamQueue(c, "test_idle_consumer", func(string) {}, 1, WithConsumer("live"), WithClaimIdle(50*time.Millisecond))
+	defer q.Close()
+	sq := q.(*StreamQueue)
+	hasStale := func() bool {
+		consumers, err := sq.consumers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, consumer := range consumers {
+			if consumer.name == "stale" {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 之前的实例留下的消费者，没有未确认的消息
+	if err := c.XGroupCreateMkStream(ctx, stream, "xqueue", "$").Err(); err != nil {
+		t.Fatal(err)
+	}
+	id := c.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]any{"data": "x"}}).Val()
+	c.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "xqueue", Consumer: "stale", Streams: []string{stream, ">"}, Count: 1, Block: -1})
+	c.XClaim(ctx, &redis.XClaimArgs{Stream: stream, Group: "xqueue", Consumer: "stale", Messages: []string{id}})
+	c.XAck(ctx, stream, "xqueue", id)
+	if !hasStale() {
+		t.Fatal("expected stale consumer to exist")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	go q.Subscribe()
+	waitFor(t, func() bool { return !hasStale() })
+}
+
+func TestStreamQueueBackoff(t *testing.T) {
+	q := &StreamQueue{minBackoff: time.Second, maxBackoff: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 100: 5 * time.Second} {
+		if got := q.backoff(attempt); got != want {
+			t.Fatalf("attempt %d: expected %s, got %s", attempt, want, got)
+		}
+	}
+}
+
+func TestDeadLettersUnsupported(t *testing.T) {
+	if _, err := ListDeadLetters(context.Background(), "not_exists", "", 10); err == nil {
+		t.Fatal("expected queue not found error")
+	}
+	q := AddQueue(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"}), "test_pubsub_dead", func(string) {}, 1)
+	defer q.Close()
+	if _, err := ReplayDeadLetters(context.Background(), "test_pubsub_dead"); err == nil {
+		t.Fatal("pub/sub queue should not support dead letters")
+	}
+}
+
+func TestStreamQueueDeadLetter(t *testing.T) {
+	c := testRedis(t)
+	ctx := context.Background()
+	keys := []string{"xqueue:{test_dead}", "xqueue:{test_dead}:dead", "xqueue:{test_dead}:delayed", "xqueue:{test_dead}:delayed:data"}
+	c.Del(ctx, keys...)
+	defer c.Del(ctx, keys...)
+
+	var fail atomic.Bool
+	fail.Store(true)
+	var calls, done atomic.Int32
+	q := AddStreamQueueE(c, "test_dead", func(_ context.Context, data string) error {
+		calls.Add(1)
+		if fail.Load() {
+			return errors.New("bad " + data)
+		}
+		done.Add(1)
+		return nil
+	}, 1, WithBlock(50*time.Millisecond), WithMaxAttempts(2), WithRetryBackoff(10*time.Millisecond, 10*time.Millisecond))
+	defer q.Close()
+	go q.Subscribe()
+
+	if err := q.Publish("x"); err != nil {
+		t.Fatal(err)
+	}
+	var list []DeadLetter
+	deadline := time.Now().Add(5 * time.Second)
+	for len(list) == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		list, _ = ListDeadLetters(ctx, "test_dead", "", 10)
+	}
+	if len(list) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(list))
+	}
+	d := list[0]
+	if d.Data != "x" || d.Attempts != 2 || d.Error != "bad x" || len(d.History) != 2 || calls.Load() != 2 {
+		t.Fatalf("unexpected dead letter %+v", d)
+	}
+	if got, err := InspectDeadLetter(ctx, "test_dead", d.ID); err != nil || got.OriginID != d.OriginID {
+		t.Fatalf("unexpected inspect %+v %v", got, err)
+	}
+
+	fail.Store(false)
+	if n, err := ReplayDeadLetters(ctx, "test_dead"); err != nil || n != 1 {
+		t.Fatalf("unexpected replay %d %v", n, err)
+	}
+	deadline = time.Now().Add(3 * time.Second)
+	for done.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if done.Load() != 1 {
+		t.Fatal("replayed message should be handled")
+	}
+	if _, err := InspectDeadLetter(ctx, "test_dead", d.ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Fatalf("expected ErrDeadLetterNotFound, got %v", err)
+	}
+	if n, err := PurgeDeadLetters(ctx, "test_dead"); err != nil || n != 0 {
+		t.Fatalf("unexpected purge %d %v", n, err)
+	}
+}
+
+func TestStreamQueueDelayed(t *testing.T) {
+	c := testRedis(t)
+	ctx := context.Background()
+	keys := []string{"xqueue:{test_delayed}", "xqueue:{test_delayed}:delayed", "xqueue:{test_delayed}:delayed:data"}
+	c.Del(ctx, keys...)
+	defer c.Del(ctx, keys...)
+
+	q := AddStreamQueue(c, "test_delayed", func(string) {}, 1).(*StreamQueue)
+	defer q.Close()
+
+	for i := 0; i < 20; i++ {
+		if _, err := PublishAt("test_delayed", "due", time.Now().Add(-time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	later, err := PublishDelayed("test_delayed", "later", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	canceled, err := PublishDelayed("test_delayed", "canceled", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := Cancel("test_delayed", canceled); err != nil || !ok {
+		t.Fatalf("expected cancel, got %v %v", ok, err)
+	}
+	if ok, _ := Cancel("test_delayed", canceled); ok {
+		t.Fatal("second cancel should return false")
+	}
+
+	// 多个实例同时搬运，每条到期消息只投递一次
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = q.moveDue(ctx)
+		}()
+	}
+	wg.Wait()
+	if n, _ := c.XLen(ctx, "xqueue:{test_delayed}").Result(); n != 20 {
+		t.Fatalf("expected 20 messages promoted, got %d", n)
+	}
+	if ok, _ := Cancel("test_delayed", later); !ok {
+		t.Fatal("pending delayed message should be cancelable")
+	}
+}
+
+func TestDelayedUnsupported(t *testing.T) {
+	q := AddQueue(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"}), "test_pubsub_delayed", func(string) {}, 1)
+	defer q.Close()
+	if _, err := PublishDelayed("test_pubsub_delayed", "x", time.Second); err == nil {
+		t.Fatal("pub/sub queue should not support delayed messages")
+	}
+}
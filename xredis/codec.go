@@ -0,0 +1,48 @@
+package xredis
+
+import (
+	"encoding/json"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Codec 类型化结构的序列化方式
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	// JSONCodec 默认的编码，便于在 redis-cli 中查看
+	JSONCodec Codec = jsonCodec{}
+	// MsgpackCodec 体积更小，兼容 codec 与 json 标签
+	MsgpackCodec Codec = newMsgpackCodec()
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type msgpackCodec struct {
+	h *codec.MsgpackHandle
+}
+
+func newMsgpackCodec() msgpackCodec {
+	h := &codec.MsgpackHandle{WriteExt: true}
+	h.RawToString = true
+	// Set、SortedSet 以编码结果作为成员，map 需要按 key 排序保证相同的值编码一致
+	h.Canonical = true
+	return msgpackCodec{h: h}
+}
+
+func (c msgpackCodec) Marshal(v any) ([]byte, error) {
+	var b []byte
+	err := codec.NewEncoderBytes(&b, c.h).Encode(v)
+	return b, err
+}
+
+func (c msgpackCodec) Unmarshal(data []byte, v any) error {
+	return codec.NewDecoderBytes(data, c.h).Decode(v)
+}
@@ -18,25 +18,14 @@ func (r Counter) key(k string) string {
 	return fmt.Sprintf("counter:%s:%s", r.Prefix, k)
 }
 
+// Incr 计数加一，首次计数时设置过期时间，redis 出错时返回 false
 func (r Counter) Incr(ctx context.Context, key string) bool {
-	_key := r.key(key)
-	val, err := client.Get(ctx, _key).Int()
-	if err != nil && !errors.Is(err, redis.Nil) {
-		return true
-	}
-
-	if val == 0 {
-		client.Set(ctx, _key, 1, r.Period)
-	} else {
-		client.Incr(ctx, _key)
-	}
-
-	return true
+	return incrExpire(ctx, r.key(key), 1, r.Period) == nil
 }
 
 func (r Counter) Get(ctx context.Context, key string) int64 {
 	redisKey := r.key(key)
-	i, err := client.Get(ctx, redisKey).Int64()
+	i, err := Get().Get(ctx, redisKey).Int64()
 	if err != nil && !errors.Is(err, redis.Nil) {
 		return 0
 	}
@@ -46,5 +35,5 @@ func (r Counter) Get(ctx context.Context, key string) int64 {
 // GetRemainingTime 获取剩余的限制时间
 func (r Counter) GetRemainingTime(ctx context.Context, key string) time.Duration {
 	redisKey := r.key(key)
-	return client.TTL(ctx, redisKey).Val()
+	return Get().TTL(ctx, redisKey).Val()
 }
@@ -0,0 +1,331 @@
+package xredis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/daodao97/xgo/xmetrics"
+)
+
+// Algorithm 限流算法
+type Algorithm int
+
+const (
+	// FixedWindow 固定窗口计数，窗口边界处最多允许 2 倍突发
+	FixedWindow Algorithm = iota
+	// SlidingLog 滑动日志，精确但每个请求占用一个 zset 成员
+	SlidingLog
+	// TokenBucket 令牌桶，按速率补充令牌，允许 Burst 大小的突发
+	TokenBucket
+	// GCRA 通用信元速率算法，效果与令牌桶相同，只需保存一个时间戳
+	GCRA
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case FixedWindow:
+		return "fixed_window"
+	case SlidingLog:
+		return "sliding_log"
+	case TokenBucket:
+		return "token_bucket"
+	case GCRA:
+		return "gcra"
+	default:
+		return fmt.Sprintf("algorithm(%d)", int(a))
+	}
+}
+
+// Limit 每 Period 允许 Rate 次，Burst 为令牌桶与 GCRA 的突发容量，默认等于 Rate
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+func PerSecond(rate int) Limit { return Limit{Rate: rate, Period: time.Second} }
+
+func PerMinute(rate int) Limit { return Limit{Rate: rate, Period: time.Minute} }
+
+func PerHour(rate int) Limit { return Limit{Rate: rate, Period: time.Hour} }
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Rate
+}
+
+// LimitResult 一次限流判断的结果
+type LimitResult struct {
+	Allowed bool
+	// Limit 窗口内（或桶）的容量
+	Limit int
+	// Remaining 剩余可用次数
+	Remaining int
+	// ResetAfter 多久后恢复到满额
+	ResetAfter time.Duration
+	// RetryAfter 被拒绝时多久后可以重试，为 -1 表示请求数超过容量，永远不会被允许
+	RetryAfter time.Duration
+}
+
+type Limiter struct {
+	algorithm Algorithm
+	limit     Limit
+	prefix    string
+	name      string
+	client    redis.UniversalClient
+	failOpen  bool
+}
+
+type LimiterOption func(*Limiter)
+
+// WithLimiterPrefix 设置 key 前缀，同时作为指标中的 limiter 名称
+func WithLimiterPrefix(prefix string) LimiterOption {
+	return func(l *Limiter) {
+		l.prefix = prefix
+	}
+}
+
+// WithLimiterClient 指定 redis 客户端，默认使用 Get() 返回的默认客户端
+func WithLimiterClient(client redis.UniversalClient) LimiterOption {
+	return func(l *Limiter) {
+		l.client = client
+	}
+}
+
+// WithFailOpen redis 出错时是否放行，默认 true；为 false 时出错即拒绝
+func WithFailOpen(failOpen bool) LimiterOption {
+	return func(l *Limiter) {
+		l.failOpen = failOpen
+	}
+}
+
+// NewLimiter 创建基于 Lua 脚本的限流器，判断在 redis 中原子完成，时间取自 redis 服务端
+//
+//	l := xredis.NewLimiter(xredis.GCRA, xredis.PerSecond(10), xredis.WithLimiterPrefix("api"))
+//	res, err := l.Allow(ctx, userID)
+func NewLimiter(algorithm Algorithm, limit Limit, opts ...LimiterOption) *Limiter {
+	l := &Limiter{
+		algorithm: algorithm,
+		limit:     limit,
+		failOpen:  true,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.name = l.prefix
+	if l.name == "" {
+		l.name = algorithm.String()
+	}
+	return l
+}
+
+func (l *Limiter) key(k string) string {
+	return fmt.Sprintf("ratelimit:%s:%s:%s", l.algorithm, l.prefix, k)
+}
+
+func (l *Limiter) redis() redis.UniversalClient {
+	if l.client != nil {
+		return l.client
+	}
+	return Get()
+}
+
+// Allow 消耗一次配额
+func (l *Limiter) Allow(ctx context.Context, key string) (*LimitResult, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN 消耗 n 次配额，不足时不消耗。redis 出错时按 WithFailOpen 决定 Allowed 并返回错误
+func (l *Limiter) AllowN(ctx context.Context, key string, n int) (*LimitResult, error) {
+	res, err := l.allowN(ctx, key, n)
+	if err != nil {
+		res = &LimitResult{Allowed: l.failOpen, Limit: l.capacity()}
+	}
+	xmetrics.ObserveLimiter(l.name, res.Allowed)
+	return res, err
+}
+
+// Reset 清除 key 的限流状态
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	c := l.redis()
+	if c == nil {
+		return errors.New("xredis: limiter client not initialized")
+	}
+	return c.Del(ctx, l.key(key)).Err()
+}
+
+func (l *Limiter) capacity() int {
+	if l.algorithm == TokenBucket || l.algorithm == GCRA {
+		return l.limit.burst()
+	}
+	return l.limit.Rate
+}
+
+func (l *Limiter) allowN(ctx context.Context, key string, n int) (*LimitResult, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("xredis: invalid n %d", n)
+	}
+	if l.limit.Rate <= 0 || l.limit.Period <= 0 {
+		return nil, fmt.Errorf("xredis: invalid limit %+v", l.limit)
+	}
+	c := l.redis()
+	if c == nil {
+		return nil, errors.New("xredis: limiter client not initialized")
+	}
+	capacity := l.capacity()
+	if n > capacity {
+		return &LimitResult{Limit: capacity, RetryAfter: -1}, nil
+	}
+
+	periodUs := l.limit.Period.Microseconds()
+	keys := []string{l.key(key)}
+	var (
+		vals []int64
+		err  error
+	)
+	switch l.algorithm {
+	case FixedWindow:
+		vals, err = fixedWindowScript.Run(ctx, c, keys, l.limit.Rate, l.limit.Period.Milliseconds(), n).Int64Slice()
+	case SlidingLog:
+		vals, err = slidingLogScript.Run(ctx, c, keys, l.limit.Rate, periodUs, n, randomMember()).Int64Slice()
+	case TokenBucket:
+		vals, err = tokenBucketScript.Run(ctx, c, keys, capacity, float64(periodUs)/float64(l.limit.Rate), n).Int64Slice()
+	case GCRA:
+		vals, err = gcraScript.Run(ctx, c, keys, capacity, float64(periodUs)/float64(l.limit.Rate), n).Int64Slice()
+	default:
+		return nil, fmt.Errorf("xredis: unknown algorithm %d", l.algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) != 4 {
+		return nil, fmt.Errorf("xredis: unexpected limiter reply %v", vals)
+	}
+	res := &LimitResult{
+		Allowed:    vals[0] == 1,
+		Limit:      capacity,
+		Remaining:  int(max(vals[1], 0)),
+		ResetAfter: time.Duration(vals[2]) * time.Millisecond,
+	}
+	if !res.Allowed {
+		res.RetryAfter = time.Duration(vals[3]) * time.Millisecond
+	}
+	return res, nil
+}
+
+func randomMember() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// 以下脚本均返回 {allowed, remaining, reset_after_ms, retry_after_ms}，
+// 时间取自 redis TIME，单位为微秒，避免各实例时钟不一致；
+// 时间戳传给 redis.call 前用 string.format 转换，默认的数字转换只保留 14 位有效数字
+
+// ARGV: limit, window_ms, n
+var fixedWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	ttl = window
+end
+if current + n > limit then
+	return {0, limit - current, ttl, ttl}
+end
+current = redis.call('INCRBY', KEYS[1], n)
+if current == n or redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], window)
+	ttl = window
+end
+return {1, limit - current, ttl, 0}
+`)
+
+// ARGV: limit, window_us, n, member
+var slidingLogScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', string.format('%.0f', now - window))
+local count = redis.call('ZCARD', KEYS[1])
+if count + n > limit then
+	-- 需要等到第 count+n-limit 个最早的记录过期
+	local e = redis.call('ZRANGE', KEYS[1], count + n - limit - 1, count + n - limit - 1, 'WITHSCORES')
+	local retry = math.ceil((tonumber(e[2]) + window - now) / 1000)
+	local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+	local reset = math.ceil((tonumber(last[2]) + window - now) / 1000)
+	return {0, limit - count, reset, retry}
+end
+for i = 1, n do
+	redis.call('ZADD', KEYS[1], string.format('%.0f', now), ARGV[4] .. ':' .. i)
+end
+redis.call('PEXPIRE', KEYS[1], math.ceil(window / 1000))
+return {1, limit - count - n, math.ceil(window / 1000), 0}
+`)
+
+// ARGV: capacity, interval_us（每个令牌的补充间隔）, n
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) / interval)
+end
+local allowed = 0
+local retry = 0
+if tokens >= n then
+	tokens = tokens - n
+	allowed = 1
+else
+	retry = math.ceil((n - tokens) * interval / 1000)
+end
+local reset = math.ceil((capacity - tokens) * interval / 1000)
+redis.call('HSET', KEYS[1], 'tokens', string.format('%.6f', tokens), 'ts', string.format('%.0f', now))
+redis.call('PEXPIRE', KEYS[1], math.max(reset, 1))
+return {allowed, math.floor(tokens), reset, retry}
+`)
+
+// ARGV: burst, interval_us（emission interval）, n
+var gcraScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local tolerance = interval * burst
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if tat == nil or tat < now then
+	tat = now
+end
+local new_tat = tat + interval * n
+local diff = now - (new_tat - tolerance)
+if diff < 0 then
+	local remaining = math.floor((now - (tat - tolerance)) / interval)
+	return {0, remaining, math.ceil((tat - now) / 1000), math.ceil(-diff / 1000)}
+end
+local reset = math.ceil((new_tat - now) / 1000)
+redis.call('SET', KEYS[1], string.format('%.0f', new_tat), 'PX', math.max(reset, 1))
+return {1, math.floor(diff / interval), reset, 0}
+`)
@@ -0,0 +1,76 @@
+package xredis
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type rateLimitMiddleware struct {
+	key    func(c *gin.Context) string
+	reject func(c *gin.Context, res *LimitResult)
+}
+
+type RateLimitMiddlewareOption func(*rateLimitMiddleware)
+
+// WithRateLimitKey 设置限流维度，默认按客户端 IP
+func WithRateLimitKey(fn func(c *gin.Context) string) RateLimitMiddlewareOption {
+	return func(m *rateLimitMiddleware) {
+		m.key = fn
+	}
+}
+
+// WithRateLimitReject 自定义被拒绝时的响应，默认返回 429
+func WithRateLimitReject(fn func(c *gin.Context, res *LimitResult)) RateLimitMiddlewareOption {
+	return func(m *rateLimitMiddleware) {
+		m.reject = fn
+	}
+}
+
+// GinRateLimit 限流中间件，设置 X-RateLimit-Limit、X-RateLimit-Remaining、X-RateLimit-Reset（秒），
+// 拒绝时设置 Retry-After（秒）。redis 出错时按 Limiter 的 WithFailOpen 放行或拒绝，错误记录到 c.Errors
+//
+//	r.Use(xredis.GinRateLimit(xredis.NewLimiter(xredis.GCRA, xredis.PerSecond(10))))
+func GinRateLimit(l *Limiter, opts ...RateLimitMiddlewareOption) gin.HandlerFunc {
+	m := &rateLimitMiddleware{
+		key: func(c *gin.Context) string {
+			return c.ClientIP()
+		},
+		reject: func(c *gin.Context, res *LimitResult) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"code":    http.StatusTooManyRequests,
+				"message": "too many requests",
+			})
+		},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return func(c *gin.Context) {
+		res, err := l.Allow(c.Request.Context(), m.key(c))
+		if err != nil {
+			_ = c.Error(err)
+		} else {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			c.Header("X-RateLimit-Reset", seconds(res.ResetAfter))
+			if !res.Allowed && res.RetryAfter >= 0 {
+				c.Header("Retry-After", seconds(res.RetryAfter))
+			}
+		}
+		if !res.Allowed {
+			m.reject(c, res)
+			return
+		}
+		c.Next()
+	}
+}
+
+// seconds 向上取整，避免客户端在配额恢复前重试
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,131 @@
+package xredis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// testRedis 连接本地 redis，不可用时跳过
+func testRedis(t *testing.T) redis.UniversalClient {
+	t.Helper()
+	c := redis.NewClient(&redis.Options{Addr: "localhost:6379", DialTimeout: 200 * time.Millisecond})
+	if err := c.Ping(context.Background()).Err(); err != nil {
+		_ = c.Close()
+		t.Skipf("redis not available: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func unreachableRedis(t *testing.T) redis.UniversalClient {
+	c := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestLimiterAlgorithms(t *testing.T) {
+	c := testRedis(t)
+	ctx := context.Background()
+	for _, alg := range []Algorithm{FixedWindow, SlidingLog, TokenBucket, GCRA} {
+		t.Run(alg.String(), func(t *testing.T) {
+			l := NewLimiter(alg, PerMinute(3), WithLimiterClient(c), WithLimiterPrefix("test"))
+			_ = l.Reset(ctx, "user")
+			defer l.Reset(ctx, "user")
+
+			for i := 2; i >= 0; i-- {
+				res, err := l.Allow(ctx, "user")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !res.Allowed || res.Remaining != i || res.Limit != 3 {
+					t.Fatalf("request %d: unexpected result %+v", 3-i, res)
+				}
+			}
+			res, err := l.Allow(ctx, "user")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > time.Minute || res.ResetAfter <= 0 {
+				t.Fatalf("expected rejection, got %+v", res)
+			}
+		})
+	}
+}
+
+func TestLimiterFailOpen(t *testing.T) {
+	ctx := context.Background()
+	c := unreachableRedis(t)
+
+	res, err := NewLimiter(GCRA, PerSecond(1), WithLimiterClient(c)).Allow(ctx, "k")
+	if err == nil || !res.Allowed {
+		t.Fatalf("fail open should allow with error, got %+v %v", res, err)
+	}
+	res, err = NewLimiter(GCRA, PerSecond(1), WithLimiterClient(c), WithFailOpen(false)).Allow(ctx, "k")
+	if err == nil || res.Allowed {
+		t.Fatalf("fail closed should reject with error, got %+v %v", res, err)
+	}
+
+	res, err = NewLimiter(TokenBucket, Limit{Rate: 1, Period: time.Second, Burst: 2}, WithLimiterClient(c)).AllowN(ctx, "k", 3)
+	if err != nil || res.Allowed || res.RetryAfter != -1 {
+		t.Fatalf("n over burst should never be allowed, got %+v %v", res, err)
+	}
+}
+
+func TestGinRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c := unreachableRedis(t)
+
+	for _, failOpen := range []bool{true, false} {
+		r := gin.New()
+		r.Use(GinRateLimit(NewLimiter(FixedWindow, PerSecond(1), WithLimiterClient(c), WithFailOpen(failOpen))))
+		r.GET("/", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		want := http.StatusOK
+		if !failOpen {
+			want = http.StatusTooManyRequests
+		}
+		if w.Code != want {
+			t.Fatalf("failOpen=%v: expected %d, got %d", failOpen, want, w.Code)
+		}
+	}
+}
+
+func TestGinRateLimitHeaders(t *testing.T) {
+	rdb := testRedis(t)
+	gin.SetMode(gin.TestMode)
+	l := NewLimiter(FixedWindow, PerMinute(1), WithLimiterClient(rdb), WithLimiterPrefix("test_gin"))
+	_ = l.Reset(context.Background(), "192.0.2.1")
+	defer l.Reset(context.Background(), "192.0.2.1")
+
+	r := gin.New()
+	r.Use(GinRateLimit(l))
+	r.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	w := do()
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "1" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("unexpected first response %d %v", w.Code, w.Header())
+	}
+	w = do()
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("unexpected second response %d %v", w.Code, w.Header())
+	}
+}
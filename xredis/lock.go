@@ -0,0 +1,276 @@
+package xredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrLockNotAcquired 锁已被其他持有者占用
+	ErrLockNotAcquired = errors.New("xredis: lock not acquired")
+	// ErrLockNotHeld 锁已过期或被其他持有者获取
+	ErrLockNotHeld = errors.New("xredis: lock not held")
+)
+
+type lockOptions struct {
+	ttl        time.Duration
+	autoRenew  bool
+	block      bool
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	client     redis.UniversalClient
+	token      string
+}
+
+type LockOption func(*lockOptions)
+
+// WithLockTTL 锁的租期，默认 30s
+func WithLockTTL(ttl time.Duration) LockOption {
+	return func(o *lockOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithLockAutoRenew 是否在后台每 ttl/3 自动续期，默认开启
+func WithLockAutoRenew(autoRenew bool) LockOption {
+	return func(o *lockOptions) {
+		o.autoRenew = autoRenew
+	}
+}
+
+// WithLockBlock 阻塞等待直到获取锁或 ctx 结束，默认只尝试一次
+func WithLockBlock() LockOption {
+	return func(o *lockOptions) {
+		o.block = true
+	}
+}
+
+// WithLockBackoff 阻塞等待时的重试间隔，按指数退避，默认 50ms ~ 1s
+func WithLockBackoff(min, max time.Duration) LockOption {
+	return func(o *lockOptions) {
+		o.minBackoff = min
+		o.maxBackoff = max
+	}
+}
+
+// WithLockClient 指定 redis 客户端，默认使用 Get() 返回的默认客户端
+func WithLockClient(client redis.UniversalClient) LockOption {
+	return func(o *lockOptions) {
+		o.client = client
+	}
+}
+
+// WithLockToken 指定持有者 token，默认随机生成。
+// 持有者再次以相同 token 获取同一个 key 时重入，持有计数加一，fencing token 不变，
+// 每次获取都需要对应一次 Unlock，计数归零时才释放锁
+//
+//	outer, _ := xredis.Lock(ctx, "order:1")
+//	inner, _ := xredis.Lock(ctx, "order:1", xredis.WithLockToken(outer.Token()))
+func WithLockToken(token string) LockOption {
+	return func(o *lockOptions) {
+		o.token = token
+	}
+}
+
+// Mutex 已获取的分布式锁
+type Mutex struct {
+	client redis.UniversalClient
+	key    string
+	token  string
+	fence  int64
+	ttl    time.Duration
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	renewed  chan struct{}
+	lostOnce sync.Once
+	lost     chan struct{}
+}
+
+// Lock 获取分布式锁，锁是以持有者 token 为 field、持有计数为值的 hash，只有持有者能续期和释放。
+// 每次获取成功都会通过 INCR 得到单调递增的 fencing token，写入外部存储时带上它，
+// 可以拒绝过期持有者的写入，例如 UPDATE ... WHERE fence < ?
+//
+//	m, err := xredis.Lock(ctx, "order:1", xredis.WithLockBlock())
+//	if err != nil {
+//		return err
+//	}
+//	defer m.Unlock(context.Background())
+func Lock(ctx context.Context, key string, opts ...LockOption) (*Mutex, error) {
+	o := &lockOptions{
+		ttl:        30 * time.Second,
+		autoRenew:  true,
+		minBackoff: 50 * time.Millisecond,
+		maxBackoff: time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	c := o.client
+	if c == nil {
+		c = Get()
+	}
+	if c == nil {
+		return nil, errors.New("xredis: lock client not initialized")
+	}
+	if o.ttl < time.Millisecond {
+		return nil, fmt.Errorf("xredis: invalid lock ttl %s", o.ttl)
+	}
+	if o.token == "" {
+		o.token = randomMember() + randomMember()
+	}
+
+	m := &Mutex{
+		client: c,
+		// 使用 hash tag 使锁与 fencing 计数在集群中位于同一个 slot
+		key:     "xlock:{" + key + "}",
+		token:   o.token,
+		ttl:     o.ttl,
+		stop:    make(chan struct{}),
+		renewed: make(chan struct{}),
+		lost:    make(chan struct{}),
+	}
+
+	backoff := o.minBackoff
+	for {
+		fence, err := lockAcquireScript.Run(ctx, c, []string{m.key, m.key + ":fence"}, m.token, o.ttl.Milliseconds()).Int64()
+		if err != nil {
+			return nil, err
+		}
+		if fence > 0 {
+			m.fence = fence
+			break
+		}
+		if !o.block {
+			return nil, ErrLockNotAcquired
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, o.maxBackoff)
+	}
+
+	if o.autoRenew {
+		go m.renewLoop()
+	} else {
+		close(m.renewed)
+	}
+	return m, nil
+}
+
+// Key 返回 redis 中的锁 key
+func (m *Mutex) Key() string { return m.key }
+
+// Token 返回持有者 token
+func (m *Mutex) Token() string { return m.token }
+
+// Fence 返回本次获取的 fencing token
+func (m *Mutex) Fence() int64 { return m.fence }
+
+// Lost 自动续期发现锁已丢失时关闭，持有者应尽快停止受保护的操作
+func (m *Mutex) Lost() <-chan struct{} { return m.lost }
+
+// Refresh 手动续期，锁已丢失时返回 ErrLockNotHeld
+func (m *Mutex) Refresh(ctx context.Context, ttl time.Duration) error {
+	ok, err := lockRefreshScript.Run(ctx, m.client, []string{m.key}, m.token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		m.markLost()
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Unlock 停止续期并减少持有计数，计数归零时释放锁，锁已过期或被他人获取时返回 ErrLockNotHeld
+func (m *Mutex) Unlock(ctx context.Context) error {
+	m.stopOnce.Do(func() {
+		close(m.stop)
+	})
+	<-m.renewed
+	ok, err := lockReleaseScript.Run(ctx, m.client, []string{m.key}, m.token).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (m *Mutex) markLost() {
+	m.lostOnce.Do(func() {
+		close(m.lost)
+	})
+}
+
+func (m *Mutex) renewLoop() {
+	defer close(m.renewed)
+	ticker := time.NewTicker(max(m.ttl/3, time.Millisecond))
+	defer ticker.Stop()
+	lastRenew := time.Now()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), m.ttl/3+time.Second)
+			err := m.Refresh(ctx, m.ttl)
+			cancel()
+			switch {
+			case err == nil:
+				lastRenew = time.Now()
+			case errors.Is(err, ErrLockNotHeld):
+				return
+			case time.Since(lastRenew) >= m.ttl:
+				// redis 持续不可用超过租期，锁可能已被他人获取
+				m.markLost()
+				return
+			}
+		}
+	}
+}
+
+// 获取成功返回 fencing token，重入时返回当前的 fencing token，失败返回 0
+var lockAcquireScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 or redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+	local holds = redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	if holds == 1 then
+		return redis.call('INCR', KEYS[2])
+	end
+	local fence = redis.call('GET', KEYS[2])
+	if not fence then
+		return redis.call('INCR', KEYS[2])
+	end
+	return tonumber(fence)
+end
+return 0
+`)
+
+var lockRefreshScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+var lockReleaseScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+if redis.call('HINCRBY', KEYS[1], ARGV[1], -1) <= 0 then
+	redis.call('DEL', KEYS[1])
+end
+return 1
+`)
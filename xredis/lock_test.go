@@ -0,0 +1,115 @@
+package xredis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	c := testRedis(t)
+	ctx := context.Background()
+	c.Del(ctx, "xlock:{test:lock}")
+
+	m1, err := Lock(ctx, "test:lock", WithLockClient(c), WithLockTTL(300*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Lock(ctx, "test:lock", WithLockClient(c)); !errors.Is(err, ErrLockNotAcquired) {
+		t.Fatalf("expected ErrLockNotAcquired, got %v", err)
+	}
+
+	// 自动续期使锁在超过 ttl 后仍被持有
+	time.Sleep(500 * time.Millisecond)
+	if holds, _ := c.HGet(ctx, m1.Key(), m1.Token()).Int(); holds != 1 {
+		t.Fatalf("lock should be renewed, got %d holds", holds)
+	}
+
+	// 阻塞获取在锁释放后成功，fencing token 递增
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = m1.Unlock(ctx)
+		close(released)
+	}()
+	waitCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	m2, err := Lock(waitCtx, "test:lock", WithLockClient(c), WithLockBlock(), WithLockBackoff(10*time.Millisecond, 50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-released
+	if m2.Fence() <= m1.Fence() {
+		t.Fatalf("fence should increase: %d <= %d", m2.Fence(), m1.Fence())
+	}
+	if err := m1.Unlock(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("stale holder must not release new lock, got %v", err)
+	}
+	if err := m2.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockLost(t *testing.T) {
+	c := testRedis(t)
+	ctx := context.Background()
+	m, err := Lock(ctx, "test:lost", WithLockClient(c), WithLockTTL(150*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Del(ctx, m.Key())
+	select {
+	case <-m.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("expected lock lost")
+	}
+	if err := m.Unlock(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("expected ErrLockNotHeld, got %v", err)
+	}
+}
+
+func TestLockReentrant(t *testing.T) {
+	c := testRedis(t)
+	ctx := context.Background()
+	c.Del(ctx, "xlock:{test:reentrant}")
+
+	outer, err := Lock(ctx, "test:reentrant", WithLockClient(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := Lock(ctx, "test:reentrant", WithLockClient(c), WithLockToken(outer.Token()))
+	if err != nil {
+		t.Fatalf("same owner should re-acquire, got %v", err)
+	}
+	if inner.Fence() != outer.Fence() {
+		t.Fatalf("reentrant acquire should keep fence %d, got %d", outer.Fence(), inner.Fence())
+	}
+	if _, err := Lock(ctx, "test:reentrant", WithLockClient(c)); !errors.Is(err, ErrLockNotAcquired) {
+		t.Fatalf("other owner should not acquire, got %v", err)
+	}
+
+	// 内层释放后外层仍持有
+	if err := inner.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Lock(ctx, "test:reentrant", WithLockClient(c)); !errors.Is(err, ErrLockNotAcquired) {
+		t.Fatalf("lock should still be held by outer, got %v", err)
+	}
+	if err := outer.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.Exists(ctx, outer.Key()).Result(); n != 0 {
+		t.Fatal("lock should be released after all holds are unlocked")
+	}
+}
+
+func TestLockErrors(t *testing.T) {
+	c := unreachableRedis(t)
+	if _, err := Lock(context.Background(), "test:down", WithLockClient(c)); err == nil {
+		t.Fatal("expected error when redis is unavailable")
+	}
+	if _, err := Lock(context.Background(), "test:ttl", WithLockClient(c), WithLockTTL(0)); err == nil {
+		t.Fatal("expected invalid ttl error")
+	}
+}
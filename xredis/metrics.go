@@ -0,0 +1,57 @@
+package xredis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/daodao97/xgo/xmetrics"
+)
+
+// MetricsHook 按客户端名记录每条命令的耗时，Init 系列函数创建的客户端已默认添加
+func MetricsHook(client string) redis.Hook {
+	return metricsHook{client: client}
+}
+
+type metricsHook struct {
+	client string
+}
+
+func (metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		xmetrics.ObserveRedis(h.client, cmd.Name(), time.Since(start), metricsError(err))
+		return err
+	}
+}
+
+func (h metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		xmetrics.ObserveRedis(h.client, "pipeline", time.Since(start), metricsError(err))
+		return err
+	}
+}
+
+// redis.Nil 表示 key 不存在，不计为失败
+func metricsError(err error) error {
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
+
+// instrument 为客户端添加追踪与指标 hook，并登记连接池指标
+func instrument(name string, c redis.UniversalClient) {
+	c.AddHook(TracingHook())
+	c.AddHook(MetricsHook(name))
+	xmetrics.RegisterRedis(name, c)
+}
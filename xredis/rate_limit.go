@@ -43,7 +43,7 @@ func (r RateLimit) key(k string) string {
 // IsAllowed 检查是否允许进行下一次API调用
 func (r RateLimit) IsAllowed(ctx context.Context, key string) bool {
 	redisKey := r.key(key)
-	val, err := client.Get(ctx, redisKey).Int()
+	val, err := Get().Get(ctx, redisKey).Int()
 	if err != nil && !errors.Is(err, redis.Nil) {
 		return true
 	}
@@ -51,41 +51,19 @@ func (r RateLimit) IsAllowed(ctx context.Context, key string) bool {
 	return val < r.Limit
 }
 
+// Incr 计数加一，首次计数时设置过期时间，redis 出错时返回 false
 func (r RateLimit) Incr(ctx context.Context, key string) bool {
-	redisKey := fmt.Sprintf("rate_limit_%s:%s", r.Prefix, key)
-	val, err := client.Get(ctx, redisKey).Int()
-	if err != nil && !errors.Is(err, redis.Nil) {
-		return true
-	}
-
-	if val == 0 {
-		client.Set(ctx, redisKey, 1, r.Period)
-	} else {
-		client.Incr(ctx, redisKey)
-	}
-
-	return true
+	return r.IncrBy(ctx, key, 1)
 }
 
+// IncrBy 计数增加 value，与 Incr 一样在 redis 中原子完成
 func (r RateLimit) IncrBy(ctx context.Context, key string, value int) bool {
-	redisKey := fmt.Sprintf("rate_limit_%s:%s", r.Prefix, key)
-	val, err := client.Get(ctx, redisKey).Int()
-	if err != nil && !errors.Is(err, redis.Nil) {
-		return true
-	}
-
-	if val == 0 {
-		client.Set(ctx, redisKey, value, r.Period)
-	} else {
-		client.IncrBy(ctx, redisKey, int64(value))
-	}
-
-	return true
+	return incrExpire(ctx, r.key(key), value, r.Period) == nil
 }
 
 func (r RateLimit) Get(ctx context.Context, key string) int64 {
 	redisKey := r.key(key)
-	i, err := client.Get(ctx, redisKey).Int64()
+	i, err := Get().Get(ctx, redisKey).Int64()
 	if err != nil && !errors.Is(err, redis.Nil) {
 		return 0
 	}
@@ -95,5 +73,22 @@ func (r RateLimit) Get(ctx context.Context, key string) int64 {
 // GetRemainingTime 获取剩余的限制时间
 func (r RateLimit) GetRemainingTime(ctx context.Context, key string) time.Duration {
 	redisKey := r.key(key)
-	return client.TTL(ctx, redisKey).Val()
+	return Get().TTL(ctx, redisKey).Val()
+}
+
+// incrExpireScript 增加计数，key 没有过期时间时设置过期时间
+var incrExpireScript = redis.NewScript(`
+local v = redis.call('INCRBY', KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 and redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return v
+`)
+
+func incrExpire(ctx context.Context, key string, value int, period time.Duration) error {
+	c := Get()
+	if c == nil {
+		return errors.New("xredis: client not initialized")
+	}
+	return incrExpireScript.Run(ctx, c, []string{key}, value, period.Milliseconds()).Err()
 }
@@ -2,10 +2,15 @@ package xredis
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+
+	"github.com/daodao97/xgo/xmetrics"
 )
 
 type Options struct {
@@ -29,21 +34,36 @@ type Options struct {
 	ClusterPassword string   `env:"CLUSTER_PASSWORD" yaml:"cluster_password"`
 }
 
-var client redis.UniversalClient
+var (
+	clientMu sync.RWMutex
+	client   redis.UniversalClient
+)
+
+func setClient(c redis.UniversalClient) {
+	clientMu.Lock()
+	client = c
+	clientMu.Unlock()
+}
 
 func Init(opt *redis.Options) error {
-	client = redis.NewClient(opt)
-	return client.Ping(context.Background()).Err()
+	c := redis.NewClient(opt)
+	instrument("default", c)
+	setClient(c)
+	return c.Ping(context.Background()).Err()
 }
 
 func InitCluster(opt *redis.ClusterOptions) error {
-	client = redis.NewClusterClient(opt)
-	return client.Ping(context.Background()).Err()
+	c := redis.NewClusterClient(opt)
+	instrument("default", c)
+	setClient(c)
+	return c.Ping(context.Background()).Err()
 }
 
 func InitUniversal(opt *redis.UniversalOptions) error {
-	client = redis.NewUniversalClient(opt)
-	return client.Ping(context.Background()).Err()
+	c := redis.NewUniversalClient(opt)
+	instrument("default", c)
+	setClient(c)
+	return c.Ping(context.Background()).Err()
 }
 
 var clients sync.Map
@@ -94,12 +114,15 @@ func Inits(conf []Options) error {
 			c = redis.NewClient(opt)
 		}
 
+		instrument(conf.Name, c)
+
 		if err = c.Ping(context.Background()).Err(); err != nil {
+			xmetrics.UnregisterRedis(conf.Name)
 			return err
 		}
 
 		if conf.Name == "default" {
-			client = c
+			setClient(c)
 		}
 		clients.Store(conf.Name, c)
 	}
@@ -107,9 +130,63 @@ func Inits(conf []Options) error {
 }
 
 func Get() redis.UniversalClient {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
 	return client
 }
 
+// Close 关闭默认客户端及所有具名客户端
+func Close() error {
+	var errs []error
+	closed := map[redis.UniversalClient]struct{}{}
+	clients.Range(func(key, value any) bool {
+		c := value.(redis.UniversalClient)
+		closed[c] = struct{}{}
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close redis %v: %w", key, err))
+		}
+		clients.Delete(key)
+		xmetrics.UnregisterRedis(key.(string))
+		return true
+	})
+	clientMu.Lock()
+	c := client
+	client = nil
+	clientMu.Unlock()
+	xmetrics.UnregisterRedis("default")
+	if c != nil {
+		if _, ok := closed[c]; !ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("close redis default: %w", err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Ping 检查指定名称的客户端是否可用，name 为空时检查默认客户端
+func Ping(ctx context.Context, name string) error {
+	c := Get()
+	if name != "" {
+		c = GetClient(name)
+	}
+	if c == nil {
+		return fmt.Errorf("redis client not found: %s", name)
+	}
+	return c.Ping(ctx).Err()
+}
+
+// Names 返回通过 Inits 初始化的具名客户端
+func Names() []string {
+	var names []string
+	clients.Range(func(key, value any) bool {
+		names = append(names, key.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
 func GetClient(name string) redis.UniversalClient {
 	if c, ok := clients.Load(name); ok {
 		return c.(redis.UniversalClient)
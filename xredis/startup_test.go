@@ -0,0 +1,29 @@
+package xredis
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCloseConcurrentGet(t *testing.T) {
+	_ = Init(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = Get()
+			}
+		}()
+	}
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	if Get() != nil {
+		t.Fatal("expected default client to be reset")
+	}
+}
@@ -0,0 +1,70 @@
+package xredis
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/daodao97/xgo/xtrace"
+)
+
+// TracingHook 为 ctx 中已有 span 的命令创建子 span，Init 系列函数创建的客户端已默认添加，
+// 自行创建的客户端可通过 client.AddHook(xredis.TracingHook()) 启用
+func TracingHook() redis.Hook {
+	return tracingHook{}
+}
+
+type tracingHook struct{}
+
+func (tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := xtrace.StartChild(ctx, "redis."+cmd.Name(),
+			xtrace.WithSpanKind(xtrace.SpanKindClient),
+			xtrace.WithSpanAttr("db.system", "redis"),
+			xtrace.WithSpanAttr("db.operation", cmd.Name()),
+		)
+		if span == nil {
+			return next(ctx, cmd)
+		}
+		err := next(ctx, cmd)
+		spanError(span, err)
+		span.End()
+		return err
+	}
+}
+
+func (tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := xtrace.StartChild(ctx, "redis.pipeline",
+			xtrace.WithSpanKind(xtrace.SpanKindClient),
+			xtrace.WithSpanAttr("db.system", "redis"),
+			xtrace.WithSpanAttr("db.redis.num_cmd", len(cmds)),
+		)
+		if span == nil {
+			return next(ctx, cmds)
+		}
+		names := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			names = append(names, cmd.Name())
+		}
+		span.SetAttr("db.operation", strings.Join(names, " "))
+		err := next(ctx, cmds)
+		spanError(span, err)
+		span.End()
+		return err
+	}
+}
+
+// redis.Nil 表示 key 不存在，不记为错误
+func spanError(span *xtrace.Span, err error) {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return
+	}
+	span.SetError(err)
+}
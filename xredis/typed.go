@@ -0,0 +1,670 @@
+package xredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNotFound key 或字段不存在，与 redis.Nil 相同
+var ErrNotFound = redis.Nil
+
+type typedOptions struct {
+	prefix     string
+	codec      Codec
+	clientName string
+	client     redis.UniversalClient
+	maxLen     int64
+}
+
+type TypedOption func(*typedOptions)
+
+// WithPrefix 设置 key 前缀，实际 key 为 prefix:key，与 cache.WithPrefix 一致
+func WithPrefix(prefix string) TypedOption {
+	return func(o *typedOptions) {
+		o.prefix = prefix
+	}
+}
+
+// WithCodec 设置序列化方式，默认 JSONCodec
+func WithCodec(c Codec) TypedOption {
+	return func(o *typedOptions) {
+		o.codec = c
+	}
+}
+
+// WithClientName 使用 Inits 注册的具名客户端，调用时才查找，可以在 Inits 之前声明
+func WithClientName(name string) TypedOption {
+	return func(o *typedOptions) {
+		o.clientName = name
+	}
+}
+
+// WithClient 直接指定客户端，默认使用 Get() 返回的默认客户端
+func WithClient(c redis.UniversalClient) TypedOption {
+	return func(o *typedOptions) {
+		o.client = c
+	}
+}
+
+// WithStreamMaxLen Stream 写入时近似裁剪到 n 条，默认不裁剪
+func WithStreamMaxLen(n int64) TypedOption {
+	return func(o *typedOptions) {
+		o.maxLen = n
+	}
+}
+
+type typed[T any] struct {
+	o *typedOptions
+}
+
+func newTyped[T any](opts []TypedOption) typed[T] {
+	o := &typedOptions{codec: JSONCodec}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return typed[T]{o: o}
+}
+
+func (t typed[T]) key(k string) string {
+	if t.o.prefix != "" {
+		return t.o.prefix + ":" + k
+	}
+	return k
+}
+
+func (t typed[T]) redis() (redis.UniversalClient, error) {
+	if t.o.client != nil {
+		return t.o.client, nil
+	}
+	if t.o.clientName != "" {
+		if c := GetClient(t.o.clientName); c != nil {
+			return c, nil
+		}
+		return nil, fmt.Errorf("redis client not found: %s", t.o.clientName)
+	}
+	if c := Get(); c != nil {
+		return c, nil
+	}
+	return nil, errors.New("xredis: client not initialized")
+}
+
+func (t typed[T]) encode(v T) (string, error) {
+	b, err := t.o.codec.Marshal(v)
+	return string(b), err
+}
+
+func (t typed[T]) decode(s string) (T, error) {
+	var v T
+	err := t.o.codec.Unmarshal([]byte(s), &v)
+	return v, err
+}
+
+func (t typed[T]) decodeAll(list []string) ([]T, error) {
+	out := make([]T, 0, len(list))
+	for _, s := range list {
+		v, err := t.decode(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// Value 以 key 存储单个 T
+//
+//	users := xredis.NewValue[User](xredis.WithPrefix("user"), xredis.WithClientName("cache"))
+//	err := users.Set(ctx, "1", u, time.Hour)
+type Value[T any] struct {
+	typed[T]
+}
+
+func NewValue[T any](opts ...TypedOption) *Value[T] {
+	return &Value[T]{newTyped[T](opts)}
+}
+
+// Get 不存在时返回 ErrNotFound
+func (v *Value[T]) Get(ctx context.Context, key string) (T, error) {
+	var zero T
+	c, err := v.redis()
+	if err != nil {
+		return zero, err
+	}
+	s, err := c.Get(ctx, v.key(key)).Result()
+	if err != nil {
+		return zero, err
+	}
+	return v.decode(s)
+}
+
+// Set ttl 为 0 时不过期
+func (v *Value[T]) Set(ctx context.Context, key string, val T, ttl time.Duration) error {
+	c, err := v.redis()
+	if err != nil {
+		return err
+	}
+	s, err := v.encode(val)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, v.key(key), s, ttl).Err()
+}
+
+// SetNX key 不存在时写入，返回是否写入
+func (v *Value[T]) SetNX(ctx context.Context, key string, val T, ttl time.Duration) (bool, error) {
+	c, err := v.redis()
+	if err != nil {
+		return false, err
+	}
+	s, err := v.encode(val)
+	if err != nil {
+		return false, err
+	}
+	return c.SetNX(ctx, v.key(key), s, ttl).Result()
+}
+
+// MGet 返回以 key 为索引的结果（不含前缀），不存在的 key 不在结果中
+func (v *Value[T]) MGet(ctx context.Context, keys ...string) (map[string]T, error) {
+	c, err := v.redis()
+	if err != nil {
+		return nil, err
+	}
+	full := make([]string, len(keys))
+	for i, k := range keys {
+		full[i] = v.key(k)
+	}
+	vals, err := c.MGet(ctx, full...).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]T, len(keys))
+	for i, val := range vals {
+		s, ok := val.(string)
+		if !ok {
+			continue
+		}
+		item, err := v.decode(s)
+		if err != nil {
+			return nil, err
+		}
+		out[keys[i]] = item
+	}
+	return out, nil
+}
+
+func (v *Value[T]) Del(ctx context.Context, keys ...string) error {
+	c, err := v.redis()
+	if err != nil {
+		return err
+	}
+	full := make([]string, len(keys))
+	for i, k := range keys {
+		full[i] = v.key(k)
+	}
+	return c.Del(ctx, full...).Err()
+}
+
+func (v *Value[T]) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	c, err := v.redis()
+	if err != nil {
+		return err
+	}
+	return c.Expire(ctx, v.key(key), ttl).Err()
+}
+
+// Hash 以 field 存储 T 的哈希表
+type Hash[T any] struct {
+	typed[T]
+}
+
+func NewHash[T any](opts ...TypedOption) *Hash[T] {
+	return &Hash[T]{newTyped[T](opts)}
+}
+
+// Get 字段不存在时返回 ErrNotFound
+func (h *Hash[T]) Get(ctx context.Context, key, field string) (T, error) {
+	var zero T
+	c, err := h.redis()
+	if err != nil {
+		return zero, err
+	}
+	s, err := c.HGet(ctx, h.key(key), field).Result()
+	if err != nil {
+		return zero, err
+	}
+	return h.decode(s)
+}
+
+func (h *Hash[T]) Set(ctx context.Context, key, field string, val T) error {
+	return h.SetMany(ctx, key, map[string]T{field: val})
+}
+
+func (h *Hash[T]) SetMany(ctx context.Context, key string, fields map[string]T) error {
+	c, err := h.redis()
+	if err != nil {
+		return err
+	}
+	args := make([]any, 0, len(fields)*2)
+	for f, val := range fields {
+		s, err := h.encode(val)
+		if err != nil {
+			return err
+		}
+		args = append(args, f, s)
+	}
+	return c.HSet(ctx, h.key(key), args...).Err()
+}
+
+func (h *Hash[T]) GetAll(ctx context.Context, key string) (map[string]T, error) {
+	c, err := h.redis()
+	if err != nil {
+		return nil, err
+	}
+	m, err := c.HGetAll(ctx, h.key(key)).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]T, len(m))
+	for f, s := range m {
+		val, err := h.decode(s)
+		if err != nil {
+			return nil, err
+		}
+		out[f] = val
+	}
+	return out, nil
+}
+
+func (h *Hash[T]) Del(ctx context.Context, key string, fields ...string) error {
+	c, err := h.redis()
+	if err != nil {
+		return err
+	}
+	return c.HDel(ctx, h.key(key), fields...).Err()
+}
+
+func (h *Hash[T]) Exists(ctx context.Context, key, field string) (bool, error) {
+	c, err := h.redis()
+	if err != nil {
+		return false, err
+	}
+	return c.HExists(ctx, h.key(key), field).Result()
+}
+
+func (h *Hash[T]) Len(ctx context.Context, key string) (int64, error) {
+	c, err := h.redis()
+	if err != nil {
+		return 0, err
+	}
+	return c.HLen(ctx, h.key(key)).Result()
+}
+
+// Set 以编码结果作为成员的集合，相同的值需编码一致
+type Set[T any] struct {
+	typed[T]
+}
+
+func NewSet[T any](opts ...TypedOption) *Set[T] {
+	return &Set[T]{newTyped[T](opts)}
+}
+
+func (s *Set[T]) encodeAll(members []T) ([]any, error) {
+	out := make([]any, 0, len(members))
+	for _, m := range members {
+		e, err := s.encode(m)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// Add 返回新增的成员数
+func (s *Set[T]) Add(ctx context.Context, key string, members ...T) (int64, error) {
+	c, err := s.redis()
+	if err != nil {
+		return 0, err
+	}
+	args, err := s.encodeAll(members)
+	if err != nil {
+		return 0, err
+	}
+	return c.SAdd(ctx, s.key(key), args...).Result()
+}
+
+func (s *Set[T]) Remove(ctx context.Context, key string, members ...T) (int64, error) {
+	c, err := s.redis()
+	if err != nil {
+		return 0, err
+	}
+	args, err := s.encodeAll(members)
+	if err != nil {
+		return 0, err
+	}
+	return c.SRem(ctx, s.key(key), args...).Result()
+}
+
+func (s *Set[T]) Contains(ctx context.Context, key string, member T) (bool, error) {
+	c, err := s.redis()
+	if err != nil {
+		return false, err
+	}
+	e, err := s.encode(member)
+	if err != nil {
+		return false, err
+	}
+	return c.SIsMember(ctx, s.key(key), e).Result()
+}
+
+func (s *Set[T]) Members(ctx context.Context, key string) ([]T, error) {
+	c, err := s.redis()
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.SMembers(ctx, s.key(key)).Result()
+	if err != nil {
+		return nil, err
+	}
+	return s.decodeAll(list)
+}
+
+func (s *Set[T]) Len(ctx context.Context, key string) (int64, error) {
+	c, err := s.redis()
+	if err != nil {
+		return 0, err
+	}
+	return c.SCard(ctx, s.key(key)).Result()
+}
+
+// Scored 带分数的成员
+type Scored[T any] struct {
+	Member T
+	Score  float64
+}
+
+// SortedSet 有序集合，可用于排行榜
+//
+//	board := xredis.NewSortedSet[string](xredis.WithPrefix("rank"))
+//	board.IncrBy(ctx, "daily", "u1", 10)
+//	top, _ := board.RevRange(ctx, "daily", 0, 9)
+type SortedSet[T any] struct {
+	typed[T]
+}
+
+func NewSortedSet[T any](opts ...TypedOption) *SortedSet[T] {
+	return &SortedSet[T]{newTyped[T](opts)}
+}
+
+func (z *SortedSet[T]) decodeZ(list []redis.Z) ([]Scored[T], error) {
+	out := make([]Scored[T], 0, len(list))
+	for _, item := range list {
+		s, ok := item.Member.(string)
+		if !ok {
+			return nil, fmt.Errorf("xredis: unexpected member type %T", item.Member)
+		}
+		m, err := z.decode(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Scored[T]{Member: m, Score: item.Score})
+	}
+	return out, nil
+}
+
+// Add 添加或更新成员分数
+func (z *SortedSet[T]) Add(ctx context.Context, key string, members ...Scored[T]) error {
+	c, err := z.redis()
+	if err != nil {
+		return err
+	}
+	list := make([]redis.Z, 0, len(members))
+	for _, m := range members {
+		e, err := z.encode(m.Member)
+		if err != nil {
+			return err
+		}
+		list = append(list, redis.Z{Score: m.Score, Member: e})
+	}
+	return c.ZAdd(ctx, z.key(key), list...).Err()
+}
+
+// IncrBy 增加成员分数，返回新的分数
+func (z *SortedSet[T]) IncrBy(ctx context.Context, key string, member T, delta float64) (float64, error) {
+	c, err := z.redis()
+	if err != nil {
+		return 0, err
+	}
+	e, err := z.encode(member)
+	if err != nil {
+		return 0, err
+	}
+	return c.ZIncrBy(ctx, z.key(key), delta, e).Result()
+}
+
+// Score 成员不存在时返回 ErrNotFound
+func (z *SortedSet[T]) Score(ctx context.Context, key string, member T) (float64, error) {
+	c, err := z.redis()
+	if err != nil {
+		return 0, err
+	}
+	e, err := z.encode(member)
+	if err != nil {
+		return 0, err
+	}
+	return c.ZScore(ctx, z.key(key), e).Result()
+}
+
+// Rank 按分数从低到高的排名（从 0 开始），成员不存在时返回 ErrNotFound
+func (z *SortedSet[T]) Rank(ctx context.Context, key string, member T) (int64, error) {
+	c, err := z.redis()
+	if err != nil {
+		return 0, err
+	}
+	e, err := z.encode(member)
+	if err != nil {
+		return 0, err
+	}
+	return c.ZRank(ctx, z.key(key), e).Result()
+}
+
+// RevRank 按分数从高到低的排名（从 0 开始），成员不存在时返回 ErrNotFound
+func (z *SortedSet[T]) RevRank(ctx context.Context, key string, member T) (int64, error) {
+	c, err := z.redis()
+	if err != nil {
+		return 0, err
+	}
+	e, err := z.encode(member)
+	if err != nil {
+		return 0, err
+	}
+	return c.ZRevRank(ctx, z.key(key), e).Result()
+}
+
+// Range 按分数从低到高返回排名 [start, stop] 的成员，stop 为 -1 表示到末尾
+func (z *SortedSet[T]) Range(ctx context.Context, key string, start, stop int64) ([]Scored[T], error) {
+	c, err := z.redis()
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.ZRangeWithScores(ctx, z.key(key), start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	return z.decodeZ(list)
+}
+
+// RevRange 按分数从高到低返回排名 [start, stop] 的成员
+func (z *SortedSet[T]) RevRange(ctx context.Context, key string, start, stop int64) ([]Scored[T], error) {
+	c, err := z.redis()
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.ZRevRangeWithScores(ctx, z.key(key), start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	return z.decodeZ(list)
+}
+
+// RangeByScore 返回分数在 [min, max] 之间的成员，按分数从低到高，limit 为 0 时不限制数量
+func (z *SortedSet[T]) RangeByScore(ctx context.Context, key string, min, max float64, offset, limit int64) ([]Scored[T], error) {
+	c, err := z.redis()
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.ZRangeByScoreWithScores(ctx, z.key(key), &redis.ZRangeBy{
+		Min:    formatScore(min),
+		Max:    formatScore(max),
+		Offset: offset,
+		Count:  limit,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	return z.decodeZ(list)
+}
+
+func (z *SortedSet[T]) Remove(ctx context.Context, key string, members ...T) (int64, error) {
+	c, err := z.redis()
+	if err != nil {
+		return 0, err
+	}
+	args := make([]any, 0, len(members))
+	for _, m := range members {
+		e, err := z.encode(m)
+		if err != nil {
+			return 0, err
+		}
+		args = append(args, e)
+	}
+	return c.ZRem(ctx, z.key(key), args...).Result()
+}
+
+func (z *SortedSet[T]) Len(ctx context.Context, key string) (int64, error) {
+	c, err := z.redis()
+	if err != nil {
+		return 0, err
+	}
+	return c.ZCard(ctx, z.key(key)).Result()
+}
+
+func formatScore(f float64) string {
+	return fmt.Sprintf("%v", f)
+}
+
+// StreamEntry Stream 中的一条消息
+type StreamEntry[T any] struct {
+	ID    string
+	Value T
+}
+
+// Stream 每条消息的 data 字段保存编码后的 T
+type Stream[T any] struct {
+	typed[T]
+}
+
+const streamField = "data"
+
+func NewStream[T any](opts ...TypedOption) *Stream[T] {
+	return &Stream[T]{newTyped[T](opts)}
+}
+
+// Add 追加消息，返回消息 id
+func (s *Stream[T]) Add(ctx context.Context, key string, val T) (string, error) {
+	c, err := s.redis()
+	if err != nil {
+		return "", err
+	}
+	e, err := s.encode(val)
+	if err != nil {
+		return "", err
+	}
+	args := &redis.XAddArgs{Stream: s.key(key), Values: []any{streamField, e}}
+	if s.o.maxLen > 0 {
+		args.MaxLen = s.o.maxLen
+		args.Approx = true
+	}
+	return c.XAdd(ctx, args).Result()
+}
+
+func (s *Stream[T]) decodeMessages(msgs []redis.XMessage) ([]StreamEntry[T], error) {
+	out := make([]StreamEntry[T], 0, len(msgs))
+	for _, msg := range msgs {
+		raw, ok := msg.Values[streamField].(string)
+		if !ok {
+			return nil, fmt.Errorf("xredis: stream message %s has no %s field", msg.ID, streamField)
+		}
+		val, err := s.decode(raw)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, StreamEntry[T]{ID: msg.ID, Value: val})
+	}
+	return out, nil
+}
+
+// Range 返回 id 在 [start, end] 之间的消息，"-" 与 "+" 表示最小与最大，count 为 0 时不限制
+func (s *Stream[T]) Range(ctx context.Context, key, start, end string, count int64) ([]StreamEntry[T], error) {
+	c, err := s.redis()
+	if err != nil {
+		return nil, err
+	}
+	var msgs []redis.XMessage
+	if count > 0 {
+		msgs, err = c.XRangeN(ctx, s.key(key), start, end, count).Result()
+	} else {
+		msgs, err = c.XRange(ctx, s.key(key), start, end).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.decodeMessages(msgs)
+}
+
+// Read 读取 id 大于 lastID 的消息，lastID 为 "$" 表示只读新消息；block 大于 0 时最多等待 block，
+// 超时没有消息返回空切片
+func (s *Stream[T]) Read(ctx context.Context, key, lastID string, count int64, block time.Duration) ([]StreamEntry[T], error) {
+	c, err := s.redis()
+	if err != nil {
+		return nil, err
+	}
+	if block <= 0 {
+		block = -1
+	}
+	streams, err := c.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{s.key(key), lastID},
+		Count:   count,
+		Block:   block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(streams) == 0 {
+		return nil, nil
+	}
+	return s.decodeMessages(streams[0].Messages)
+}
+
+func (s *Stream[T]) Len(ctx context.Context, key string) (int64, error) {
+	c, err := s.redis()
+	if err != nil {
+		return 0, err
+	}
+	return c.XLen(ctx, s.key(key)).Result()
+}
+
+func (s *Stream[T]) Del(ctx context.Context, key string, ids ...string) (int64, error) {
+	c, err := s.redis()
+	if err != nil {
+		return 0, err
+	}
+	return c.XDel(ctx, s.key(key), ids...).Result()
+}
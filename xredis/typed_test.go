@@ -0,0 +1,136 @@
+package xredis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type typedUser struct {
+	Name string            `json:"name"`
+	Age  int               `json:"age"`
+	Tags map[string]string `json:"tags"`
+}
+
+func TestCodecs(t *testing.T) {
+	u := typedUser{Name: "a", Age: 3, Tags: map[string]string{"b": "2", "a": "1", "c": "3"}}
+	for name, c := range map[string]Codec{"json": JSONCodec, "msgpack": MsgpackCodec} {
+		b1, err := c.Marshal(u)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		// 集合成员依赖编码结果，相同的值多次编码必须一致
+		for i := 0; i < 5; i++ {
+			b2, _ := c.Marshal(u)
+			if string(b1) != string(b2) {
+				t.Fatalf("%s: encoding not deterministic", name)
+			}
+		}
+		var got typedUser
+		if err := c.Unmarshal(b1, &got); err != nil {
+			t.Fatal(name, err)
+		}
+		if !reflect.DeepEqual(got, u) {
+			t.Fatalf("%s: got %+v", name, got)
+		}
+	}
+}
+
+func TestTypedKeyAndClient(t *testing.T) {
+	v := NewValue[int](WithPrefix("user"))
+	if k := v.key("1"); k != "user:1" {
+		t.Fatalf("unexpected key %s", k)
+	}
+	if k := NewValue[int]().key("1"); k != "1" {
+		t.Fatalf("unexpected key %s", k)
+	}
+	_, err := NewValue[int](WithClientName("not_exists")).Get(context.Background(), "1")
+	if err == nil || err.Error() != "redis client not found: not_exists" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestTypedValueAndHash(t *testing.T) {
+	c := testRedis(t)
+	ctx := context.Background()
+	for _, codec := range []Codec{JSONCodec, MsgpackCodec} {
+		v := NewValue[typedUser](WithClient(c), WithPrefix("test_typed"), WithCodec(codec))
+		_ = v.Del(ctx, "u1", "u2")
+		if _, err := v.Get(ctx, "u1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+		u := typedUser{Name: "a", Age: 1}
+		if err := v.Set(ctx, "u1", u, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		got, err := v.Get(ctx, "u1")
+		if err != nil || got.Name != "a" || got.Age != 1 {
+			t.Fatalf("unexpected %+v %v", got, err)
+		}
+		m, err := v.MGet(ctx, "u1", "u2")
+		if err != nil || len(m) != 1 || m["u1"].Name != "a" {
+			t.Fatalf("unexpected %+v %v", m, err)
+		}
+		_ = v.Del(ctx, "u1")
+
+		h := NewHash[typedUser](WithClient(c), WithPrefix("test_typed"), WithCodec(codec))
+		_ = h.Del(ctx, "h", "a", "b")
+		if err := h.SetMany(ctx, "h", map[string]typedUser{"a": {Age: 1}, "b": {Age: 2}}); err != nil {
+			t.Fatal(err)
+		}
+		all, err := h.GetAll(ctx, "h")
+		if err != nil || len(all) != 2 || all["b"].Age != 2 {
+			t.Fatalf("unexpected %+v %v", all, err)
+		}
+		_ = h.Del(ctx, "h", "a", "b")
+	}
+}
+
+func TestTypedSortedSet(t *testing.T) {
+	c := testRedis(t)
+	ctx := context.Background()
+	z := NewSortedSet[string](WithClient(c), WithPrefix("test_typed"))
+	_ = NewValue[string](WithClient(c), WithPrefix("test_typed")).Del(ctx, "rank")
+	if err := z.Add(ctx, "rank", Scored[string]{"a", 1}, Scored[string]{"b", 2}, Scored[string]{"c", 3}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := z.IncrBy(ctx, "rank", "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	top, err := z.RevRange(ctx, "rank", 0, 1)
+	if err != nil || len(top) != 2 || top[0].Member != "a" || top[0].Score != 11 || top[1].Member != "c" {
+		t.Fatalf("unexpected %+v %v", top, err)
+	}
+	if r, err := z.RevRank(ctx, "rank", "b"); err != nil || r != 2 {
+		t.Fatalf("unexpected rank %d %v", r, err)
+	}
+	list, err := z.RangeByScore(ctx, "rank", 2, 3, 0, 0)
+	if err != nil || len(list) != 2 || list[0].Member != "b" {
+		t.Fatalf("unexpected %+v %v", list, err)
+	}
+	if _, err := z.Score(ctx, "rank", "x"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestTypedStream(t *testing.T) {
+	c := testRedis(t)
+	ctx := context.Background()
+	s := NewStream[typedUser](WithClient(c), WithPrefix("test_typed"), WithStreamMaxLen(100))
+	c.Del(ctx, "test_typed:events")
+	id, err := s.Add(ctx, "events", typedUser{Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.Range(ctx, "events", "-", "+", 0)
+	if err != nil || len(list) != 1 || list[0].ID != id || list[0].Value.Name != "a" {
+		t.Fatalf("unexpected %+v %v", list, err)
+	}
+	list, err = s.Read(ctx, "events", id, 10, 50*time.Millisecond)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("expected no new messages, got %+v %v", list, err)
+	}
+	c.Del(ctx, "test_typed:events")
+}
@@ -309,6 +309,17 @@ func (r *Request) do() (*Response, error) {
 
 	r.debug = RequestDebug
 
+	method := r.method
+	if method == "" {
+		method = http.MethodGet
+	}
+	ctx, span := xtrace.StartChild(ctx, "HTTP "+method,
+		xtrace.WithSpanKind(xtrace.SpanKindClient),
+		xtrace.WithSpanAttr("http.request.method", method),
+		xtrace.WithSpanAttr("url.full", r.targetUrl),
+	)
+	defer span.End()
+
 	req, err := r.makeRequest(ctx)
 	if err != nil {
 		return nil, NewRequestError("创建请求失败", err)
@@ -351,6 +362,7 @@ func (r *Request) do() (*Response, error) {
 	_ = _curlString
 
 	if err != nil {
+		span.SetError(err)
 		logFunc = xlog.WarnCtx
 		args = append(args, xlog.Any("error", err))
 		logFunc(ctx, "xrequest network error", args...)
@@ -366,6 +378,10 @@ func (r *Request) do() (*Response, error) {
 
 	if resp != nil {
 		args = append(args, xlog.Any("status", resp.StatusCode))
+		span.SetAttr("http.response.status_code", resp.StatusCode)
+		if resp.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(xtrace.StatusError, resp.Status)
+		}
 	}
 
 	_resp := NewResponse(resp)
@@ -473,16 +489,21 @@ func (r *Request) makeRequest(ctx context.Context) (*http.Request, error) {
 }
 
 func addTraceIDHeader(ctx context.Context, req *http.Request) {
-	if req == nil || req.Header.Get(xtrace.DefaultTraceIdHeader) != "" {
+	if req == nil {
+		return
+	}
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	// traceparent/tracestate 按 W3C 规范向下游传播当前 span
+	xtrace.Inject(ctx, req.Header)
+	if req.Header.Get(xtrace.DefaultTraceIdHeader) != "" {
 		return
 	}
 	traceID := xtrace.FromTraceId(ctx)
 	if traceID == "" {
 		return
 	}
-	if req.Header == nil {
-		req.Header = make(http.Header)
-	}
 	req.Header.Set(xtrace.DefaultTraceIdHeader, traceID)
 }
 
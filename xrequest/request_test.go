@@ -171,6 +171,30 @@ func TestRequestKeepsExplicitTraceIDHeader(t *testing.T) {
 	}
 }
 
+func TestRequestPropagatesTraceparent(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(xtrace.TraceparentHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx, span := xtrace.Start(context.Background(), "parent")
+	defer span.End()
+	if _, err := New().WithContext(ctx).SetClient(server.Client()).Get(server.URL); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	sc, err := xtrace.ParseTraceparent(traceparent)
+	if err != nil {
+		t.Fatalf("invalid traceparent %q: %v", traceparent, err)
+	}
+	// 下游收到的是 xrequest 创建的客户端子 span
+	if sc.TraceID != span.SpanContext().TraceID || sc.SpanID == span.SpanContext().SpanID {
+		t.Fatalf("unexpected traceparent %q", traceparent)
+	}
+}
+
 func TestRequestSSE(t *testing.T) {
 	// 启动测试服务器
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,252 @@
+package xtrace
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Exporter 批量导出结束的 span
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+type exportConfig struct {
+	batchSize    int
+	batchTimeout time.Duration
+	queueSize    int
+	sampleRatio  float64
+}
+
+type ExportOption func(*exportConfig)
+
+// WithBatchSize 单次导出的最大 span 数，默认 512
+func WithBatchSize(n int) ExportOption {
+	return func(c *exportConfig) {
+		c.batchSize = n
+	}
+}
+
+// WithBatchTimeout 未攒满一批时的最长等待时间，默认 5s
+func WithBatchTimeout(d time.Duration) ExportOption {
+	return func(c *exportConfig) {
+		c.batchTimeout = d
+	}
+}
+
+// WithMaxQueueSize 等待导出的 span 上限，超出后丢弃，默认 2048
+func WithMaxQueueSize(n int) ExportOption {
+	return func(c *exportConfig) {
+		c.queueSize = n
+	}
+}
+
+// WithSampleRatio 新 trace 的采样比例，取值 0~1，默认 1；子 span 跟随上游的采样标志
+func WithSampleRatio(ratio float64) ExportOption {
+	return func(c *exportConfig) {
+		c.sampleRatio = ratio
+	}
+}
+
+var (
+	processor atomic.Pointer[batchProcessor]
+
+	errorHandler atomic.Pointer[func(error)]
+)
+
+// SetErrorHandler 设置导出失败时的回调，默认写入 slog.Default()
+func SetErrorHandler(fn func(error)) {
+	errorHandler.Store(&fn)
+}
+
+func handleError(err error) {
+	if fn := errorHandler.Load(); fn != nil && *fn != nil {
+		(*fn)(err)
+		return
+	}
+	slog.Default().Error("xtrace export failed", slog.Any("error", err))
+}
+
+// SetExporter 设置 span 导出器，span 在后台按批导出。传 nil 停止导出。
+// 替换时会先关闭之前的导出器并导出其剩余的 span
+func SetExporter(exp Exporter, opts ...ExportOption) {
+	var p *batchProcessor
+	if exp != nil {
+		cfg := &exportConfig{
+			batchSize:    512,
+			batchTimeout: 5 * time.Second,
+			queueSize:    2048,
+			sampleRatio:  1,
+		}
+		for _, opt := range opts {
+			opt(cfg)
+		}
+		p = newBatchProcessor(exp, cfg)
+	}
+	if old := processor.Swap(p); old != nil {
+		_ = old.shutdown(context.Background())
+	}
+}
+
+// Shutdown 导出剩余的 span 并关闭导出器，应在进程退出前调用
+func Shutdown(ctx context.Context) error {
+	if p := processor.Swap(nil); p != nil {
+		return p.shutdown(ctx)
+	}
+	return nil
+}
+
+func shouldSample() bool {
+	p := processor.Load()
+	if p == nil || p.cfg.sampleRatio >= 1 {
+		return true
+	}
+	return rand.Float64() < p.cfg.sampleRatio
+}
+
+func export(data SpanData) {
+	if p := processor.Load(); p != nil {
+		p.enqueue(data)
+	}
+}
+
+type batchProcessor struct {
+	exp   Exporter
+	cfg   *exportConfig
+	queue chan SpanData
+
+	closeOnce sync.Once
+	closing   chan struct{}
+	done      chan struct{}
+	mu        sync.RWMutex
+	closed    bool
+}
+
+func newBatchProcessor(exp Exporter, cfg *exportConfig) *batchProcessor {
+	if cfg.batchSize <= 0 {
+		cfg.batchSize = 512
+	}
+	if cfg.queueSize < cfg.batchSize {
+		cfg.queueSize = cfg.batchSize
+	}
+	if cfg.batchTimeout <= 0 {
+		cfg.batchTimeout = 5 * time.Second
+	}
+	p := &batchProcessor{
+		exp:     exp,
+		cfg:     cfg,
+		queue:   make(chan SpanData, cfg.queueSize),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *batchProcessor) enqueue(data SpanData) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return
+	}
+	select {
+	case p.queue <- data:
+	default:
+		// 队列已满时丢弃，不阻塞业务
+	}
+}
+
+func (p *batchProcessor) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.cfg.batchTimeout)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, p.cfg.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := p.exp.ExportSpans(ctx, batch); err != nil {
+			handleError(err)
+		}
+		cancel()
+		batch = make([]SpanData, 0, p.cfg.batchSize)
+	}
+
+	for {
+		select {
+		case data := <-p.queue:
+			batch = append(batch, data)
+			if len(batch) >= p.cfg.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-p.closing:
+			for {
+				select {
+				case data := <-p.queue:
+					batch = append(batch, data)
+					if len(batch) >= p.cfg.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *batchProcessor) shutdown(ctx context.Context) error {
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+		close(p.closing)
+	})
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return p.exp.Shutdown(ctx)
+}
+
+type stdoutExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewStdoutExporter 每个 span 输出一行 JSON，w 为 nil 时输出到标准输出，用于本地调试
+func NewStdoutExporter(w io.Writer) Exporter {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &stdoutExporter{w: w}
+}
+
+func (e *stdoutExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	enc := json.NewEncoder(e.w)
+	for _, span := range spans {
+		if err := enc.Encode(span); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *stdoutExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -2,6 +2,7 @@ package xtrace
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -37,7 +38,9 @@ func WithNextTraceId(f func() string) Option {
 }
 
 // TraceId is a middleware that injects a trace id into the context of each
-// request. if it is empty, set to write head
+// request. if it is empty, set to write head.
+// It also parses the W3C traceparent/tracestate headers and starts a server
+// span for the request, so spans started from the request context become its children.
 //   - traceIdHeader is the name of the HTTP Header which contains the trace id.
 //     Exported so that it can be changed by developers. (default "X-Trace-Id")
 //   - nextTraceID generates the next trace id.(default NewSequence function use utilities/sequence)
@@ -53,15 +56,51 @@ func TraceId(opts ...Option) gin.HandlerFunc {
 	}
 
 	return func(c *gin.Context) {
+		sc, remote := Extract(c.Request.Header)
 		traceId := c.Request.Header.Get(cc.traceIdHeader)
 		if traceId == "" {
-			traceId = cc.nextTraceID()
+			if remote {
+				traceId = sc.TraceID.String()
+			} else {
+				traceId = cc.nextTraceID()
+			}
 		}
 		// set response header
 		c.Header(cc.traceIdHeader, traceId)
 		// set request context
-		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxTraceIdKey{}, traceId))
+		ctx := context.WithValue(c.Request.Context(), ctxTraceIdKey{}, traceId)
+
+		var spanOpts []SpanOption
+		if remote {
+			ctx = ContextWithRemoteSpanContext(ctx, sc)
+		} else if tid, err := ParseTraceID(traceId); err == nil {
+			// uuid 形式的 trace id 直接作为 span 的 trace id
+			spanOpts = append(spanOpts, withTraceID(tid))
+		}
+		route := c.FullPath()
+		name := c.Request.Method
+		if route != "" {
+			name += " " + route
+		}
+		spanOpts = append(spanOpts,
+			WithSpanKind(SpanKindServer),
+			WithSpanAttr("http.request.method", c.Request.Method),
+			WithSpanAttr("url.path", c.Request.URL.Path),
+		)
+		if route != "" {
+			spanOpts = append(spanOpts, WithSpanAttr("http.route", route))
+		}
+		ctx, span := Start(ctx, name, spanOpts...)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
 		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttr("http.response.status_code", status)
+		if status >= 500 {
+			span.SetStatus(StatusError, http.StatusText(status))
+		}
 	}
 }
 
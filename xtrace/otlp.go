@@ -0,0 +1,190 @@
+package xtrace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+type otlpExporter struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+}
+
+type OTLPOption func(*otlpExporter)
+
+// WithOTLPHeader 添加请求头，如鉴权 token
+func WithOTLPHeader(key, value string) OTLPOption {
+	return func(e *otlpExporter) {
+		e.headers[key] = value
+	}
+}
+
+// WithOTLPServiceName 设置 resource 的 service.name，默认为进程名
+func WithOTLPServiceName(name string) OTLPOption {
+	return func(e *otlpExporter) {
+		e.serviceName = name
+	}
+}
+
+// WithOTLPHTTPClient 自定义 http.Client，默认超时 10s
+func WithOTLPHTTPClient(client *http.Client) OTLPOption {
+	return func(e *otlpExporter) {
+		e.client = client
+	}
+}
+
+// NewOTLPExporter 以 OTLP/HTTP JSON 格式导出到 endpoint，如 http://localhost:4318/v1/traces
+func NewOTLPExporter(endpoint string, opts ...OTLPOption) Exporter {
+	e := &otlpExporter{
+		endpoint:    endpoint,
+		headers:     map[string]string{},
+		serviceName: filepath.Base(os.Args[0]),
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("xtrace: otlp export status %d: %s", resp.StatusCode, msg)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (e *otlpExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// 以下为 ExportTraceServiceRequest 的 JSON 编码，id 使用十六进制字符串，64 位整数使用字符串
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	TraceState        string         `json:"traceState,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func (e *otlpExporter) request(spans []SpanData) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.TraceID.String(),
+			SpanID:            s.SpanID.String(),
+			TraceState:        s.TraceState,
+			Name:              s.Name,
+			Kind:              int(s.Kind) + 1, // OTLP 中 0 为 UNSPECIFIED
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Status:            otlpStatus{Code: int(s.Status), Message: s.StatusMessage},
+		}
+		if s.ParentSpanID.IsValid() {
+			span.ParentSpanID = s.ParentSpanID.String()
+		}
+		for _, attr := range s.Attrs {
+			span.Attributes = append(span.Attributes, otlpAttr(attr.Key, attr.Value))
+		}
+		out = append(out, span)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{otlpAttr("service.name", e.serviceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/daodao97/xgo/xtrace"}, Spans: out}},
+	}}}
+}
+
+func otlpAttr(key string, value any) otlpKeyValue {
+	var v map[string]any
+	switch val := value.(type) {
+	case string:
+		v = map[string]any{"stringValue": val}
+	case bool:
+		v = map[string]any{"boolValue": val}
+	case int:
+		v = map[string]any{"intValue": strconv.FormatInt(int64(val), 10)}
+	case int32:
+		v = map[string]any{"intValue": strconv.FormatInt(int64(val), 10)}
+	case int64:
+		v = map[string]any{"intValue": strconv.FormatInt(val, 10)}
+	case uint32:
+		v = map[string]any{"intValue": strconv.FormatUint(uint64(val), 10)}
+	case float32:
+		v = map[string]any{"doubleValue": float64(val)}
+	case float64:
+		v = map[string]any{"doubleValue": val}
+	case time.Duration:
+		v = map[string]any{"stringValue": val.String()}
+	case error:
+		v = map[string]any{"stringValue": val.Error()}
+	default:
+		v = map[string]any{"stringValue": fmt.Sprint(val)}
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
@@ -0,0 +1,82 @@
+package xtrace
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// ParseTraceparent 解析 W3C traceparent，格式为 version-traceid-parentid-flags
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 {
+		return sc, fmt.Errorf("xtrace: invalid traceparent %q", s)
+	}
+	version := parts[0]
+	// 00 版本必须恰好 4 段，ff 为无效版本，更高版本按规范只读取前 4 段
+	if len(version) != 2 || version == "ff" || (version == "00" && len(parts) != 4) {
+		return sc, fmt.Errorf("xtrace: invalid traceparent version %q", s)
+	}
+	if _, err := hex.DecodeString(version); err != nil {
+		return sc, fmt.Errorf("xtrace: invalid traceparent version %q", s)
+	}
+	if len(parts[1]) != 32 || strings.ToLower(parts[1]) != parts[1] {
+		return sc, fmt.Errorf("xtrace: invalid trace id %q", s)
+	}
+	traceID, err := ParseTraceID(parts[1])
+	if err != nil {
+		return sc, err
+	}
+	if len(parts[2]) != 16 || strings.ToLower(parts[2]) != parts[2] {
+		return sc, fmt.Errorf("xtrace: invalid parent id %q", s)
+	}
+	var spanID SpanID
+	if _, err := hex.Decode(spanID[:], []byte(parts[2])); err != nil || !spanID.IsValid() {
+		return sc, fmt.Errorf("xtrace: invalid parent id %q", s)
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return sc, fmt.Errorf("xtrace: invalid trace flags %q", s)
+	}
+	return SpanContext{TraceID: traceID, SpanID: spanID, Flags: flags[0], Remote: true}, nil
+}
+
+// Traceparent 生成 W3C traceparent 头的值
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// Extract 从请求头解析 traceparent 和 tracestate
+func Extract(h http.Header) (SpanContext, bool) {
+	v := h.Get(TraceparentHeader)
+	if v == "" {
+		return SpanContext{}, false
+	}
+	sc, err := ParseTraceparent(v)
+	if err != nil {
+		return SpanContext{}, false
+	}
+	// 多个 tracestate 头按规范合并
+	sc.TraceState = strings.Join(h.Values(TracestateHeader), ",")
+	return sc, true
+}
+
+// Inject 将 ctx 中的 span 写入 traceparent 和 tracestate 请求头，已存在时不覆盖
+func Inject(ctx context.Context, h http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() || h.Get(TraceparentHeader) != "" {
+		return
+	}
+	h.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		h.Set(TracestateHeader, sc.TraceState)
+	}
+}
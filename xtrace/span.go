@@ -0,0 +1,381 @@
+package xtrace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TraceID W3C trace-id，16 字节
+type TraceID [16]byte
+
+// SpanID W3C parent-id，8 字节
+type SpanID [8]byte
+
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// MarshalText 无效值编码为空字符串
+func (t TraceID) MarshalText() ([]byte, error) {
+	if !t.IsValid() {
+		return []byte{}, nil
+	}
+	return []byte(t.String()), nil
+}
+
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// MarshalText 无效值编码为空字符串
+func (s SpanID) MarshalText() ([]byte, error) {
+	if !s.IsValid() {
+		return []byte{}, nil
+	}
+	return []byte(s.String()), nil
+}
+
+// ParseTraceID 解析 32 位十六进制的 trace id，兼容带 "-" 的 UUID
+func ParseTraceID(s string) (TraceID, error) {
+	var t TraceID
+	s = strings.ReplaceAll(s, "-", "")
+	if len(s) != 32 {
+		return t, fmt.Errorf("xtrace: invalid trace id %q", s)
+	}
+	if _, err := hex.Decode(t[:], []byte(s)); err != nil {
+		return t, fmt.Errorf("xtrace: invalid trace id %q", s)
+	}
+	if !t.IsValid() {
+		return t, errors.New("xtrace: trace id is all zeros")
+	}
+	return t, nil
+}
+
+func newTraceID() TraceID {
+	var t TraceID
+	_, _ = rand.Read(t[:])
+	return t
+}
+
+func newSpanID() SpanID {
+	var s SpanID
+	_, _ = rand.Read(s[:])
+	return s
+}
+
+// FlagsSampled traceparent 中的 sampled 标志位
+const FlagsSampled byte = 0x01
+
+// SpanContext 跨进程传播的 span 标识
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	// Remote 为 true 表示从请求头中解析而来
+	Remote bool
+}
+
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+func (sc SpanContext) IsSampled() bool { return sc.Flags&FlagsSampled != 0 }
+
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = iota
+	SpanKindServer
+	SpanKindClient
+	SpanKindProducer
+	SpanKindConsumer
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	case SpanKindProducer:
+		return "producer"
+	case SpanKindConsumer:
+		return "consumer"
+	default:
+		return "internal"
+	}
+}
+
+func (k SpanKind) MarshalText() ([]byte, error) { return []byte(k.String()), nil }
+
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+func (c StatusCode) String() string {
+	switch c {
+	case StatusOK:
+		return "ok"
+	case StatusError:
+		return "error"
+	default:
+		return "unset"
+	}
+}
+
+func (c StatusCode) MarshalText() ([]byte, error) { return []byte(c.String()), nil }
+
+type Attr struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+// SpanData 结束后的 span 快照，交给 Exporter 导出
+type SpanData struct {
+	Name          string     `json:"name"`
+	Kind          SpanKind   `json:"kind"`
+	TraceID       TraceID    `json:"trace_id"`
+	SpanID        SpanID     `json:"span_id"`
+	ParentSpanID  SpanID     `json:"parent_span_id,omitempty"`
+	TraceState    string     `json:"trace_state,omitempty"`
+	Start         time.Time  `json:"start"`
+	End           time.Time  `json:"end"`
+	Attrs         []Attr     `json:"attrs,omitempty"`
+	Status        StatusCode `json:"status"`
+	StatusMessage string     `json:"status_message,omitempty"`
+}
+
+// Span 一次操作的耗时记录，方法对 nil 安全，StartChild 没有上级 span 时返回 nil
+type Span struct {
+	mu    sync.Mutex
+	data  SpanData
+	sc    SpanContext
+	ended bool
+}
+
+type spanConfig struct {
+	kind    SpanKind
+	start   time.Time
+	attrs   []Attr
+	traceID TraceID
+	parent  *SpanContext
+}
+
+type SpanOption func(*spanConfig)
+
+// WithSpanKind 设置 span 类型，默认 SpanKindInternal
+func WithSpanKind(kind SpanKind) SpanOption {
+	return func(c *spanConfig) {
+		c.kind = kind
+	}
+}
+
+// WithStartTime 指定开始时间，用于事后补记的 span
+func WithStartTime(t time.Time) SpanOption {
+	return func(c *spanConfig) {
+		c.start = t
+	}
+}
+
+// WithSpanAttr 设置初始属性
+func WithSpanAttr(key string, value any) SpanOption {
+	return func(c *spanConfig) {
+		c.attrs = append(c.attrs, Attr{Key: key, Value: value})
+	}
+}
+
+// withTraceID 根 span 沿用指定的 trace id，使 X-Trace-Id 与 traceparent 一致
+func withTraceID(t TraceID) SpanOption {
+	return func(c *spanConfig) {
+		c.traceID = t
+	}
+}
+
+// WithParent 忽略 ctx 中的 span，以 sc 为父级，用于从消息载荷中恢复上游链路；sc 无效时创建新的 trace
+func WithParent(sc SpanContext) SpanOption {
+	return func(c *spanConfig) {
+		c.parent = &sc
+	}
+}
+
+type ctxSpanKey struct{}
+
+type ctxRemoteKey struct{}
+
+// Start 创建 span 并写入 ctx，ctx 中已有 span 或远端 SpanContext 时作为其子 span，否则创建新的 trace。
+// 新的 trace 同时写入 trace id，使日志带上 traceid
+func Start(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cfg := &spanConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.start.IsZero() {
+		cfg.start = time.Now()
+	}
+
+	s := &Span{}
+	parent := SpanContextFromContext(ctx)
+	if cfg.parent != nil {
+		parent = *cfg.parent
+	}
+	if parent.IsValid() {
+		s.sc = SpanContext{TraceID: parent.TraceID, Flags: parent.Flags, TraceState: parent.TraceState}
+		s.data.ParentSpanID = parent.SpanID
+	} else {
+		s.sc.TraceID = cfg.traceID
+		if !s.sc.TraceID.IsValid() {
+			s.sc.TraceID = newTraceID()
+		}
+		if shouldSample() {
+			s.sc.Flags = FlagsSampled
+		}
+		if FromTraceId(ctx) == "" {
+			ctx = SetTraceId(ctx, s.sc.TraceID.String())
+		}
+	}
+	s.sc.SpanID = newSpanID()
+	s.data.Name = name
+	s.data.Kind = cfg.kind
+	s.data.Start = cfg.start
+	s.data.Attrs = cfg.attrs
+
+	return context.WithValue(ctx, ctxSpanKey{}, s), s
+}
+
+// StartChild 仅在 ctx 中已有 span 时创建子 span，否则原样返回 ctx 和 nil，
+// 用于数据库、缓存等不应单独成为 trace 的调用
+func StartChild(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	if ctx == nil || !SpanContextFromContext(ctx).IsValid() {
+		return ctx, nil
+	}
+	return Start(ctx, name, opts...)
+}
+
+// SpanFromContext 返回 ctx 中当前的 span，没有时返回 nil
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		if c.Request == nil {
+			return nil
+		}
+		ctx = c.Request.Context()
+	}
+	s, _ := ctx.Value(ctxSpanKey{}).(*Span)
+	return s
+}
+
+// SpanContextFromContext 返回当前 span 或远端传入的 SpanContext
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.SpanContext()
+	}
+	if ctx == nil {
+		return SpanContext{}
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		if c.Request == nil {
+			return SpanContext{}
+		}
+		ctx = c.Request.Context()
+	}
+	sc, _ := ctx.Value(ctxRemoteKey{}).(SpanContext)
+	return sc
+}
+
+// ContextWithRemoteSpanContext 将从上游解析出的 SpanContext 写入 ctx，之后 Start 的 span 以其为父级
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, ctxRemoteKey{}, sc)
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttr 设置属性，同名属性会被覆盖
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.data.Attrs {
+		if s.data.Attrs[i].Key == key {
+			s.data.Attrs[i].Value = value
+			return
+		}
+	}
+	s.data.Attrs = append(s.data.Attrs, Attr{Key: key, Value: value})
+}
+
+// SetName 修改名称，如路由匹配后才能确定的名称
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+// SetStatus 设置状态，StatusError 时 msg 作为错误描述
+func (s *Span) SetStatus(code StatusCode, msg string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = code
+	s.data.StatusMessage = msg
+}
+
+// SetError err 不为 nil 时将状态设为 StatusError
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End 结束 span，采样的 span 交给 SetExporter 设置的导出器，重复调用无效
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	s.data.TraceID = s.sc.TraceID
+	s.data.SpanID = s.sc.SpanID
+	s.data.TraceState = s.sc.TraceState
+	data := s.data
+	data.Attrs = append([]Attr(nil), s.data.Attrs...)
+	s.mu.Unlock()
+
+	if s.sc.IsSampled() {
+		export(data)
+	}
+}
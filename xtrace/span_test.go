@@ -0,0 +1,198 @@
+package xtrace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type memoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (e *memoryExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(ctx context.Context) error { return nil }
+
+func TestParseTraceparent(t *testing.T) {
+	const tp = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.IsSampled() || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+	if sc.Traceparent() != tp {
+		t.Fatalf("round trip mismatch %s", sc.Traceparent())
+	}
+
+	for _, bad := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, err := ParseTraceparent(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+	// 更高版本允许附加字段
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTraceIdMiddlewareSpans(t *testing.T) {
+	exp := &memoryExporter{}
+	SetExporter(exp)
+	defer SetExporter(nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(TraceId())
+	var downstream http.Header
+	r.GET("/users/:id", func(c *gin.Context) {
+		_, child := StartChild(c, "load user")
+		child.SetError(errors.New("not found"))
+		child.End()
+
+		downstream = http.Header{}
+		Inject(c, downstream)
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(TracestateHeader, "vendor=a")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get(DefaultTraceIdHeader); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("unexpected trace id header %q", got)
+	}
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(exp.spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(exp.spans))
+	}
+	child, server := exp.spans[0], exp.spans[1]
+	if server.Name != "GET /users/:id" || server.Kind != SpanKindServer || server.Status != StatusError {
+		t.Fatalf("unexpected server span %+v", server)
+	}
+	if server.ParentSpanID.String() != "00f067aa0ba902b7" || server.TraceState != "vendor=a" {
+		t.Fatalf("server span should continue remote parent: %+v", server)
+	}
+	if child.ParentSpanID != server.SpanID || child.TraceID != server.TraceID || child.StatusMessage != "not found" {
+		t.Fatalf("unexpected child span %+v", child)
+	}
+	if !strings.Contains(downstream.Get(TraceparentHeader), server.TraceID.String()) || downstream.Get(TracestateHeader) != "vendor=a" {
+		t.Fatalf("unexpected downstream headers %v", downstream)
+	}
+}
+
+func TestStartRootAndChild(t *testing.T) {
+	if _, span := StartChild(context.Background(), "orphan"); span != nil {
+		t.Fatal("StartChild without parent should return nil")
+	}
+
+	var buf bytes.Buffer
+	SetExporter(NewStdoutExporter(&buf))
+	ctx, root := Start(context.Background(), "job", WithSpanAttr("job", "sync"))
+	if FromTraceId(ctx) != root.SpanContext().TraceID.String() {
+		t.Fatal("root span should set trace id")
+	}
+	_, child := StartChild(ctx, "query")
+	child.End()
+	root.End()
+	root.End()
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	var data map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data["name"] != "job" || data["parent_span_id"] != "" || data["kind"] != "internal" {
+		t.Fatalf("unexpected root span %s", lines[1])
+	}
+}
+
+func TestStartWithParent(t *testing.T) {
+	ctx, local := Start(context.Background(), "local")
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, span := Start(ctx, "consume", WithParent(remote))
+	if span.SpanContext().TraceID != remote.TraceID || span.data.ParentSpanID != remote.SpanID {
+		t.Fatalf("span should use explicit parent, got %+v", span.SpanContext())
+	}
+	_, root := Start(ctx, "consume", WithParent(SpanContext{}))
+	if root.SpanContext().TraceID == local.SpanContext().TraceID || root.data.ParentSpanID.IsValid() {
+		t.Fatal("invalid parent should start a new trace")
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+	}))
+	defer srv.Close()
+
+	exp := NewOTLPExporter(srv.URL, WithOTLPServiceName("api"), WithOTLPHeader("Authorization", "Bearer token"))
+	_, span := Start(context.Background(), "op", WithSpanKind(SpanKindClient), WithSpanAttr("n", 3))
+	span.SetError(errors.New("boom"))
+	span.End()
+	span.mu.Lock()
+	data := span.data
+	span.mu.Unlock()
+	if err := exp.ExportSpans(context.Background(), []SpanData{data}); err != nil {
+		t.Fatal(err)
+	}
+
+	rs := body["resourceSpans"].([]any)[0].(map[string]any)
+	service := rs["resource"].(map[string]any)["attributes"].([]any)[0].(map[string]any)
+	if service["value"].(map[string]any)["stringValue"] != "api" {
+		t.Fatalf("unexpected resource %v", rs["resource"])
+	}
+	s := rs["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)[0].(map[string]any)
+	if s["traceId"] != data.TraceID.String() || s["kind"] != float64(3) || s["status"].(map[string]any)["code"] != float64(2) {
+		t.Fatalf("unexpected span %v", s)
+	}
+	attr := s["attributes"].([]any)[0].(map[string]any)
+	if attr["value"].(map[string]any)["intValue"] != "3" {
+		t.Fatalf("unexpected attribute %v", attr)
+	}
+
+	bad := NewOTLPExporter(srv.URL)
+	if err := bad.ExportSpans(context.Background(), []SpanData{data}); err == nil {
+		t.Fatal("expected error on 401")
+	}
+}